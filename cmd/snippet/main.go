@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/cli"
+)
+
+func main() {
+	app := &cli.App{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+
+	os.Exit(app.Run(os.Args[1:]))
+}
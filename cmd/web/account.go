@@ -0,0 +1,270 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+func (app *application) account(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.FeedURL = fmt.Sprintf("/user/feed/%d", app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	data.AlternateLinks = feedLinks("Your snippets", data.FeedURL)
+
+	app.render(w, http.StatusOK, "account.html", data)
+}
+
+// the layout of profile.json and snippets.json in the export, kept apart
+// from the models so that the password hash can never end up in it
+type exportProfile struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email"`
+	Created time.Time `json:"created"`
+}
+
+type exportSnippet struct {
+	ID         int               `json:"id"`
+	Title      string            `json:"title"`
+	File       string            `json:"file"`
+	Language   string            `json:"language"`
+	Visibility models.Visibility `json:"visibility"`
+	Created    time.Time         `json:"created"`
+	Expires    time.Time         `json:"expires"`
+}
+
+// accountExport() sends a ZIP archive of the user's profile and snippets,
+// with the content of each snippet as a file of its own
+func (app *application) accountExport(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	export, err := app.accounts.Export(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	profile := exportProfile{
+		ID:      export.User.ID,
+		Name:    export.User.Name,
+		Email:   export.User.Email,
+		Created: export.User.Created,
+	}
+
+	app.audit(r, "account.export", fmt.Sprintf("user:%d", id), nil)
+
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	snippets := []exportSnippet{}
+	for _, s := range export.Snippets {
+		file := fmt.Sprintf("snippets/%d.txt", s.ID)
+
+		f, err := archive.Create(file)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		_, err = f.Write([]byte(s.Content))
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		snippets = append(snippets, exportSnippet{
+			ID:         s.ID,
+			Title:      s.Title,
+			File:       file,
+			Language:   s.Language,
+			Visibility: s.Visibility,
+			Created:    s.Created,
+			Expires:    s.Expires,
+		})
+	}
+
+	for name, data := range map[string]any{"profile.json": profile, "snippets.json": snippets} {
+		f, err := archive.Create(name)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "\t")
+		err = enc.Encode(data)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="snippetbox-export.zip"`)
+
+	buf.WriteTo(w)
+}
+
+type accountDeleteForm struct {
+	Password            string `form:"password"`
+	Snippets            string `form:"snippets"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountDelete(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = accountDeleteForm{Snippets: "delete"}
+	app.render(w, http.StatusOK, "delete.html", data)
+}
+
+func (app *application) accountDeletePost(w http.ResponseWriter, r *http.Request) {
+	var form accountDeleteForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	reauthenticated := app.reauthenticated(r)
+
+	form.CheckField(reauthenticated || form.Validator.NotBlank(form.Password), "password", "This field cannot be blank")
+	form.CheckField(validator.PermittedValue(form.Snippets, "delete", "anonymise"), "snippets", "This field must equal delete or anonymise")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "delete.html", data)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// make sure it really is the user asking, and not someone who found
+	// their browser logged in, unless they have just shown it is at the
+	// identity provider
+	if !reauthenticated {
+		authenticatedID, err := app.authenticator.Authenticate(user.Email, form.Password)
+		if err != nil && !errors.Is(err, models.ErrInvalidCredentials) {
+			app.serverError(w, err)
+			return
+		}
+		if err != nil || authenticatedID != id {
+			form.AddFieldError("password", "Password is incorrect")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "delete.html", data)
+			return
+		}
+	}
+
+	// anonymised snippets stay up, so only deleted ones are sent to the
+	// webhooks
+	deleted, err := app.accounts.Delete(id, form.Snippets == "anonymise")
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "account.delete", fmt.Sprintf("user:%d", id), map[string]any{"snippets": form.Snippets})
+	app.snippetsDeleted(deleted)
+
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	clearPersistentLoginCookie(w)
+
+	app.sessionManager.Put(r.Context(), "flash", "Your account has been deleted")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+type accountPasswordForm struct {
+	CurrentPassword         string `form:"current_password"`
+	NewPassword             string `form:"new_password"`
+	NewPasswordConfirmation string `form:"new_password_confirmation"`
+	validator.Validator     `form:"-"`
+}
+
+func (app *application) accountPassword(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = accountPasswordForm{}
+	app.render(w, http.StatusOK, "password.html", data)
+}
+
+func (app *application) accountPasswordPost(w http.ResponseWriter, r *http.Request) {
+	var form accountPasswordForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	reauthenticated := app.reauthenticated(r)
+
+	form.CheckField(reauthenticated || form.Validator.NotBlank(form.CurrentPassword), "currentPassword", "This field cannot be blank")
+	form.CheckField(form.Validator.NotBlank(form.NewPassword), "newPassword", "This field cannot be blank")
+	form.CheckField(form.Validator.MinChars(form.NewPassword, 8), "newPassword", "This field must be at least 8 characters long")
+	form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "Passwords do not match")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "password.html", data)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	// users who have just logged in again at the identity provider may
+	// not have a password of their own to give
+	if reauthenticated {
+		err = app.users.PasswordSet(id, form.NewPassword)
+	} else {
+		err = app.users.PasswordUpdate(id, form.CurrentPassword, form.NewPassword)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddFieldError("currentPassword", "Current password is incorrect")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "password.html", data)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "reauthenticated")
+
+	app.audit(r, "account.password_change", fmt.Sprintf("user:%d", id), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been updated!")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
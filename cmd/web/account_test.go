@@ -0,0 +1,212 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+func TestAccountExport(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	code, headers, body := ts.get(t, "/account/export")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Content-Type"), "application/zip")
+
+	archive, err := zip.NewReader(strings.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+
+	var profile map[string]any
+	err = json.Unmarshal([]byte(files["profile.json"]), &profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, profile["email"], any("test@example.com"))
+	assert.Equal(t, strings.Contains(files["profile.json"], "assword"), false)
+
+	assert.StringContains(t, files["snippets.json"], `"file": "snippets/1.txt"`)
+	assert.StringContains(t, files["snippets.json"], `"language": ""`)
+	assert.StringContains(t, files["snippets.json"], `"visibility": "public"`)
+	assert.Equal(t, files["snippets/1.txt"], "An old silent pond...")
+}
+
+func TestAccountDelete(t *testing.T) {
+	tests := []struct {
+		name          string
+		password      string
+		snippets      string
+		wantCode      int
+		wantBody      string
+		wantDeleted   int
+		wantAnonymise int
+		wantEvents    int
+	}{
+		{
+			name:     "Wrong password",
+			password: "wrong-password",
+			snippets: "delete",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Password is incorrect",
+		},
+		{
+			name:     "Invalid choice",
+			password: "password",
+			snippets: "keep",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must equal delete or anonymise",
+		},
+		{
+			name:        "Delete snippets",
+			password:    "password",
+			snippets:    "delete",
+			wantCode:    http.StatusSeeOther,
+			wantDeleted: 1,
+			wantEvents:  1,
+		},
+		{
+			name:          "Anonymise snippets",
+			password:      "password",
+			snippets:      "anonymise",
+			wantCode:      http.StatusSeeOther,
+			wantDeleted:   1,
+			wantAnonymise: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+
+			hooks := app.webhooks.(*mocks.WebhookModel)
+			hooks.Insert(0, "https://example.com/hook", []string{models.EventSnippetDeleted})
+
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			ts.login(t)
+
+			_, _, body := ts.get(t, "/account/delete")
+
+			form := url.Values{}
+			form.Add("password", tt.password)
+			form.Add("snippets", tt.snippets)
+			form.Add("csrf_token", extractCSRFToken(t, body))
+
+			code, _, body := ts.postForm(t, "/account/delete", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+
+			accounts := app.accounts.(*mocks.AccountModel)
+			assert.Equal(t, len(accounts.Deleted), tt.wantDeleted)
+			assert.Equal(t, len(accounts.Anonymised), tt.wantAnonymise)
+			assert.Equal(t, len(hooks.Queue), tt.wantEvents)
+
+			// the browser that deleted the account is logged out
+			if tt.wantDeleted > 0 {
+				code, _, _ = ts.get(t, "/account")
+				assert.Equal(t, code, http.StatusSeeOther)
+			}
+		})
+	}
+}
+
+func TestAccountPasswordPost(t *testing.T) {
+	tests := []struct {
+		name            string
+		currentPassword string
+		newPassword     string
+		confirmation    string
+		wantCode        int
+		wantBody        string
+	}{
+		{
+			name:            "Valid",
+			currentPassword: "password",
+			newPassword:     "new-password",
+			confirmation:    "new-password",
+			wantCode:        http.StatusSeeOther,
+		},
+		{
+			name:            "Wrong current password",
+			currentPassword: "wrong-password",
+			newPassword:     "new-password",
+			confirmation:    "new-password",
+			wantCode:        http.StatusUnprocessableEntity,
+			wantBody:        "Current password is incorrect",
+		},
+		{
+			name:            "Short password",
+			currentPassword: "password",
+			newPassword:     "pa$$",
+			confirmation:    "pa$$",
+			wantCode:        http.StatusUnprocessableEntity,
+			wantBody:        "This field must be at least 8 characters long",
+		},
+		{
+			name:            "Mismatched confirmation",
+			currentPassword: "password",
+			newPassword:     "new-password",
+			confirmation:    "other-password",
+			wantCode:        http.StatusUnprocessableEntity,
+			wantBody:        "Passwords do not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			ts.login(t)
+
+			_, _, body := ts.get(t, "/account/password")
+
+			form := url.Values{}
+			form.Add("csrf_token", extractCSRFToken(t, body))
+			form.Add("current_password", tt.currentPassword)
+			form.Add("new_password", tt.newPassword)
+			form.Add("new_password_confirmation", tt.confirmation)
+
+			code, _, body := ts.postForm(t, "/account/password", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
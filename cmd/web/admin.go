@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+func (app *application) adminDashboard(w http.ResponseWriter, r *http.Request) {
+	stats, err := app.stats.Get(30)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Stats = stats
+
+	app.render(w, http.StatusOK, "admin/dashboard.html", data)
+}
+
+type adminUserSearchForm struct {
+	Search string `form:"q"`
+}
+
+func (app *application) adminUsers(w http.ResponseWriter, r *http.Request) {
+	var form adminUserSearchForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	users, err := app.users.List(form.Search)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Users = users
+	data.Form = form
+
+	app.render(w, http.StatusOK, "admin/users.html", data)
+}
+
+type adminUserRoleForm struct {
+	ID                  int         `form:"id"`
+	Role                models.Role `form:"role"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) adminUserRolePost(w http.ResponseWriter, r *http.Request) {
+	var form adminUserRoleForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil || !form.Role.Valid() {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// an admin taking away their own role could leave nobody able to
+	// manage the site
+	if form.ID == app.sessionManager.GetInt(r.Context(), "authenticatedUserID") {
+		app.sessionManager.Put(r.Context(), "flash", "You can't change your own role")
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+
+	err = app.users.SetRole(form.ID, form.Role)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "admin.user.role", fmt.Sprintf("user:%d", form.ID), map[string]any{"role": form.Role})
+
+	app.sessionManager.Put(r.Context(), "flash", "Role changed successfully!")
+
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+type adminUserForm struct {
+	ID                  int  `form:"id"`
+	Disabled            bool `form:"disabled"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) adminUserDisablePost(w http.ResponseWriter, r *http.Request) {
+	var form adminUserForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if form.ID == app.sessionManager.GetInt(r.Context(), "authenticatedUserID") {
+		app.sessionManager.Put(r.Context(), "flash", "You can't disable your own account")
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+
+	err = app.users.SetDisabled(form.ID, form.Disabled)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	action := "admin.user.enable"
+	flash := "Account enabled successfully!"
+
+	// a disabled user is logged out straight away
+	if form.Disabled {
+		err = app.revokeAllSessions(form.ID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		action = "admin.user.disable"
+		flash = "Account disabled successfully!"
+	}
+
+	app.audit(r, action, fmt.Sprintf("user:%d", form.ID), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+// adminUserResetPasswordPost() makes the user choose a new password,
+// logging them out everywhere so that whoever may know the old one
+// can't carry on
+func (app *application) adminUserResetPasswordPost(w http.ResponseWriter, r *http.Request) {
+	var form adminUserForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.users.RequirePasswordChange(form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.revokeAllSessions(form.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "admin.user.reset_password", fmt.Sprintf("user:%d", form.ID), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "The user will have to change their password when they next log in")
+
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+// adminUserUnlockPost() lifts a lockout on the user's account after too
+// many failed logins, for a user who can't get at the unlock email
+func (app *application) adminUserUnlockPost(w http.ResponseWriter, r *http.Request) {
+	var form adminUserForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.users.Get(form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.loginAttempts.Reset(accountLoginKey(user.Email))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "admin.user.unlock", fmt.Sprintf("user:%d", form.ID), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "Account unlocked successfully!")
+
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+type adminSnippetFilterForm struct {
+	Search string `form:"q"`
+	UserID int    `form:"user"`
+	Status string `form:"status"`
+}
+
+func (app *application) adminSnippets(w http.ResponseWriter, r *http.Request) {
+	var form adminSnippetFilterForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil || !validator.PermittedValue(form.Status, "", "active", "expired") {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	snippets, err := app.snippets.Search(models.SnippetFilter{
+		Search: form.Search,
+		UserID: form.UserID,
+		Status: form.Status,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Form = form
+
+	app.render(w, http.StatusOK, "admin/snippets.html", data)
+}
+
+type adminSnippetDeleteForm struct {
+	IDs                 []int `form:"id"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) adminSnippetsDeletePost(w http.ResponseWriter, r *http.Request) {
+	var form adminSnippetDeleteForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if len(form.IDs) == 0 {
+		app.sessionManager.Put(r.Context(), "flash", "No snippets were selected")
+		http.Redirect(w, r, "/admin/snippets", http.StatusSeeOther)
+		return
+	}
+
+	deleted, err := app.getSnippets(form.IDs)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	n, err := app.snippets.DeleteMany(form.IDs)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "admin.snippet.delete", "", map[string]any{"ids": form.IDs, "deleted": n})
+	app.snippetsDeleted(deleted)
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%d snippet(s) deleted", n))
+
+	http.Redirect(w, r, "/admin/snippets", http.StatusSeeOther)
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+func TestAdminAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		email     string
+		wantCode  int
+		wantAdmin bool
+	}{
+		{
+			name:     "Anonymous",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "User",
+			email:    "test@example.com",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Moderator",
+			email:    "moderator@example.com",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:      "Admin",
+			email:     "admin@example.com",
+			wantCode:  http.StatusOK,
+			wantAdmin: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			if tt.email != "" {
+				ts.loginAs(t, tt.email)
+			}
+
+			code, _, _ := ts.get(t, "/admin")
+			assert.Equal(t, code, tt.wantCode)
+
+			// only admins see the link in the navigation
+			_, _, body := ts.get(t, "/")
+			assert.Equal(t, strings.Contains(body, "href='/admin'"), tt.wantAdmin)
+		})
+	}
+}
+
+func TestAdminUserRolePost(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.loginAs(t, "admin@example.com")
+
+	tests := []struct {
+		name     string
+		id       string
+		role     string
+		wantCode int
+		wantRole models.Role
+	}{
+		{
+			name:     "Promote user",
+			id:       "1",
+			role:     "moderator",
+			wantCode: http.StatusSeeOther,
+			wantRole: models.RoleModerator,
+		},
+		{
+			name:     "Unknown role",
+			id:       "1",
+			role:     "superuser",
+			wantCode: http.StatusBadRequest,
+			wantRole: models.RoleModerator,
+		},
+		{
+			name:     "Unknown user",
+			id:       "99",
+			role:     "admin",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, body := ts.get(t, "/admin")
+
+			form := url.Values{}
+			form.Add("csrf_token", extractCSRFToken(t, body))
+			form.Add("id", tt.id)
+			form.Add("role", tt.role)
+
+			code, _, _ := ts.postForm(t, "/admin/users/role", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantRole != "" {
+				assert.Equal(t, app.users.(*mocks.UserModel).Roles[1], tt.wantRole)
+			}
+		})
+	}
+
+	t.Run("Own role", func(t *testing.T) {
+		_, _, body := ts.get(t, "/admin")
+
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		form.Add("id", "3")
+		form.Add("role", "user")
+
+		code, _, _ := ts.postForm(t, "/admin/users/role", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, _, _ = ts.get(t, "/admin")
+		assert.Equal(t, code, http.StatusOK)
+	})
+}
+
+func TestAdminConsole(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.loginAs(t, "admin@example.com")
+
+	tests := []struct {
+		name        string
+		urlPath     string
+		wantCode    int
+		wantBody    string
+		notWantBody string
+	}{
+		{
+			name:     "Dashboard",
+			urlPath:  "/admin",
+			wantCode: http.StatusOK,
+			wantBody: "Active users, last 7 days",
+		},
+		{
+			name:     "All users",
+			urlPath:  "/admin/users",
+			wantCode: http.StatusOK,
+			wantBody: "moderator@example.com",
+		},
+		{
+			name:        "Search users",
+			urlPath:     "/admin/users?q=Bob",
+			wantCode:    http.StatusOK,
+			wantBody:    "moderator@example.com",
+			notWantBody: "admin@example.com",
+		},
+		{
+			name:     "Snippets by owner",
+			urlPath:  "/admin/snippets?user=1",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond",
+		},
+		{
+			name:        "Snippets by other owner",
+			urlPath:     "/admin/snippets?user=2",
+			wantCode:    http.StatusOK,
+			notWantBody: "An old silent pond",
+		},
+		{
+			name:     "Invalid status",
+			urlPath:  "/admin/snippets?status=deleted",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+			if tt.notWantBody != "" {
+				assert.Equal(t, strings.Contains(body, tt.notWantBody), false)
+			}
+		})
+	}
+}
+
+// adminPost() submits a form on the admin console with a fresh CSRF token
+func adminPost(t *testing.T, ts *testServer, urlPath string, form url.Values) int {
+	_, _, body := ts.get(t, "/admin")
+	form.Set("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := ts.postForm(t, urlPath, form)
+	return code
+}
+
+func TestAdminUserDisable(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	user := device(t, ts)
+	ts.login(t)
+
+	admin := device(t, ts)
+	ts.loginAs(t, "admin@example.com")
+
+	code := adminPost(t, ts, "/admin/users/disable", url.Values{"id": {"1"}, "disabled": {"true"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	last := events[len(events)-1]
+	assert.Equal(t, last.Action, "admin.user.disable")
+	assert.Equal(t, last.Target, "user:1")
+	assert.Equal(t, last.ActorID, 3)
+
+	// the user is logged out and can't log back in
+	ts.Client().Jar = user
+	code, _, _ = ts.get(t, "/snippet/create")
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	code, _, body := postLogin(t, ts, "test@example.com", "password")
+	assert.Equal(t, code, http.StatusForbidden)
+	assert.StringContains(t, body, "This account has been disabled")
+
+	ts.Client().Jar = admin
+	code = adminPost(t, ts, "/admin/users/disable", url.Values{"id": {"1"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	ts.Client().Jar = user
+	code, _, _ = postLogin(t, ts, "test@example.com", "password")
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	t.Run("Own account", func(t *testing.T) {
+		ts.Client().Jar = admin
+		code := adminPost(t, ts, "/admin/users/disable", url.Values{"id": {"3"}, "disabled": {"true"}})
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, app.users.(*mocks.UserModel).Disabled[3], false)
+	})
+
+	t.Run("Without CSRF token", func(t *testing.T) {
+		ts.Client().Jar = admin
+		code, _, _ := ts.postForm(t, "/admin/users/disable", url.Values{"id": {"1"}, "disabled": {"true"}})
+		assert.Equal(t, code, http.StatusBadRequest)
+		assert.Equal(t, app.users.(*mocks.UserModel).Disabled[1], false)
+	})
+}
+
+func TestAdminForcePasswordReset(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.loginAs(t, "admin@example.com")
+
+	code := adminPost(t, ts, "/admin/users/reset-password", url.Values{"id": {"1"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	// the user can log in, but can't do anything but change their password
+	device(t, ts)
+	ts.login(t)
+
+	code, headers, _ := ts.get(t, "/snippet/create")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/account/password")
+
+	_, _, body := ts.get(t, "/account/password")
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	form.Add("current_password", "password")
+	form.Add("new_password", "new-password")
+	form.Add("new_password_confirmation", "new-password")
+	code, _, _ = ts.postForm(t, "/account/password", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	code, _, _ = ts.get(t, "/snippet/create")
+	assert.Equal(t, code, http.StatusOK)
+}
+
+func TestAdminUserUnlock(t *testing.T) {
+	app := newTestApplication(t)
+	app.loginAttempts.(*mocks.LoginAttemptModel).Attempts = map[string]*models.LoginAttempt{
+		"account:test@example.com": {Failures: accountLockout.maxAttempts, LockedUntil: time.Now().Add(time.Hour)},
+	}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := postLogin(t, ts, "test@example.com", "password")
+	assert.Equal(t, code, http.StatusTooManyRequests)
+
+	device(t, ts)
+	ts.loginAs(t, "admin@example.com")
+
+	code = adminPost(t, ts, "/admin/users/unlock", url.Values{"id": {"99"}})
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code = adminPost(t, ts, "/admin/users/unlock", url.Values{"id": {"1"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	last := events[len(events)-1]
+	assert.Equal(t, last.Action, "admin.user.unlock")
+	assert.Equal(t, last.Target, "user:1")
+	assert.Equal(t, last.ActorID, 3)
+
+	device(t, ts)
+	code, _, _ = postLogin(t, ts, "test@example.com", "password")
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	t.Run("Not an admin", func(t *testing.T) {
+		_, _, body := ts.get(t, "/account")
+		form := url.Values{"id": {"1"}, "csrf_token": {extractCSRFToken(t, body)}}
+
+		code, _, _ := ts.postForm(t, "/admin/users/unlock", form)
+		assert.Equal(t, code, http.StatusForbidden)
+	})
+}
+
+func TestAdminSnippetsDelete(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.loginAs(t, "admin@example.com")
+
+	code := adminPost(t, ts, "/admin/snippets/delete", url.Values{"id": {"1", "5"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	assert.Equal(t, len(app.snippets.(*mocks.SnippetModel).Deleted), 2)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	last := events[len(events)-1]
+	assert.Equal(t, last.Action, "admin.snippet.delete")
+	assert.Equal(t, last.Metadata["deleted"], any(1))
+}
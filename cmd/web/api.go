@@ -0,0 +1,516 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// maxAPIBodyBytes is the largest request body the API reads, which
+	// leaves room for a snippet of any size the database will take
+	maxAPIBodyBytes = 1 << 20
+
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// apiError is the body of every error the API sends, as
+//
+//	{"error": {"status": 422, "message": "...", "fields": {"title": "..."}}}
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Status  int               `json:"status"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// apiSnippet is a snippet as the API shows it
+type apiSnippet struct {
+	ID         int               `json:"id"`
+	Title      string            `json:"title"`
+	Content    string            `json:"content"`
+	Language   string            `json:"language"`
+	Visibility models.Visibility `json:"visibility"`
+	Created    time.Time         `json:"created"`
+	Expires    time.Time         `json:"expires"`
+	Tags       []string          `json:"tags,omitempty"`
+	Hidden     bool              `json:"hidden,omitempty"`
+	URL        string            `json:"url"`
+}
+
+func (app *application) apiSnippet(s *models.Snippet, tags []string) apiSnippet {
+	return apiSnippet{
+		ID:         s.ID,
+		Title:      s.Title,
+		Content:    s.Content,
+		Language:   s.Language,
+		Visibility: s.Visibility,
+		Tags:       tags,
+		Created:    s.Created.UTC(),
+		Expires:    s.Expires.UTC(),
+		Hidden:     s.Hidden,
+		URL:        fmt.Sprintf("%s/snippet/view/%d", app.baseURL, s.ID),
+	}
+}
+
+// isAPIRequest() reports whether the request is for the JSON API, and so
+// should get its errors as JSON
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+func (app *application) errorJSON(w http.ResponseWriter, status int, message string, fields map[string]string) {
+	app.writeJSON(w, status, apiError{Error: apiErrorBody{Status: status, Message: message, Fields: fields}})
+}
+
+// serverErrorJSON() and friends are the API's versions of serverError(),
+// clientError() and notFound()
+func (app *application) serverErrorJSON(w http.ResponseWriter, err error) {
+	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
+	app.errorLog.Output(2, trace)
+
+	app.errorJSON(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+}
+
+func (app *application) clientErrorJSON(w http.ResponseWriter, status int) {
+	app.errorJSON(w, status, http.StatusText(status), nil)
+}
+
+func (app *application) notFoundJSON(w http.ResponseWriter) {
+	app.clientErrorJSON(w, http.StatusNotFound)
+}
+
+// failedValidationJSON() sends the validator's errors, with a message for
+// each field that was wrong
+func (app *application) failedValidationJSON(w http.ResponseWriter, v validator.Validator) {
+	message := "The request contains invalid fields"
+	if len(v.NonFieldErrors) > 0 {
+		message = strings.Join(v.NonFieldErrors, " ")
+	}
+
+	app.errorJSON(w, http.StatusUnprocessableEntity, message, v.FieldErrors)
+}
+
+// badRequestJSON() explains why readJSON() couldn't read the body
+func (app *application) badRequestJSON(w http.ResponseWriter, err error) {
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesError):
+		app.errorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("The body must not be larger than %d bytes", maxBytesError.Limit), nil)
+	case errors.Is(err, errUnsupportedMediaType):
+		app.errorJSON(w, http.StatusUnsupportedMediaType, "The body must be application/json", nil)
+	default:
+		app.errorJSON(w, http.StatusBadRequest, err.Error(), nil)
+	}
+}
+
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// readJSON() decodes a JSON request body into dst. The body must be a
+// single JSON object no larger than maxAPIBodyBytes, with no fields dst
+// doesn't have; the errors returned can be shown to the client.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return errUnsupportedMediaType
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPIBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			return err
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("The body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("The body contains badly-formed JSON")
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("The body contains the wrong type for the field %q", unmarshalTypeError.Field)
+			}
+			return fmt.Errorf("The body contains the wrong type (at character %d)", unmarshalTypeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("The body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("The body contains the unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		default:
+			return err
+		}
+	}
+
+	err = dec.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return err
+		}
+		return errors.New("The body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// authenticateToken() is the API's version of authenticate. It takes the
+// user from an "Authorization: Bearer" token rather than the session
+// cookie, so API clients need no cookies and can't be the victims of
+// CSRF. Requests without a token carry on anonymously. Unless the token
+// has the admin scope the user only gets an ordinary user's role.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the id always goes in the context, so nothing falls back to
+		// looking for a session that isn't there
+		r = r.WithContext(context.WithValue(r.Context(), userIDContextKey, 0))
+
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			app.invalidToken(w, r)
+			return
+		}
+
+		apiToken, err := app.apiTokens.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.invalidToken(w, r)
+			} else {
+				app.tokenServerError(w, r, err)
+			}
+			return
+		}
+
+		user, err := app.users.Get(apiToken.UserID)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.invalidToken(w, r)
+			} else {
+				app.tokenServerError(w, r, err)
+			}
+			return
+		}
+
+		if user.Disabled {
+			app.invalidToken(w, r)
+			return
+		}
+
+		role := user.Role
+		if !apiToken.HasScope(models.ScopeAdmin) && role.Includes(models.RoleModerator) {
+			role = models.RoleUser
+		}
+
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, roleContextKey, role)
+		ctx = context.WithValue(ctx, userIDContextKey, apiToken.UserID)
+		ctx = context.WithValue(ctx, apiTokenContextKey, apiToken)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireToken() is the API's version of requireAuthentication
+func (app *application) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.tokenRequired(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) tokenRequired(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	app.tokenError(w, r, http.StatusUnauthorized, "This endpoint needs an API token")
+}
+
+// requireScope() refuses requests made with a token that lacks scope.
+// Anonymous requests are left to requireToken.
+func (app *application) requireScope(scope models.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiToken, ok := r.Context().Value(apiTokenContextKey).(*models.APIToken)
+			if ok && !apiToken.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+				app.tokenError(w, r, http.StatusForbidden, fmt.Sprintf("The API token needs the %s scope", scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (app *application) invalidToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	app.tokenError(w, r, http.StatusUnauthorized, "The API token is invalid or has been revoked")
+}
+
+// tokenError() sends an error from the token middleware, as JSON to the
+// API and as plain text to the paste endpoint, which also takes tokens
+func (app *application) tokenError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if isAPIRequest(r) {
+		app.errorJSON(w, status, message, nil)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+func (app *application) tokenServerError(w http.ResponseWriter, r *http.Request, err error) {
+	if isAPIRequest(r) {
+		app.serverErrorJSON(w, err)
+		return
+	}
+	app.serverError(w, err)
+}
+
+// apiSnippetList() pages through the public snippets that haven't
+// expired, newest first, optionally only those containing the q
+// parameter. With owner=me it is the token owner's own snippets instead.
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	v := validator.Validator{}
+	page := readInt(&v, query, "page", 1)
+	perPage := readInt(&v, query, "per_page", defaultPerPage)
+	owner := query.Get("owner")
+
+	v.CheckField(page >= 1, "page", "This field must be at least 1")
+	v.CheckField(perPage >= 1 && perPage <= maxPerPage, "per_page", fmt.Sprintf("This field must be between 1 and %d", maxPerPage))
+	v.CheckField(owner == "" || owner == "me", "owner", "This field must be me")
+
+	if !v.Valid() {
+		app.failedValidationJSON(w, v)
+		return
+	}
+
+	userID := 0
+	if owner == "me" {
+		if !app.isAuthenticated(r) {
+			app.tokenRequired(w, r)
+			return
+		}
+		userID = app.userID(r)
+	}
+
+	snippets, total, err := app.snippets.Page(query.Get("q"), userID, perPage, (page-1)*perPage)
+	if err != nil {
+		app.serverErrorJSON(w, err)
+		return
+	}
+
+	data := struct {
+		Snippets []apiSnippet `json:"snippets"`
+		Metadata struct {
+			Page     int `json:"page"`
+			PerPage  int `json:"per_page"`
+			Total    int `json:"total"`
+			LastPage int `json:"last_page"`
+		} `json:"metadata"`
+	}{Snippets: []apiSnippet{}}
+
+	for _, s := range snippets {
+		tags, err := app.snippets.Tags(s.ID)
+		if err != nil {
+			app.serverErrorJSON(w, err)
+			return
+		}
+		data.Snippets = append(data.Snippets, app.apiSnippet(s, tags))
+	}
+	data.Metadata.Page = page
+	data.Metadata.PerPage = perPage
+	data.Metadata.Total = total
+	data.Metadata.LastPage = (total + perPage - 1) / perPage
+
+	app.writeJSON(w, http.StatusOK, data)
+}
+
+// readInt() returns the named query parameter as an int, or def if it's
+// missing, noting an error in v if it isn't a number
+func readInt(v *validator.Validator, query url.Values, key string, def int) int {
+	s := query.Get(key)
+	if s == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddFieldError(key, "This field must be an integer")
+		return def
+	}
+	return n
+}
+
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFoundJSON(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFoundJSON(w)
+		} else {
+			app.serverErrorJSON(w, err)
+		}
+		return
+	}
+
+	if !app.canSee(r, snippet) {
+		app.notFoundJSON(w)
+		return
+	}
+
+	if snippet.Hidden && !app.role(r).Includes(models.RoleModerator) {
+		app.clientErrorJSON(w, http.StatusUnavailableForLegalReasons)
+		return
+	}
+
+	tags, err := app.snippets.Tags(snippet.ID)
+	if err != nil {
+		app.serverErrorJSON(w, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, map[string]any{"snippet": app.apiSnippet(snippet, tags)})
+}
+
+// apiSnippetDelete() deletes one of the token owner's snippets. Anybody
+// else's is answered 404, the same as one that doesn't exist.
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFoundJSON(w)
+		return
+	}
+
+	deleted, err := app.getSnippets([]int{id})
+	if err != nil {
+		app.serverErrorJSON(w, err)
+		return
+	}
+
+	err = app.snippets.Delete(app.userID(r), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFoundJSON(w)
+		} else {
+			app.serverErrorJSON(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "snippet.delete", fmt.Sprintf("snippet:%d", id), map[string]any{"via": "api"})
+	app.snippetsDeleted(deleted)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title      string            `json:"title"`
+		Content    string            `json:"content"`
+		Language   string            `json:"language"`
+		Visibility models.Visibility `json:"visibility"`
+		Tags       []string          `json:"tags"`
+		Expires    int               `json:"expires"`
+		Redact     bool              `json:"redact"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestJSON(w, err)
+		return
+	}
+
+	if input.Visibility == "" {
+		input.Visibility = models.VisibilityPublic
+	}
+
+	v := validator.Validator{}
+	redacted, _ := app.checkSnippet(&v, &input.Title, &input.Content, input.Language, input.Visibility, input.Expires, input.Redact)
+
+	// the same rules as tags typed into the form, but each one is already
+	// separate, so none of them may hold a comma or space
+	tags, ok := models.ParseTags(strings.Join(input.Tags, ","))
+	for _, tag := range input.Tags {
+		ok = ok && models.ValidTag(strings.ToLower(tag))
+	}
+	v.CheckField(ok, "tags", fmt.Sprintf("Up to %d tags of letters, numbers and dashes", models.MaxTags))
+
+	if !v.Valid() {
+		app.failedValidationJSON(w, v)
+		return
+	}
+
+	id, err := app.snippets.Insert(app.userID(r), input.Title, input.Content, input.Language, input.Visibility, input.Expires)
+	if err != nil {
+		app.serverErrorJSON(w, err)
+		return
+	}
+
+	if len(tags) > 0 {
+		err = app.snippets.SetTags(id, tags)
+		if err != nil {
+			app.serverErrorJSON(w, err)
+			return
+		}
+	}
+
+	metadata := map[string]any{"via": "api"}
+	if redacted > 0 {
+		metadata["secrets_redacted"] = redacted
+	}
+	app.audit(r, "snippet.create", fmt.Sprintf("snippet:%d", id), metadata)
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		app.serverErrorJSON(w, err)
+		return
+	}
+
+	app.webhookEvent(models.EventSnippetCreated, snippet)
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/snippets/%d", id))
+	app.writeJSON(w, http.StatusCreated, map[string]any{"snippet": app.apiSnippet(snippet, tags)})
+}
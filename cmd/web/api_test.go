@@ -0,0 +1,567 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+// apiRequest() calls the JSON API with the token, if any. It goes
+// straight to the transport, so no cookies are sent.
+func (ts *testServer) apiRequest(t *testing.T, method, urlPath, token, body string) (int, http.Header, string) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rs, err := ts.Client().Transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	respBody, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(respBody)
+}
+
+// newAPIToken() creates a token for the mock user with the given id,
+// which never expires
+func newAPIToken(t *testing.T, app *application, userID int, scopes ...models.Scope) string {
+	_, token, err := app.apiTokens.Insert(userID, "test", scopes, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// decodeAPIError() reads the error envelope
+func decodeAPIError(t *testing.T, body string) apiErrorBody {
+	var e apiError
+	err := json.Unmarshal([]byte(body), &e)
+	if err != nil {
+		t.Fatalf("error body %q isn't JSON: %s", body, err)
+	}
+	return e.Error
+}
+
+func TestAPISnippetGet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	moderatorToken := newAPIToken(t, app, 2, models.ScopeRead, models.ScopeAdmin)
+	unprivilegedToken := newAPIToken(t, app, 2, models.ScopeRead)
+	writeOnlyToken := newAPIToken(t, app, 1, models.ScopeWrite)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		token    string
+		hidden   bool
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid ID",
+			urlPath:  "/api/v1/snippets/1",
+			wantCode: http.StatusOK,
+			wantBody: `"title":"An old silent pond"`,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/api/v1/snippets/2",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"error":{"status":404,"message":"Not Found"}}`,
+		},
+		{
+			name:     "String ID",
+			urlPath:  "/api/v1/snippets/foo",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Hidden",
+			urlPath:  "/api/v1/snippets/1",
+			hidden:   true,
+			wantCode: http.StatusUnavailableForLegalReasons,
+		},
+		{
+			name:     "Hidden, as a moderator",
+			urlPath:  "/api/v1/snippets/1",
+			token:    moderatorToken,
+			hidden:   true,
+			wantCode: http.StatusOK,
+			wantBody: `"hidden":true`,
+		},
+		{
+			name:     "Hidden, as a moderator without the admin scope",
+			urlPath:  "/api/v1/snippets/1",
+			token:    unprivilegedToken,
+			hidden:   true,
+			wantCode: http.StatusUnavailableForLegalReasons,
+		},
+		{
+			name:     "Without the read scope",
+			urlPath:  "/api/v1/snippets/1",
+			token:    writeOnlyToken,
+			wantCode: http.StatusForbidden,
+			wantBody: `"message":"The API token needs the read scope"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.snippets.SetHidden(1, tt.hidden)
+
+			code, header, body := ts.apiRequest(t, http.MethodGet, tt.urlPath, tt.token, "")
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Content-Type"), "application/json")
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestAPISnippetList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "First page",
+			wantCode: http.StatusOK,
+			wantBody: `"metadata":{"page":1,"per_page":20,"total":1,"last_page":1}`,
+		},
+		{
+			name:     "Search",
+			query:    "?q=pond",
+			wantCode: http.StatusOK,
+			wantBody: `"title":"An old silent pond"`,
+		},
+		{
+			name:     "No matches",
+			query:    "?q=frog",
+			wantCode: http.StatusOK,
+			wantBody: `{"snippets":[],`,
+		},
+		{
+			name:     "Past the last page",
+			query:    "?page=2&per_page=5",
+			wantCode: http.StatusOK,
+			wantBody: `{"snippets":[],"metadata":{"page":2,"per_page":5,"total":1,"last_page":1}}`,
+		},
+		{
+			name:     "Page size too large",
+			query:    "?per_page=1000",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"fields":{"per_page":"This field must be between 1 and 100"}`,
+		},
+		{
+			name:     "Page not a number",
+			query:    "?page=two",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"fields":{"page":"This field must be an integer"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.apiRequest(t, http.MethodGet, "/api/v1/snippets"+tt.query, "", "")
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestAPISnippetCreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	token := newAPIToken(t, app, 1, models.ScopeWrite)
+	readOnlyToken := newAPIToken(t, app, 1, models.ScopeRead)
+	disabledToken := newAPIToken(t, app, 3, models.ScopeWrite)
+	app.apiTokens.(*mocks.APITokenModel).Tokens[models.APITokenPrefix+"EXPIRED"] = &models.APIToken{
+		ID:      99,
+		UserID:  1,
+		Scopes:  []models.Scope{models.ScopeWrite},
+		Expires: time.Now().Add(-time.Minute),
+	}
+	app.users.(*mocks.UserModel).Disabled = map[int]bool{3: true}
+
+	const valid = `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7}`
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantCode   int
+		wantFields map[string]string
+	}{
+		{
+			name:     "Valid",
+			token:    token,
+			body:     valid,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "No token",
+			body:     valid,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Unknown token",
+			token:    "sbx_NOTAREALTOKEN",
+			body:     valid,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Expired token",
+			token:    models.APITokenPrefix + "EXPIRED",
+			body:     valid,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Without the write scope",
+			token:    readOnlyToken,
+			body:     valid,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Disabled user",
+			token:    disabledToken,
+			body:     valid,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Invalid fields",
+			token:    token,
+			body:     `{"title": "", "content": "Climb Mount Fuji", "expires": 2}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantFields: map[string]string{
+				"title":   "This field cannot be blank",
+				"expires": "This field must equal 1, 7 or 365",
+			},
+		},
+		{
+			name:     "Unknown field",
+			token:    token,
+			body:     `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7, "author": "Issa"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Wrong type",
+			token:    token,
+			body:     `{"title": "O snail", "content": "Climb Mount Fuji", "expires": "7"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Two values",
+			token:    token,
+			body:     valid + valid,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Empty body",
+			token:    token,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Too large",
+			token:    token,
+			body:     `{"title": "O snail", "content": "` + strings.Repeat("a", maxAPIBodyBytes) + `", "expires": 7}`,
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", tt.token, tt.body)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Content-Type"), "application/json")
+
+			if code == http.StatusCreated {
+				assert.Equal(t, header.Get("Location"), "/api/v1/snippets/2")
+				assert.StringContains(t, body, `"title":"O snail"`)
+				return
+			}
+
+			e := decodeAPIError(t, body)
+			assert.Equal(t, e.Status, tt.wantCode)
+			assert.Equal(t, len(e.Fields), len(tt.wantFields))
+			for field, message := range tt.wantFields {
+				assert.Equal(t, e.Fields[field], message)
+			}
+
+			if code == http.StatusUnauthorized {
+				assert.StringContains(t, header.Get("WWW-Authenticate"), "Bearer")
+			}
+			if code == http.StatusForbidden {
+				assert.Equal(t, header.Get("WWW-Authenticate"), `Bearer error="insufficient_scope", scope="write"`)
+			}
+		})
+	}
+
+	// the snippet belongs to the token's owner, and the audit log says
+	// where it came from
+	inserted := app.snippets.(*mocks.SnippetModel).Inserted
+	assert.Equal(t, len(inserted), 1)
+	assert.Equal(t, inserted[0].UserID, 1)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	event := events[len(events)-1]
+	assert.Equal(t, event.Action, "snippet.create")
+	assert.Equal(t, event.ActorID, 1)
+	assert.Equal(t, event.Metadata["via"], "api")
+}
+
+func TestAPISnippetCreateSecrets(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	token := newAPIToken(t, app, 1, models.ScopeWrite)
+	content := "export GITHUB_TOKEN=ghp_" + "R8yQm2Vx4Lk9Pz7Tw3Nc6Hb1Jd5Fg0Sa2Ue8"
+
+	code, _, body := ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", token,
+		`{"title": "Deploy", "content": "`+content+`", "expires": 7}`)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, decodeAPIError(t, body).Fields["content"], "GitHub token on line 1")
+
+	code, _, _ = ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", token,
+		`{"title": "Deploy", "content": "`+content+`", "expires": 7, "redact": true}`)
+	assert.Equal(t, code, http.StatusCreated)
+
+	inserted := app.snippets.(*mocks.SnippetModel).Inserted
+	assert.Equal(t, inserted[0].Content, "export GITHUB_TOKEN=[REDACTED]")
+}
+
+func TestAPISnippetTags(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	token := newAPIToken(t, app, 1, models.ScopeRead, models.ScopeWrite)
+
+	tests := []struct {
+		name     string
+		tags     string
+		wantCode int
+		wantTags []string
+	}{
+		{"None", `[]`, http.StatusCreated, nil},
+		{"Some", `["HTTP", "go", "go"]`, http.StatusCreated, []string{"go", "http"}},
+		{"Invalid", `["c#"]`, http.StatusUnprocessableEntity, nil},
+		{"Two in one", `["go http"]`, http.StatusUnprocessableEntity, nil},
+		{"Too many", `["a", "b", "c", "d", "e", "f"]`, http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.snippets.(*mocks.SnippetModel).Tagged = nil
+
+			code, _, body := ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", token,
+				`{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7, "tags": `+tt.tags+`}`)
+			assert.Equal(t, code, tt.wantCode)
+
+			tagged := app.snippets.(*mocks.SnippetModel).Tagged
+			assert.Equal(t, strings.Join(tagged[2], ","), strings.Join(tt.wantTags, ","))
+
+			if code != http.StatusCreated {
+				assert.StringContains(t, decodeAPIError(t, body).Fields["tags"], "Up to 5 tags")
+				return
+			}
+
+			var rs struct {
+				Snippet apiSnippet `json:"snippet"`
+			}
+			err := json.Unmarshal([]byte(body), &rs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, strings.Join(rs.Snippet.Tags, ","), strings.Join(tt.wantTags, ","))
+		})
+	}
+
+	// they are shown when the snippet is fetched, and left out if it has
+	// none
+	_, _, body := ts.apiRequest(t, http.MethodGet, "/api/v1/snippets/1", token, "")
+	assert.Equal(t, strings.Contains(body, `"tags"`), false)
+
+	app.snippets.SetTags(1, []string{"haiku", "poetry"})
+
+	_, _, body = ts.apiRequest(t, http.MethodGet, "/api/v1/snippets/1", token, "")
+	assert.StringContains(t, body, `"tags":["haiku","poetry"]`)
+
+	_, _, body = ts.apiRequest(t, http.MethodGet, "/api/v1/snippets", token, "")
+	assert.StringContains(t, body, `"tags":["haiku","poetry"]`)
+}
+
+func TestAPIErrors(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, body := ts.apiRequest(t, http.MethodGet, "/api/v1/missing", "", "")
+	assert.Equal(t, code, http.StatusNotFound)
+	assert.Equal(t, header.Get("Content-Type"), "application/json")
+	assert.Equal(t, decodeAPIError(t, body).Status, http.StatusNotFound)
+
+	code, _, body = ts.apiRequest(t, http.MethodPut, "/api/v1/snippets/1", "", "")
+	assert.Equal(t, code, http.StatusMethodNotAllowed)
+	assert.Equal(t, decodeAPIError(t, body).Status, http.StatusMethodNotAllowed)
+
+	// a body that isn't JSON
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/snippets", strings.NewReader("title=O+snail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+newAPIToken(t, app, 1, models.ScopeWrite))
+
+	rs, err := ts.Client().Transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusUnsupportedMediaType)
+}
+
+func TestAccountTokens(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/account/tokens")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		tokenName string
+		scopes    []string
+		expires   string
+		wantError string
+	}{
+		{
+			name:      "Blank name",
+			scopes:    []string{"read"},
+			expires:   "30",
+			wantError: "This field cannot be blank",
+		},
+		{
+			name:      "No scopes",
+			tokenName: "Backup script",
+			expires:   "30",
+			wantError: "Choose at least one scope",
+		},
+		{
+			name:      "Unknown scope",
+			tokenName: "Backup script",
+			scopes:    []string{"delete"},
+			expires:   "30",
+			wantError: "This field must be read, write or admin",
+		},
+		{
+			name:      "Admin scope for an ordinary user",
+			tokenName: "Backup script",
+			scopes:    []string{"read", "admin"},
+			expires:   "30",
+			wantError: "Only moderators and admins can create tokens with the admin scope",
+		},
+		{
+			name:      "Bad expiry",
+			tokenName: "Backup script",
+			scopes:    []string{"read"},
+			expires:   "10",
+			wantError: "This field must equal 0, 7, 30, 90 or 365",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", tt.tokenName)
+			form["scopes"] = tt.scopes
+			form.Add("expires", tt.expires)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/account/tokens", form)
+			assert.Equal(t, code, http.StatusUnprocessableEntity)
+			assert.StringContains(t, body, tt.wantError)
+		})
+	}
+
+	form := url.Values{}
+	form.Add("name", "Backup script")
+	form["scopes"] = []string{"read", "write"}
+	form.Add("expires", "30")
+	form.Add("csrf_token", csrfToken)
+	code, header, body := ts.postForm(t, "/account/tokens", form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("Cache-Control"), "no-store")
+	assert.StringContains(t, body, "Backup script")
+	assert.StringContains(t, body, "read, write")
+
+	// the new token is shown, and works
+	var token string
+	for t := range app.apiTokens.(*mocks.APITokenModel).Tokens {
+		token = t
+	}
+	assert.StringContains(t, body, token)
+
+	created := app.apiTokens.(*mocks.APITokenModel).Tokens[token]
+	assert.Equal(t, created.Expires.Sub(time.Now()).Round(time.Hour), 30*24*time.Hour)
+
+	code, _, _ = ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", token, `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7}`)
+	assert.Equal(t, code, http.StatusCreated)
+
+	// and only shown the once
+	_, _, body = ts.get(t, "/account/tokens")
+	assert.Equal(t, strings.Contains(body, token), false)
+
+	form = url.Values{}
+	form.Add("id", "1")
+	form.Add("csrf_token", csrfToken)
+	code, _, _ = ts.postForm(t, "/account/tokens/revoke", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	code, _, _ = ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", token, `{"title": "O snail", "content": "Climb Mount Fuji", "expires": 7}`)
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	// revoking someone else's token, or one that's gone, is a 404
+	code, _, _ = ts.postForm(t, "/account/tokens/revoke", form)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	actions := []string{}
+	for _, e := range app.auditLog.(*mocks.AuditModel).Events {
+		actions = append(actions, e.Action)
+	}
+	assert.StringContains(t, strings.Join(actions, " "), "api_token.create snippet.create api_token.revoke")
+}
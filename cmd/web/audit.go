@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// auditQueueSize is how many events may be waiting to be written before
+// new ones are dropped
+const auditQueueSize = 1024
+
+// audit() records an action taken by the current user in the audit log.
+// The event is handed to the audit writer so a slow database never holds
+// up the request; without a writer it is written straight away.
+func (app *application) audit(r *http.Request, action, target string, metadata map[string]any) {
+	event := &models.AuditEvent{
+		ActorID:   app.userID(r),
+		Action:    action,
+		Target:    target,
+		IP:        app.clientIP(r),
+		UserAgent: r.UserAgent(),
+		Metadata:  metadata,
+		Created:   time.Now(),
+	}
+
+	if app.auditEvents == nil {
+		app.writeAuditEvent(event)
+		return
+	}
+
+	select {
+	case app.auditEvents <- event:
+	default:
+		app.errorLog.Printf("audit queue full, dropping %s event for user %d", event.Action, event.ActorID)
+	}
+}
+
+func (app *application) writeAuditEvent(event *models.AuditEvent) {
+	err := app.auditLog.Insert(event)
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+}
+
+// auditWriter() writes the queued audit events until the queue is closed
+func (app *application) auditWriter(events <-chan *models.AuditEvent) {
+	for event := range events {
+		app.writeAuditEvent(event)
+	}
+}
+
+// pruneAuditLog() deletes events older than the retention period every
+// interval, forever
+func (app *application) pruneAuditLog(retention, interval time.Duration) {
+	for {
+		n, err := app.auditLog.DeleteBefore(time.Now().Add(-retention))
+		if err != nil {
+			app.errorLog.Print(err)
+		} else if n > 0 {
+			app.infoLog.Printf("deleted %d audit log events older than %s", n, retention)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+type auditFilterForm struct {
+	Actor  string
+	Action string
+	From   string
+	To     string
+}
+
+// auditFilter() reads the filter for the audit log pages from the query
+// string. From and To are dates, and To includes the whole day.
+func auditFilter(r *http.Request) (models.AuditFilter, auditFilterForm, error) {
+	query := r.URL.Query()
+	form := auditFilterForm{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+	}
+
+	filter := models.AuditFilter{Action: form.Action}
+
+	var err error
+	if form.Actor != "" {
+		filter.ActorID, err = strconv.Atoi(form.Actor)
+		if err != nil || filter.ActorID < 1 {
+			return filter, form, fmt.Errorf("invalid actor %q", form.Actor)
+		}
+	}
+	if form.From != "" {
+		filter.From, err = time.Parse(time.DateOnly, form.From)
+		if err != nil {
+			return filter, form, err
+		}
+	}
+	if form.To != "" {
+		filter.To, err = time.Parse(time.DateOnly, form.To)
+		if err != nil {
+			return filter, form, err
+		}
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	return filter, form, nil
+}
+
+func (app *application) adminAudit(w http.ResponseWriter, r *http.Request) {
+	filter, form, err := auditFilter(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	events, err := app.auditLog.List(filter)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.AuditEvents = events
+	data.Form = form
+	data.AuditExportURL = "/admin/audit/export?" + r.URL.RawQuery
+
+	app.render(w, http.StatusOK, "admin/audit.html", data)
+}
+
+// adminAuditExport() streams the matching events as JSON Lines, one
+// event per line, oldest first
+func (app *application) adminAuditExport(w http.ResponseWriter, r *http.Request) {
+	filter, _, err := auditFilter(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	app.audit(r, "admin.audit.export", "", map[string]any{"query": r.URL.RawQuery})
+
+	w.Header().Set("Content-Type", "application/jsonl")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+
+	enc := json.NewEncoder(w)
+
+	err = app.auditLog.Each(filter, func(e *models.AuditEvent) error {
+		return enc.Encode(map[string]any{
+			"id":         e.ID,
+			"actor_id":   e.ActorID,
+			"action":     e.Action,
+			"target":     e.Target,
+			"ip":         e.IP,
+			"user_agent": e.UserAgent,
+			"metadata":   e.Metadata,
+			"created":    e.Created.UTC(),
+		})
+	})
+	if err != nil {
+		// part of the file may have gone out already, so all we can do
+		// is log it and cut the download short
+		app.errorLog.Print(err)
+	}
+}
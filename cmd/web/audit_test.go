@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+func TestAuditLogin(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := postLogin(t, ts, "test@example.com", "wrong password")
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+
+	ts.login(t)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	assert.Equal(t, len(events), 2)
+
+	assert.Equal(t, events[0].Action, "user.login_failed")
+	assert.Equal(t, events[0].ActorID, 0)
+	assert.Equal(t, events[0].Metadata["reason"], any("invalid_credentials"))
+
+	assert.Equal(t, events[1].Action, "user.login")
+	assert.Equal(t, events[1].ActorID, 1)
+	assert.Equal(t, events[1].Target, "user:1")
+	assert.Equal(t, events[1].IP, "127.0.0.1")
+}
+
+func TestAuditAccessDenied(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	code, _, _ := ts.get(t, "/admin/audit")
+	assert.Equal(t, code, http.StatusForbidden)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	last := events[len(events)-1]
+	assert.Equal(t, last.Action, "access.denied")
+	assert.Equal(t, last.Target, "/admin/audit")
+	assert.Equal(t, last.ActorID, 1)
+}
+
+func TestAuditWriter(t *testing.T) {
+	app := newTestApplication(t)
+	app.auditEvents = make(chan *models.AuditEvent, 2)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx, err := app.sessionManager.Load(r.Context(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = r.WithContext(ctx)
+
+	// with nothing draining the queue, the third event is dropped rather
+	// than blocking the request
+	app.audit(r, "first", "", nil)
+	app.audit(r, "second", "", nil)
+	app.audit(r, "third", "", nil)
+
+	log := app.auditLog.(*mocks.AuditModel)
+	assert.Equal(t, len(log.Actions()), 0)
+
+	close(app.auditEvents)
+	app.auditWriter(app.auditEvents)
+
+	assert.Equal(t, strings.Join(log.Actions(), ","), "first,second")
+}
+
+func TestAdminAudit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.loginAs(t, "admin@example.com")
+
+	log := app.auditLog.(*mocks.AuditModel)
+	log.Insert(&models.AuditEvent{ActorID: 2, Action: "admin.user.role", Target: "user:9", Created: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)})
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantBody string
+		dontWant string
+	}{
+		{
+			name:     "All",
+			wantCode: http.StatusOK,
+			wantBody: "user:9",
+		},
+		{
+			name:     "Action",
+			query:    "action=admin.",
+			wantCode: http.StatusOK,
+			wantBody: "user:9",
+		},
+		{
+			name:     "Other action",
+			query:    "action=passkey.",
+			wantCode: http.StatusOK,
+			dontWant: "user:9",
+		},
+		{
+			name:     "Actor",
+			query:    "actor=3",
+			wantCode: http.StatusOK,
+			wantBody: "user.login",
+			dontWant: "user:9",
+		},
+		{
+			name:     "Date range",
+			query:    "from=2024-03-01&to=2024-03-01",
+			wantCode: http.StatusOK,
+			wantBody: "user:9",
+		},
+		{
+			name:     "Before range",
+			query:    "to=2024-02-29",
+			wantCode: http.StatusOK,
+			dontWant: "user:9",
+		},
+		{
+			name:     "Invalid date",
+			query:    "from=yesterday",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid actor",
+			query:    "actor=bob",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, "/admin/audit?"+tt.query)
+
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+			if tt.dontWant != "" && strings.Contains(body, tt.dontWant) {
+				t.Errorf("did not expect body to contain %q", tt.dontWant)
+			}
+		})
+	}
+}
+
+func TestAdminAuditExport(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.loginAs(t, "admin@example.com")
+
+	code, header, body := ts.get(t, "/admin/audit/export?"+url.Values{"action": {"user."}}.Encode())
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("Content-Type"), "application/jsonl")
+
+	actions := []string{}
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var event map[string]any
+		err := json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions = append(actions, event["action"].(string))
+	}
+
+	// the export itself is recorded, but isn't a user. action
+	assert.Equal(t, strings.Join(actions, ","), "user.login")
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	assert.Equal(t, events[len(events)-1].Action, "admin.audit.export")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/pow"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+// challengeForm holds the fields the public forms carry to keep bots out:
+// the proof-of-work challenge, solved by ui/static/js/pow.js or answered
+// by hand without JavaScript, and a honeypot
+type challengeForm struct {
+	ChallengeToken    string `form:"pow_token"`
+	ChallengeSolution string `form:"pow_solution"`
+	ChallengeAnswer   string `form:"pow_answer"`
+	Website           string `form:"website"`
+}
+
+// checkChallenge() records a failed challenge on the form's validator.
+// The caller should send a 400 without any more detail if the honeypot
+// was Trapped, rather than help a bot learn what gave it away.
+func (app *application) checkChallenge(r *http.Request, c challengeForm, v *validator.Validator) {
+	v.Honeypot(c.Website)
+	if v.Trapped {
+		app.audit(r, "spam.blocked", "", map[string]any{"path": r.URL.Path, "reason": "honeypot"})
+		return
+	}
+
+	// answering the question takes no work, only a wait, so each client
+	// may only do it a few times
+	if c.ChallengeSolution == "" && c.ChallengeAnswer != "" && app.rateLimits.answer != nil {
+		if !app.rateLimits.answer.Allow(app.rateLimitKey(r)).Allowed {
+			app.audit(r, "spam.blocked", "", map[string]any{"path": r.URL.Path, "reason": "answers"})
+			v.AddNonFieldError("You've sent a lot of forms without JavaScript, please turn it on or try again later")
+			return
+		}
+	}
+
+	err := app.challenges.Verify(c.ChallengeToken, c.ChallengeSolution, c.ChallengeAnswer)
+	if err == nil {
+		return
+	}
+
+	app.audit(r, "spam.blocked", "", map[string]any{"path": r.URL.Path, "reason": "challenge", "error": err.Error()})
+
+	switch {
+	case errors.Is(err, pow.ErrExpired):
+		v.AddNonFieldError("This form has expired, please submit it again")
+	case errors.Is(err, pow.ErrTooSoon):
+		v.AddNonFieldError("That was quick! Please wait a little while and submit the form again")
+	default:
+		v.AddNonFieldError("We couldn't check you're not a bot, please try again")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+	"github.com/PPRAMANIK62/snippetbox/internal/ratelimit"
+)
+
+func TestChallenge(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	signup := func(t *testing.T, email string, solve func(body string, form url.Values)) (int, string) {
+		_, _, body := ts.get(t, "/user/signup")
+
+		form := url.Values{}
+		form.Add("name", "Bob")
+		form.Add("email", email)
+		form.Add("password", "password")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		solve(body, form)
+
+		code, _, body := ts.postForm(t, "/user/signup", form)
+		return code, body
+	}
+
+	t.Run("Solved", func(t *testing.T) {
+		code, _ := signup(t, "bob@example.com", func(body string, form url.Values) {
+			solveChallenge(t, body, form)
+		})
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+
+	t.Run("Not solved", func(t *testing.T) {
+		code, body := signup(t, "bob@example.com", func(body string, form url.Values) {
+			form.Set("pow_token", challengeTokenRegex.FindStringSubmatch(body)[1])
+		})
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "We couldn&#39;t check you&#39;re not a bot")
+	})
+
+	t.Run("Used twice", func(t *testing.T) {
+		var first url.Values
+		code, _ := signup(t, "bob@example.com", func(body string, form url.Values) {
+			solveChallenge(t, body, form)
+			first = form
+		})
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, _ = signup(t, "bob@example.com", func(body string, form url.Values) {
+			form.Set("pow_token", first.Get("pow_token"))
+			form.Set("pow_solution", first.Get("pow_solution"))
+		})
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Honeypot", func(t *testing.T) {
+		code, body := signup(t, "bot@example.com", func(body string, form url.Values) {
+			solveChallenge(t, body, form)
+			form.Set("website", "https://cheap-watches.example.com")
+		})
+		assert.Equal(t, code, http.StatusBadRequest)
+
+		// the response doesn't say what gave the bot away
+		assert.Equal(t, body, "Bad Request\n")
+
+		events := app.auditLog.(*mocks.AuditModel).Events
+		last := events[len(events)-1]
+		assert.Equal(t, last.Action, "spam.blocked")
+		assert.Equal(t, last.Metadata["reason"], "honeypot")
+	})
+}
+
+var challengeQuestionRegex = regexp.MustCompile(`<label>What is (\w+) plus (\w+)\?</label>`)
+
+func TestChallengeWithoutJavaScript(t *testing.T) {
+	app := newTestApplication(t)
+	app.challenges.MinAge = time.Nanosecond
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+
+	matches := challengeQuestionRegex.FindStringSubmatch(body)
+	if len(matches) < 3 {
+		t.Fatal("no question found in body")
+	}
+	answer := slices.Index(numbers, matches[1]) + slices.Index(numbers, matches[2])
+
+	form := url.Values{}
+	form.Add("email", "test@example.com")
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	form.Add("pow_token", html.UnescapeString(challengeTokenRegex.FindStringSubmatch(body)[1]))
+	form.Add("pow_answer", strconv.Itoa(answer))
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+}
+
+func TestChallengeAnswerLimit(t *testing.T) {
+	app := newTestApplication(t)
+	app.challenges.MinAge = time.Nanosecond
+	app.rateLimits.answer = ratelimit.New(ratelimit.Limit{Requests: 1, Per: time.Minute}, 10)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	signup := func(t *testing.T, email string) (int, string) {
+		_, _, body := ts.get(t, "/user/signup")
+
+		matches := challengeQuestionRegex.FindStringSubmatch(body)
+		if len(matches) < 3 {
+			t.Fatal("no question found in body")
+		}
+		answer := slices.Index(numbers, matches[1]) + slices.Index(numbers, matches[2])
+
+		form := url.Values{}
+		form.Add("name", "Bob")
+		form.Add("email", email)
+		form.Add("password", "password")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		form.Add("pow_token", html.UnescapeString(challengeTokenRegex.FindStringSubmatch(body)[1]))
+		form.Add("pow_answer", strconv.Itoa(answer))
+
+		code, _, body := ts.postForm(t, "/user/signup", form)
+		return code, body
+	}
+
+	code, _ := signup(t, "bob@example.com")
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	code, body := signup(t, "dave@example.com")
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "You&#39;ve sent a lot of forms without JavaScript")
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	last := events[len(events)-1]
+	assert.Equal(t, last.Action, "spam.blocked")
+	assert.Equal(t, last.Metadata["reason"], "answers")
+
+	// solving the challenge isn't limited
+	_, _, body = ts.get(t, "/user/signup")
+	form := url.Values{}
+	form.Add("name", "Dave")
+	form.Add("email", "dave@example.com")
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	solveChallenge(t, body, form)
+
+	code, _, _ = ts.postForm(t, "/user/signup", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+}
+
+var numbers = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"}
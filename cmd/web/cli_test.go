@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/cli"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+// cliRunner runs the snippet command against a test server
+type cliRunner struct {
+	ts     *testServer
+	config string
+}
+
+// newCLIRunner() returns a runner with its own config file, logged in to
+// ts with token if it isn't empty
+func newCLIRunner(t *testing.T, ts *testServer, token string) *cliRunner {
+	t.Setenv("SNIPPETBOX_URL", "")
+	t.Setenv("SNIPPETBOX_TOKEN", "")
+
+	c := &cliRunner{ts: ts, config: filepath.Join(t.TempDir(), "config.json")}
+
+	if token != "" {
+		config := &cli.Config{URL: ts.URL, Token: token}
+		err := config.Save(c.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+// run() runs the command with stdin and returns the exit status, stdout
+// and stderr
+func (c *cliRunner) run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+
+	app := &cli.App{
+		Stdin:      strings.NewReader(stdin),
+		Stdout:     &stdout,
+		Stderr:     &stderr,
+		ConfigPath: c.config,
+		// no cookie jar, like a real client of the API
+		HTTPClient: &http.Client{Transport: c.ts.Client().Transport},
+	}
+
+	status := app.Run(args)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCLILogin(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	token := newAPIToken(t, app, 1, models.ScopeRead)
+
+	t.Run("Valid token from stdin", func(t *testing.T) {
+		c := newCLIRunner(t, ts, "")
+
+		status, stdout, stderr := c.run(token+"\n", "login", "-url", ts.URL)
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Logged in to "+ts.URL)
+		assert.StringContains(t, stderr, "API token: ")
+
+		config, err := cli.LoadConfig(c.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, config.URL, ts.URL)
+		assert.Equal(t, config.Token, token)
+
+		info, err := os.Stat(c.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, info.Mode().Perm(), os.FileMode(0o600))
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		c := newCLIRunner(t, ts, "")
+
+		status, _, stderr := c.run("", "login", "-url", ts.URL, "-token", models.APITokenPrefix+strings.Repeat("A", 26))
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "snippet login:")
+
+		_, err := os.Stat(c.config)
+		assert.Equal(t, os.IsNotExist(err), true)
+	})
+
+	t.Run("No URL", func(t *testing.T) {
+		c := newCLIRunner(t, ts, "")
+
+		status, _, stderr := c.run("", "login", "-token", token)
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "-url flag is required")
+	})
+}
+
+func TestCLICreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	token := newAPIToken(t, app, 1, models.ScopeRead, models.ScopeWrite)
+
+	t.Run("From stdin", func(t *testing.T) {
+		c := newCLIRunner(t, ts, token)
+
+		status, stdout, stderr := c.run("\nFirst line\nSecond line\n", "create", "-lang", "go", "-expires", "7", "-visibility", "unlisted")
+		assert.Equal(t, status, 0)
+		assert.Equal(t, stderr, "")
+		assert.Equal(t, stdout, "https://localhost:4000/snippet/view/2\n")
+
+		inserted := app.snippets.(*mocks.SnippetModel).Inserted
+		s := inserted[len(inserted)-1]
+		assert.Equal(t, s.UserID, 1)
+		assert.Equal(t, s.Title, "First line")
+		assert.Equal(t, s.Content, "\nFirst line\nSecond line\n")
+		assert.Equal(t, s.Language, "go")
+		assert.Equal(t, s.Visibility, models.VisibilityUnlisted)
+	})
+
+	t.Run("From a file", func(t *testing.T) {
+		c := newCLIRunner(t, ts, token)
+
+		file := filepath.Join(t.TempDir(), "main.py")
+		err := os.WriteFile(file, []byte("print('hello')\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		status, _, _ := c.run("", "create", file)
+		assert.Equal(t, status, 0)
+
+		inserted := app.snippets.(*mocks.SnippetModel).Inserted
+		s := inserted[len(inserted)-1]
+		assert.Equal(t, s.Title, "main.py")
+		assert.Equal(t, s.Language, "python")
+		assert.Equal(t, s.Visibility, models.VisibilityPublic)
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		c := newCLIRunner(t, ts, token)
+
+		status, stdout, stderr := c.run("content", "create", "-expires", "2", "-lang", "cobol")
+		assert.Equal(t, status, 1)
+		assert.Equal(t, stdout, "")
+		assert.StringContains(t, stderr, "expires: This field must equal 1, 7 or 365")
+		assert.StringContains(t, stderr, "language: This field must be one of the languages listed")
+	})
+
+	t.Run("Read-only token", func(t *testing.T) {
+		c := newCLIRunner(t, ts, newAPIToken(t, app, 1, models.ScopeRead))
+
+		status, _, stderr := c.run("content", "create")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "snippet create:")
+	})
+
+	t.Run("Title with several files", func(t *testing.T) {
+		c := newCLIRunner(t, ts, token)
+
+		status, _, stderr := c.run("", "create", "-title", "Both", "a.go", "b.go")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "-title can only be used with a single file")
+	})
+
+	t.Run("Not logged in", func(t *testing.T) {
+		c := newCLIRunner(t, ts, "")
+
+		status, _, stderr := c.run("content", "create")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, `run "snippet login" first`)
+	})
+}
+
+func TestCLIGet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	c := newCLIRunner(t, ts, newAPIToken(t, app, 1, models.ScopeRead))
+
+	t.Run("Get", func(t *testing.T) {
+		status, stdout, _ := c.run("", "get", "1")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "#1 An old silent pond")
+		assert.StringContains(t, stdout, "Visibility: public")
+		assert.StringContains(t, stdout, "URL:        https://localhost:4000/snippet/view/1")
+		assert.StringContains(t, stdout, "\n\nAn old silent pond...\n")
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		status, stdout, _ := c.run("", "raw", "1")
+		assert.Equal(t, status, 0)
+		assert.Equal(t, stdout, "An old silent pond...")
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		status, stdout, stderr := c.run("", "get", "99")
+		assert.Equal(t, status, 1)
+		assert.Equal(t, stdout, "")
+		assert.StringContains(t, stderr, "snippet get: ")
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		status, _, stderr := c.run("", "raw", "one")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, `invalid snippet ID "one"`)
+	})
+
+	t.Run("Missing ID", func(t *testing.T) {
+		status, _, stderr := c.run("", "get")
+		assert.Equal(t, status, 2)
+		assert.StringContains(t, stderr, "Usage: snippet get ID")
+	})
+}
+
+func TestCLIList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Own snippets", func(t *testing.T) {
+		c := newCLIRunner(t, ts, newAPIToken(t, app, 1, models.ScopeRead))
+
+		status, stdout, _ := c.run("", "list")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "ID  TITLE")
+		assert.StringContains(t, stdout, "An old silent pond")
+		assert.StringContains(t, stdout, "Page 1 of 1, 1 snippets")
+	})
+
+	t.Run("Someone else's", func(t *testing.T) {
+		c := newCLIRunner(t, ts, newAPIToken(t, app, 2, models.ScopeRead))
+
+		status, stdout, _ := c.run("", "list")
+		assert.Equal(t, status, 0)
+		assert.Equal(t, stdout, "No snippets found\n")
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		// searching public snippets doesn't need a token
+		c := newCLIRunner(t, ts, "")
+		t.Setenv("SNIPPETBOX_URL", ts.URL)
+
+		status, stdout, _ := c.run("", "search", "silent", "pond")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "An old silent pond")
+
+		status, stdout, _ = c.run("", "search", "nothing")
+		assert.Equal(t, status, 0)
+		assert.Equal(t, stdout, "No snippets found\n")
+	})
+
+	t.Run("Search without a query", func(t *testing.T) {
+		c := newCLIRunner(t, ts, "")
+
+		status, _, _ := c.run("", "search")
+		assert.Equal(t, status, 2)
+	})
+}
+
+func TestCLIDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Own snippet", func(t *testing.T) {
+		c := newCLIRunner(t, ts, newAPIToken(t, app, 1, models.ScopeWrite))
+
+		status, stdout, _ := c.run("", "delete", "1")
+		assert.Equal(t, status, 0)
+		assert.Equal(t, stdout, "Deleted snippet 1\n")
+		assert.Equal(t, len(app.snippets.(*mocks.SnippetModel).Deleted), 1)
+	})
+
+	t.Run("Someone else's", func(t *testing.T) {
+		c := newCLIRunner(t, ts, newAPIToken(t, app, 2, models.ScopeWrite))
+
+		status, _, stderr := c.run("", "delete", "1")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "snippet delete: ")
+		assert.Equal(t, len(app.snippets.(*mocks.SnippetModel).Deleted), 1)
+	})
+}
+
+func TestCLIUsage(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	c := newCLIRunner(t, ts, "")
+
+	status, _, stderr := c.run("")
+	assert.Equal(t, status, 2)
+	assert.StringContains(t, stderr, "Usage: snippet")
+
+	status, _, stderr = c.run("", "frobnicate")
+	assert.Equal(t, status, 2)
+	assert.StringContains(t, stderr, `unknown command "frobnicate"`)
+}
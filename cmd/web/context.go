@@ -2,4 +2,14 @@ package main
 
 type contextKey string
 
-const isAuthenticatedContextKey = contextKey("isAuthenticated")
+const (
+	isAuthenticatedContextKey = contextKey("isAuthenticated")
+	roleContextKey            = contextKey("role")
+	userIDContextKey          = contextKey("userID")
+	apiTokenContextKey        = contextKey("apiToken")
+
+	mustChangePasswordContextKey = contextKey("mustChangePassword")
+
+	clientIPContextKey     = contextKey("clientIP")
+	clientSchemeContextKey = contextKey("clientScheme")
+)
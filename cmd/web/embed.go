@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// the size of an embedded snippet when the consumer doesn't ask for less.
+// The height follows the snippet's length, between the two limits.
+const (
+	embedWidth     = 640
+	embedMinHeight = 120
+	embedMaxHeight = 480
+	embedLineSize  = 27
+)
+
+// embeddablePath matches the snippet URLs the oEmbed endpoint answers for
+var embeddablePath = regexp.MustCompile(`^/snippet/(?:view|embed)/([0-9]+)$`)
+
+// parseEmbedOrigins() reads a comma separated list of the origins allowed
+// to frame embedded snippets, such as "https://wiki.example.com". A host
+// may start with a "*." wildcard, and "*" on its own allows any site.
+func parseEmbedOrigins(s string) ([]string, error) {
+	origins := []string{}
+
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if field == "*" {
+			origins = append(origins, field)
+			continue
+		}
+
+		u, err := url.Parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("embed origin %q: %w", field, err)
+		}
+		if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return nil, fmt.Errorf("embed origin %q: must be a scheme and host, such as https://wiki.example.com", field)
+		}
+		// anything CSP would read as the end of the source is refused
+		if strings.ContainsAny(u.Host, " ;,'") || strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+			return nil, fmt.Errorf("embed origin %q: invalid host", field)
+		}
+
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+
+	return origins, nil
+}
+
+// allowFraming() lets the pages of a route be framed by the site itself and
+// the embedding origins the operator allows, loosening what secureHeaders()
+// set for every other route. X-Frame-Options can't name other sites, so it
+// is dropped, leaving frame-ancestors to browsers, which prefer it anyway.
+func (app *application) allowFraming(next http.Handler) http.Handler {
+	ancestors := strings.Join(append([]string{"'self'"}, app.embedOrigins...), " ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy(ancestors))
+		w.Header().Del("X-Frame-Options")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// embeddableSnippet() fetches a snippet that may be embedded in other
+// sites, which is a public or unlisted one that hasn't been hidden. Embeds
+// are seen without a session, so private snippets never are, even to their
+// owner, and the error says which status to answer with.
+func (app *application) embeddableSnippet(id int) (*models.Snippet, int, error) {
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if snippet.Hidden {
+		return nil, http.StatusNotFound, models.ErrNoRecord
+	}
+	if snippet.Visibility == models.VisibilityPrivate {
+		return nil, http.StatusUnauthorized, models.ErrNoRecord
+	}
+
+	return snippet, http.StatusOK, nil
+}
+
+// snippetEmbed() is the snippet on its own, with nothing around it, for
+// showing in an iframe on another site
+func (app *application) snippetEmbed(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	snippet, status, err := app.embeddableSnippet(id)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			app.serverError(w, err)
+		} else {
+			app.notFound(w)
+		}
+		return
+	}
+
+	data := &templateData{Snippet: snippet}
+	app.render(w, http.StatusOK, "embed.html", data)
+}
+
+// oEmbedResponse is the answer to an oEmbed request, in JSON or XML. Only
+// the "rich" type is given, as snippets are HTML.
+type oEmbedResponse struct {
+	XMLName      xml.Name `json:"-" xml:"oembed"`
+	Type         string   `json:"type" xml:"type"`
+	Version      string   `json:"version" xml:"version"`
+	Title        string   `json:"title" xml:"title"`
+	AuthorName   string   `json:"author_name,omitempty" xml:"author_name,omitempty"`
+	ProviderName string   `json:"provider_name" xml:"provider_name"`
+	ProviderURL  string   `json:"provider_url" xml:"provider_url"`
+	HTML         string   `json:"html" xml:"html"`
+	Width        int      `json:"width" xml:"width"`
+	Height       int      `json:"height" xml:"height"`
+}
+
+// oEmbed() answers oEmbed consumers, such as wikis and chat apps, with the
+// iframe that shows a snippet given its URL. Following the spec, an unknown
+// format is 501 Not Implemented and a private snippet 401 Unauthorized.
+func (app *application) oEmbed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "xml" {
+		app.clientError(w, http.StatusNotImplemented)
+		return
+	}
+
+	u, err := url.Parse(query.Get("url"))
+	if err != nil || u.Scheme+"://"+u.Host != app.baseURL {
+		app.notFound(w)
+		return
+	}
+
+	match := embeddablePath.FindStringSubmatch(u.Path)
+	if match == nil {
+		app.notFound(w)
+		return
+	}
+
+	id, err := strconv.Atoi(match[1])
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	snippet, status, err := app.embeddableSnippet(id)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			app.serverError(w, err)
+		} else {
+			app.clientError(w, status)
+		}
+		return
+	}
+
+	width, height := embedSize(snippet.Content)
+	for _, limit := range []struct {
+		size  *int
+		param string
+	}{
+		{&width, "maxwidth"},
+		{&height, "maxheight"},
+	} {
+		if v := query.Get(limit.param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				app.clientError(w, http.StatusBadRequest)
+				return
+			}
+			*limit.size = min(*limit.size, n)
+		}
+	}
+
+	rs := &oEmbedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        snippet.Title,
+		ProviderName: "Snippetbox",
+		ProviderURL:  app.baseURL + "/",
+		HTML: fmt.Sprintf(`<iframe src="%s/snippet/embed/%d" width="%d" height="%d" title="%s" style="border: 0" loading="lazy"></iframe>`,
+			app.baseURL, snippet.ID, width, height, html.EscapeString(snippet.Title)),
+		Width:  width,
+		Height: height,
+	}
+
+	if snippet.UserID != 0 {
+		user, err := app.users.Get(snippet.UserID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+		if user != nil {
+			rs.AuthorName = user.Name
+		}
+	}
+
+	if format == "xml" {
+		out, err := xml.MarshalIndent(rs, "", "  ")
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write([]byte(xml.Header))
+		w.Write(out)
+		return
+	}
+
+	js, err := json.Marshal(rs)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// embedSize() returns the width and height of the iframe for a snippet,
+// tall enough for its lines up to a point, past which it scrolls
+func embedSize(content string) (int, int) {
+	lines := strings.Count(strings.TrimRight(content, "\n"), "\n") + 1
+	height := min(max(embedMinHeight, 90+lines*embedLineSize), embedMaxHeight)
+	return embedWidth, height
+}
+
+// oEmbedLinks() returns the links oEmbed consumers discover a snippet's
+// oEmbed endpoint by, in the snippet page's head
+func (app *application) oEmbedLinks(s *models.Snippet) []alternateLink {
+	pageURL := url.QueryEscape(fmt.Sprintf("%s/snippet/view/%d", app.baseURL, s.ID))
+
+	return []alternateLink{
+		{Title: s.Title, Type: "application/json+oembed", URL: app.baseURL + "/oembed?format=json&url=" + pageURL},
+		{Title: s.Title, Type: "text/xml+oembed", URL: app.baseURL + "/oembed?format=xml&url=" + pageURL},
+	}
+}
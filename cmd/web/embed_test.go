@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+func TestSnippetEmbed(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, body := ts.get(t, "/snippet/embed/1")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<code>An old silent pond...</code>")
+	assert.StringContains(t, body, `<a href="/snippet/view/1" target="_blank" rel="noopener">View on SnippetBox</a>`)
+
+	// the embed may be framed by the allowed origins, unlike other pages
+	assert.Equal(t, header.Get("X-Frame-Options"), "")
+	assert.StringContains(t, header.Get("Content-Security-Policy"), "frame-ancestors 'self' https://wiki.example.com")
+
+	_, header, _ = ts.get(t, "/snippet/view/1")
+	assert.Equal(t, header.Get("X-Frame-Options"), "deny")
+	assert.StringContains(t, header.Get("Content-Security-Policy"), "frame-ancestors 'none'")
+
+	// private snippets aren't embedded, even for their owner
+	app.snippets.Insert(1, "Secret plans", "x", "", models.VisibilityPrivate, 7)
+	ts.login(t)
+
+	code, _, _ = ts.get(t, "/snippet/embed/2")
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, _, _ = ts.get(t, "/snippet/embed/99")
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestOEmbed(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	oEmbedPath := func(pageURL, format string, extra ...string) string {
+		query := url.Values{"url": {pageURL}}
+		if format != "" {
+			query.Set("format", format)
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			query.Set(extra[i], extra[i+1])
+		}
+		return "/oembed?" + query.Encode()
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		code, header, body := ts.get(t, oEmbedPath("https://localhost:4000/snippet/view/1", ""))
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, header.Get("Content-Type"), "application/json")
+
+		var rs oEmbedResponse
+		err := json.Unmarshal([]byte(body), &rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, rs.Type, "rich")
+		assert.Equal(t, rs.Version, "1.0")
+		assert.Equal(t, rs.Title, "An old silent pond")
+		assert.Equal(t, rs.AuthorName, "Alice")
+		assert.Equal(t, rs.ProviderURL, "https://localhost:4000/")
+		assert.Equal(t, rs.Width, embedWidth)
+		assert.Equal(t, rs.Height, embedMinHeight)
+		assert.StringContains(t, rs.HTML, `<iframe src="https://localhost:4000/snippet/embed/1" width="640" height="120"`)
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		code, header, body := ts.get(t, oEmbedPath("https://localhost:4000/snippet/embed/1", "xml", "maxwidth", "400"))
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, header.Get("Content-Type"), "text/xml; charset=utf-8")
+
+		var rs oEmbedResponse
+		err := xml.Unmarshal([]byte(body), &rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, rs.Type, "rich")
+		assert.Equal(t, rs.Width, 400)
+		assert.StringContains(t, rs.HTML, `width="400"`)
+	})
+
+	app.snippets.Insert(1, "Secret plans", "x", "", models.VisibilityPrivate, 7)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+	}{
+		{"Private snippet", oEmbedPath("https://localhost:4000/snippet/view/2", "json"), http.StatusUnauthorized},
+		{"Unknown snippet", oEmbedPath("https://localhost:4000/snippet/view/99", "json"), http.StatusNotFound},
+		{"Other site", oEmbedPath("https://example.com/snippet/view/1", "json"), http.StatusNotFound},
+		{"Not a snippet", oEmbedPath("https://localhost:4000/admin", "json"), http.StatusNotFound},
+		{"No URL", "/oembed", http.StatusNotFound},
+		{"Unknown format", oEmbedPath("https://localhost:4000/snippet/view/1", "yaml"), http.StatusNotImplemented},
+		{"Bad size", oEmbedPath("https://localhost:4000/snippet/view/1", "json", "maxheight", "tall"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestOEmbedDiscovery(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/snippet/view/1")
+	assert.StringContains(t, body, `title="An old silent pond" href="https://localhost:4000/oembed?format=json&amp;url=https%3A%2F%2Flocalhost%3A4000%2Fsnippet%2Fview%2F1">`)
+	assert.StringContains(t, body, `title="An old silent pond" href="https://localhost:4000/oembed?format=xml&amp;url=https%3A%2F%2Flocalhost%3A4000%2Fsnippet%2Fview%2F1">`)
+
+	// private snippets can't be embedded, so aren't offered
+	app.snippets.Insert(1, "Secret plans", "x", "", models.VisibilityPrivate, 7)
+	ts.login(t)
+
+	_, _, body = ts.get(t, "/snippet/view/2")
+	assert.Equal(t, strings.Contains(body, "/oembed?"), false)
+}
+
+func TestEmbedSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantHeight int
+	}{
+		{"One line", "x", embedMinHeight},
+		{"Trailing newline", "x\n", embedMinHeight},
+		{"A few lines", "1\n2\n3\n4\n5\n6", 90 + 6*embedLineSize},
+		{"Many lines", strings.Repeat("x\n", 30), embedMaxHeight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := embedSize(tt.content)
+			assert.Equal(t, width, embedWidth)
+			assert.Equal(t, height, tt.wantHeight)
+		})
+	}
+}
+
+func TestParseEmbedOrigins(t *testing.T) {
+	origins, err := parseEmbedOrigins("")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(origins), 0)
+
+	origins, err = parseEmbedOrigins("https://wiki.example.com/, http://*.example.org:8080, *")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(origins), 3)
+	assert.Equal(t, origins[0], "https://wiki.example.com")
+	assert.Equal(t, origins[1], "http://*.example.org:8080")
+	assert.Equal(t, origins[2], "*")
+
+	for _, bad := range []string{"wiki.example.com", "ftp://example.com", "https://example.com/wiki", "https://ex*ample.com", "https://example.com;script-src"} {
+		_, err = parseEmbedOrigins(bad)
+		assert.Equal(t, err != nil, true)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// feedSize is how many snippets a feed holds
+const feedSize = 20
+
+// feedLinks() returns the links to the Atom and RSS versions of a feed,
+// whose path is given without the extension
+func feedLinks(title, feedPath string) []alternateLink {
+	return []alternateLink{
+		{Title: title, Type: "application/atom+xml", URL: feedPath + ".atom"},
+		{Title: title + " (RSS)", Type: "application/rss+xml", URL: feedPath + ".rss"},
+	}
+}
+
+// feed is what goes into a feed, whichever format it is in
+type feed struct {
+	Title    string
+	Author   string
+	Snippets []*models.Snippet
+}
+
+// updated() returns when the newest snippet in the feed was created, as
+// snippets can't be edited. The feed also changes when one is deleted,
+// hidden or expires, which leaves no time behind.
+func (f *feed) updated() time.Time {
+	if len(f.Snippets) == 0 {
+		return time.Time{}
+	}
+	return f.Snippets[0].Created.UTC()
+}
+
+func (app *application) siteFeed(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.LatestFor(0, "", "", feedSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.serveFeed(w, r, &feed{Title: "Latest snippets - Snippetbox", Author: "Snippetbox", Snippets: snippets})
+}
+
+// userFeed() is the feed of one user's snippets, at /user/feed/<id>.atom
+// or .rss
+func (app *application) userFeed(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	file := params.ByName("file")
+	ext := path.Ext(file)
+	if ext != ".atom" && ext != ".rss" {
+		app.notFound(w)
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimSuffix(file, ext))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	user, err := app.users.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	snippets, err := app.snippets.LatestFor(user.ID, "", "", feedSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.serveFeed(w, r, &feed{Title: fmt.Sprintf("Snippets by %s - Snippetbox", user.Name), Author: user.Name, Snippets: snippets})
+}
+
+// languageFeed() is the feed of snippets in one language
+func (app *application) languageFeed(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	language := params.ByName("language")
+	if language == "" || !models.ValidLanguage(language) {
+		app.notFound(w)
+		return
+	}
+
+	snippets, err := app.snippets.LatestFor(0, language, "", feedSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.serveFeed(w, r, &feed{Title: fmt.Sprintf("Latest %s snippets - Snippetbox", language), Author: "Snippetbox", Snippets: snippets})
+}
+
+// tagFeed() is the feed of snippets with one tag
+func (app *application) tagFeed(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag := params.ByName("tag")
+	if !models.ValidTag(tag) {
+		app.notFound(w)
+		return
+	}
+
+	snippets, err := app.snippets.LatestFor(0, "", tag, feedSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.serveFeed(w, r, &feed{Title: fmt.Sprintf("Latest snippets tagged %s - Snippetbox", tag), Author: "Snippetbox", Snippets: snippets})
+}
+
+// serveFeed() writes the feed as Atom or RSS, going by the path's
+// extension. The ETag is a hash of the feed, so it changes whenever a
+// snippet comes or goes, and requests with it in If-None-Match are
+// answered 304 Not Modified. There is no Last-Modified, as updated()
+// misses snippets going.
+func (app *application) serveFeed(w http.ResponseWriter, r *http.Request, f *feed) {
+	self := app.baseURL + r.URL.Path
+
+	var v any
+	contentType := "application/atom+xml; charset=utf-8"
+	if path.Ext(r.URL.Path) == ".rss" {
+		v = app.rssFeed(f, self)
+		contentType = "application/rss+xml; charset=utf-8"
+	} else {
+		v = app.atomFeed(f, self)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "  ")
+	err := enc.Encode(v)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	// feed readers poll, so let them and any cache in between keep the
+	// feed for a few minutes
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string        `xml:"id"`
+	Title     string        `xml:"title"`
+	Updated   string        `xml:"updated"`
+	Published string        `xml:"published"`
+	Link      atomLink      `xml:"link"`
+	Category  *atomCategory `xml:"category"`
+	Content   atomContent   `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// atomTime() formats a time as Atom wants, or the start of the Unix epoch
+// for an empty feed, which has no time of its own
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (app *application) atomFeed(f *feed, self string) *atomFeed {
+	a := &atomFeed{
+		ID:      self,
+		Title:   f.Title,
+		Updated: atomTime(f.updated()),
+		Author:  atomPerson{Name: f.Author},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: self},
+			{Rel: "alternate", Type: "text/html", Href: app.baseURL + "/"},
+		},
+	}
+
+	for _, s := range f.Snippets {
+		url := fmt.Sprintf("%s/snippet/view/%d", app.baseURL, s.ID)
+
+		entry := atomEntry{
+			ID:        url,
+			Title:     s.Title,
+			Updated:   atomTime(s.Created),
+			Published: atomTime(s.Created),
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: url},
+			Content:   atomContent{Type: "text", Text: s.Content},
+		}
+		if s.Language != "" {
+			entry.Category = &atomCategory{Term: s.Language}
+		}
+
+		a.Entries = append(a.Entries, entry)
+	}
+
+	return a
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Text        string `xml:",chardata"`
+}
+
+func (app *application) rssFeed(f *feed, self string) *rssFeed {
+	channel := rssChannel{
+		Title:       f.Title,
+		Link:        app.baseURL + "/",
+		Description: f.Title,
+		Self:        atomLink{Rel: "self", Type: "application/rss+xml", Href: self},
+	}
+	if updated := f.updated(); !updated.IsZero() {
+		channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+
+	for _, s := range f.Snippets {
+		url := fmt.Sprintf("%s/snippet/view/%d", app.baseURL, s.ID)
+
+		channel.Items = append(channel.Items, rssItem{
+			Title:       s.Title,
+			Link:        url,
+			GUID:        rssGUID{IsPermaLink: true, Text: url},
+			PubDate:     s.Created.UTC().Format(time.RFC1123Z),
+			Category:    s.Language,
+			Description: s.Content,
+		})
+	}
+
+	return &rssFeed{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel}
+}
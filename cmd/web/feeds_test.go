@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// getWithHeader() makes a GET request with the given headers, the way a
+// feed reader checking for changes would
+func (ts *testServer) getWithHeader(t *testing.T, urlPath string, header http.Header) (int, http.Header, string) {
+	req, err := http.NewRequest(http.MethodGet, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(body)
+}
+
+func TestSiteFeed(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	app.snippets.Insert(1, "Secret plans", "x", "", models.VisibilityPrivate, 7)
+
+	t.Run("Atom", func(t *testing.T) {
+		code, header, body := ts.get(t, "/feed.atom")
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, header.Get("Content-Type"), "application/atom+xml; charset=utf-8")
+
+		var feed atomFeed
+		err := xml.Unmarshal([]byte(body), &feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, feed.ID, "https://localhost:4000/feed.atom")
+		assert.Equal(t, feed.Title, "Latest snippets - Snippetbox")
+		// private snippets are left out
+		assert.Equal(t, len(feed.Entries), 1)
+
+		entry := feed.Entries[0]
+		assert.Equal(t, entry.ID, "https://localhost:4000/snippet/view/1")
+		assert.Equal(t, entry.Title, "An old silent pond")
+		assert.Equal(t, entry.Content.Text, "An old silent pond...")
+		assert.Equal(t, feed.Updated, entry.Updated)
+		assert.Equal(t, header.Get("Last-Modified"), "")
+	})
+
+	t.Run("RSS", func(t *testing.T) {
+		code, header, body := ts.get(t, "/feed.rss")
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, header.Get("Content-Type"), "application/rss+xml; charset=utf-8")
+
+		var feed rssFeed
+		err := xml.Unmarshal([]byte(body), &feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, feed.Version, "2.0")
+		assert.Equal(t, len(feed.Channel.Items), 1)
+		assert.Equal(t, feed.Channel.Items[0].GUID.Text, "https://localhost:4000/snippet/view/1")
+		assert.StringContains(t, body, `<atom:link rel="self" type="application/rss+xml" href="https://localhost:4000/feed.rss"></atom:link>`)
+	})
+}
+
+func TestFeedConditionalRequests(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, header, _ := ts.get(t, "/feed.atom")
+	etag := header.Get("ETag")
+
+	code, _, body := ts.getWithHeader(t, "/feed.atom", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, code, http.StatusNotModified)
+	assert.Equal(t, body, "")
+
+	// dates aren't trusted, as a deleted snippet leaves none behind
+	code, _, _ = ts.getWithHeader(t, "/feed.atom", http.Header{"If-Modified-Since": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}})
+	assert.Equal(t, code, http.StatusOK)
+
+	// the RSS feed is a different document
+	code, _, _ = ts.getWithHeader(t, "/feed.rss", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, code, http.StatusOK)
+
+	// a new snippet changes both
+	app.snippets.Insert(1, "New", "x", "", models.VisibilityPublic, 7)
+
+	code, header, _ = ts.getWithHeader(t, "/feed.atom", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("ETag") != etag, true)
+	etag = header.Get("ETag")
+
+	// and so does hiding one, though the newest snippet is the same
+	app.snippets.SetHidden(1, true)
+
+	code, header, _ = ts.getWithHeader(t, "/feed.atom", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("ETag") != etag, true)
+}
+
+func TestUserAndLanguageFeeds(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	app.snippets.Insert(1, "Hello", "print('hello')", "python", models.VisibilityPublic, 7)
+	app.snippets.SetTags(2, []string{"greeting"})
+
+	tests := []struct {
+		name       string
+		urlPath    string
+		wantCode   int
+		wantTitle  string
+		wantTitles []string
+	}{
+		{"User", "/user/feed/1.atom", http.StatusOK, "Snippets by Alice - Snippetbox", []string{"Hello", "An old silent pond"}},
+		{"User with no snippets", "/user/feed/2.atom", http.StatusOK, "Snippets by Bob - Snippetbox", []string{}},
+		{"Language", "/language/python/feed.atom", http.StatusOK, "Latest python snippets - Snippetbox", []string{"Hello"}},
+		{"Unknown user", "/user/feed/99.atom", http.StatusNotFound, "", nil},
+		{"Tag", "/tag/greeting/feed.atom", http.StatusOK, "Latest snippets tagged greeting - Snippetbox", []string{"Hello"}},
+		{"Unused tag", "/tag/farewell/feed.atom", http.StatusOK, "Latest snippets tagged farewell - Snippetbox", []string{}},
+		{"Unknown language", "/language/cobol/feed.atom", http.StatusNotFound, "", nil},
+		{"Bad tag", "/tag/Not%20a%20tag/feed.atom", http.StatusNotFound, "", nil},
+		{"Bad user", "/user/feed/x.atom", http.StatusNotFound, "", nil},
+		{"Bad format", "/user/feed/1.json", http.StatusNotFound, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var feed atomFeed
+			err := xml.Unmarshal([]byte(body), &feed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, feed.Title, tt.wantTitle)
+			titles := []string{}
+			for _, e := range feed.Entries {
+				titles = append(titles, e.Title)
+			}
+			assert.Equal(t, len(titles), len(tt.wantTitles))
+			for i := range titles {
+				assert.Equal(t, titles[i], tt.wantTitles[i])
+			}
+		})
+	}
+
+	code, _, body := ts.get(t, "/user/feed/1.rss")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<category>python</category>")
+
+	code, header, _ := ts.postForm(t, "/user/feed/1.atom", nil)
+	assert.Equal(t, code, http.StatusMethodNotAllowed)
+	assert.StringContains(t, header.Get("Allow"), http.MethodGet)
+}
+
+func TestFeedAutodiscovery(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/")
+	assert.StringContains(t, body, `<link rel="alternate" type="application/atom+xml" title="Latest snippets" href="/feed.atom">`)
+	assert.StringContains(t, body, `<link rel="alternate" type="application/rss+xml" title="Latest snippets (RSS)" href="/feed.rss">`)
+
+	// a snippet's page also links the feeds it is in
+	app.snippets.Insert(1, "Hello", "print('hello')", "python", models.VisibilityPublic, 7)
+	app.snippets.SetTags(2, []string{"greeting"})
+
+	_, _, body = ts.get(t, "/snippet/view/2")
+	assert.StringContains(t, body, `title="Snippets by this author" href="/user/feed/1.atom">`)
+	assert.StringContains(t, body, `title="Latest python snippets" href="/language/python/feed.atom">`)
+	assert.StringContains(t, body, `title="Latest snippets tagged greeting" href="/tag/greeting/feed.atom">`)
+
+	// and people can follow the author's feed from it, or their own from
+	// their account page
+	assert.StringContains(t, body, `<a href="/user/feed/1.atom" title="Feed of snippets by this author">Author's feed</a>`)
+
+	ts.login(t)
+
+	_, _, body = ts.get(t, "/account")
+	assert.StringContains(t, body, `title="Your snippets" href="/user/feed/1.atom">`)
+	assert.StringContains(t, body, `<a href="/user/feed/1.atom">Atom</a> or <a href="/user/feed/1.rss">RSS</a>`)
+}
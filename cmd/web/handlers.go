@@ -1,21 +1,28 @@
 package main
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/secrets"
 	"github.com/PPRAMANIK62/snippetbox/internal/validator"
 	"github.com/go-playground/form/v4"
 	"github.com/julienschmidt/httprouter"
 )
 
 type snippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
+	Title               string            `form:"title"`
+	Content             string            `form:"content"`
+	Language            string            `form:"language"`
+	Tags                string            `form:"tags"`
+	Visibility          models.Visibility `form:"visibility"`
+	Expires             int               `form:"expires"`
+	Redact              bool              `form:"redact"`
+	Secrets             bool              `form:"-"`
 	validator.Validator `form:"-"`
 }
 
@@ -78,8 +85,47 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !app.canSee(r, snippet) {
+		app.notFound(w)
+		return
+	}
+
+	// moderators can still see a hidden snippet, everybody else is told
+	// it has been taken down
+	if snippet.Hidden && !app.role(r).Includes(models.RoleModerator) {
+		app.clientError(w, http.StatusUnavailableForLegalReasons)
+		return
+	}
+
+	tags, err := app.snippets.Tags(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
+	data.Tags = tags
+	data.Form = snippetReportForm{}
+
+	// how to embed it in other sites, for oEmbed consumers to find
+	if snippet.Visibility != models.VisibilityPrivate && !snippet.Hidden {
+		data.AlternateLinks = append(data.AlternateLinks, app.oEmbedLinks(snippet)...)
+	}
+
+	// the feeds this snippet would be in, for following more like it
+	if snippet.Visibility == models.VisibilityPublic && !snippet.Hidden {
+		if snippet.UserID != 0 {
+			data.FeedURL = fmt.Sprintf("/user/feed/%d", snippet.UserID)
+			data.AlternateLinks = append(data.AlternateLinks, feedLinks("Snippets by this author", data.FeedURL)...)
+		}
+		if snippet.Language != "" {
+			data.AlternateLinks = append(data.AlternateLinks, feedLinks(fmt.Sprintf("Latest %s snippets", snippet.Language), "/language/"+snippet.Language+"/feed")...)
+		}
+		for _, tag := range tags {
+			data.AlternateLinks = append(data.AlternateLinks, feedLinks(fmt.Sprintf("Latest snippets tagged %s", tag), "/tag/"+tag+"/feed")...)
+		}
+	}
 
 	app.render(w, http.StatusOK, "view.html", data)
 }
@@ -88,8 +134,10 @@ func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 
 	data.Form = snippetCreateForm{
-		Expires: 365,
+		Visibility: models.VisibilityPublic,
+		Expires:    365,
 	}
+	data.Languages = models.Languages
 
 	app.render(w, http.StatusOK, "create.html", data)
 }
@@ -103,10 +151,15 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	form.CheckField(form.Validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(form.Validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(form.Validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	if form.Visibility == "" {
+		form.Visibility = models.VisibilityPublic
+	}
+
+	var redacted int
+	redacted, form.Secrets = app.checkSnippet(&form.Validator, &form.Title, &form.Content, form.Language, form.Visibility, form.Expires, form.Redact)
+
+	tags, ok := models.ParseTags(form.Tags)
+	form.CheckField(ok, "tags", fmt.Sprintf("Up to %d tags of letters, numbers and dashes, separated by commas or spaces", models.MaxTags))
 
 	// if there are any validation errors re-display the create.html
 	// template, passing in the snippetCreateForm instance as dynamic data
@@ -114,25 +167,84 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
+		data.Languages = models.Languages
 		app.render(w, http.StatusUnprocessableEntity, "create.html", data)
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	id, err := app.snippets.Insert(userID, form.Title, form.Content, form.Language, form.Visibility, form.Expires)
 	if err != nil {
 		app.serverError(w, err)
+		return
+	}
+
+	if len(tags) > 0 {
+		err = app.snippets.SetTags(id, tags)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
 	}
 
+	var metadata map[string]any
+	if redacted > 0 {
+		metadata = map[string]any{"secrets_redacted": redacted}
+	}
+	app.audit(r, "snippet.create", fmt.Sprintf("snippet:%d", id), metadata)
+	app.snippetCreated(id)
+
 	app.sessionManager.Put(r.Context(), "flash", "Snippet sucessfully created!")
 
 	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
 }
 
+// canSee() reports whether the current user may see the snippet, private
+// snippets being only for their owner. Hidden snippets are dealt with
+// separately, as everyone is told they have been taken down.
+func (app *application) canSee(r *http.Request, s *models.Snippet) bool {
+	return s.Visibility != models.VisibilityPrivate || (s.UserID != 0 && s.UserID == app.userID(r))
+}
+
+// checkSnippet() validates a new snippet, from the form or the API. It
+// looks for credentials pasted by mistake, taking them out if redact is
+// set, and returns how many were redacted and whether any were left in.
+// Only public snippets are refused for them, as unlisted and private ones
+// aren't listed for strangers to find.
+func (app *application) checkSnippet(v *validator.Validator, title, content *string, language string, visibility models.Visibility, expires int, redact bool) (int, bool) {
+	titleSecrets := app.secretScanner.Scan(*title)
+	contentSecrets := app.secretScanner.Scan(*content)
+	redacted := 0
+
+	if redact {
+		*title = secrets.Redact(*title, titleSecrets)
+		*content = secrets.Redact(*content, contentSecrets)
+		redacted = len(titleSecrets) + len(contentSecrets)
+		titleSecrets, contentSecrets = nil, nil
+	}
+	if visibility != models.VisibilityPublic {
+		titleSecrets, contentSecrets = nil, nil
+	}
+
+	v.CheckField(v.NotBlank(*title), "title", "This field cannot be blank")
+	v.CheckField(v.MaxChars(*title, 100), "title", "This field cannot be more than 100 characters long")
+	v.CheckField(len(titleSecrets) == 0, "title", secretsError(titleSecrets))
+	v.CheckField(v.NotBlank(*content), "content", "This field cannot be blank")
+	v.CheckField(len(contentSecrets) == 0, "content", secretsError(contentSecrets))
+	v.CheckField(models.ValidLanguage(language), "language", "This field must be one of the languages listed")
+	v.CheckField(visibility.Valid(), "visibility", "This field must be public, unlisted or private")
+	v.CheckField(validator.PermittedValue(expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+
+	return redacted, len(titleSecrets)+len(contentSecrets) > 0
+}
+
 type userSignupForm struct {
 	Name                string `form:"name"`
 	Email               string `form:"email"`
 	Password            string `form:"password"`
 	validator.Validator `form:"-"`
+	challengeForm
 }
 
 func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
@@ -152,6 +264,12 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	app.checkChallenge(r, form.challengeForm, &form.Validator)
+	if form.Trapped {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
 	// validate form contents
 	form.CheckField(form.Validator.NotBlank(form.Name), "name", "This field cannot be blank")
 	form.CheckField(form.Validator.NotBlank(form.Email), "email", "This field cannot be blank")
@@ -182,6 +300,8 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	app.audit(r, "user.signup", "", map[string]any{"email": form.Email})
+
 	// add a confirmation flash message to the session
 	app.sessionManager.Put(r.Context(), "flash", http.StatusSeeOther)
 
@@ -192,7 +312,9 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 type userLoginForm struct {
 	Email               string `form:"email"`
 	Password            string `form:"password"`
+	RememberMe          bool   `form:"remember_me"`
 	validator.Validator `form:"-"`
+	challengeForm
 }
 
 func (app *application) userLogin(w http.ResponseWriter, r *http.Request) {
@@ -210,6 +332,12 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	app.checkChallenge(r, form.challengeForm, &form.Validator)
+	if form.Trapped {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
 	// validation checks
 	form.CheckField(form.Validator.NotBlank(form.Email), "email", "This field cannot be blank")
 	form.CheckField(form.Validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
@@ -222,10 +350,41 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// refuse to check the password at all while the client or the
+	// account is locked out, whether or not the account exists
+	wait, err := app.loginRetryAfter(r, form.Email)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if wait > 0 {
+		app.audit(r, "user.login_failed", "", map[string]any{"email": form.Email, "reason": "locked"})
+
+		form.AddNonFieldError("Too many failed login attempts. Please try again later.")
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "login.html", data)
+		return
+	}
+
 	// check whether the credentials are valid
-	id, err := app.users.Authenticate(form.Email, form.Password)
+	id, err := app.authenticator.Authenticate(form.Email, form.Password)
 	if err != nil {
+		// count every attempt that didn't log in, even when no
+		// authenticator could check it, so an outage doesn't lift the
+		// limit on guessing
+		lockErr := app.recordLoginFailure(r, form.Email)
+		if lockErr != nil {
+			app.serverError(w, lockErr)
+			return
+		}
+
 		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.audit(r, "user.login_failed", "", map[string]any{"email": form.Email, "reason": "invalid_credentials"})
+
 			form.AddNonFieldError("Email or password is incorrect")
 
 			data := app.newTemplateData(r)
@@ -237,16 +396,45 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// cheange the current session id (when the auth state changes or
-	// privilege level changes for the user) (eg. login & logout operations)
-	err = app.sessionManager.RenewToken(r.Context())
+	err = app.loginAttempts.Reset(accountLoginKey(form.Email))
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	// add the id of the current user to the session
-	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	var series string
+	if form.RememberMe {
+		series = rand.Text()
+	}
+
+	// add the id of the current user to a fresh session
+	err = app.startSession(r, id, series)
+	if err != nil {
+		if errors.Is(err, models.ErrAccountDisabled) {
+			app.audit(r, "user.login_failed", fmt.Sprintf("user:%d", id), map[string]any{"email": form.Email, "reason": "disabled"})
+
+			form.AddNonFieldError("This account has been disabled")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, http.StatusForbidden, "login.html", data)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// start a "remember me" series if asked to, which keeps the user
+	// logged in after the session itself has expired
+	if form.RememberMe {
+		err = app.issuePersistentLogin(w, id, series)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	app.audit(r, "user.login", fmt.Sprintf("user:%d", id), map[string]any{"method": "password", "remember_me": form.RememberMe})
 
 	// add a flash message to the session
 	app.sessionManager.Put(r.Context(), "flash", "You've been logged in successfully!")
@@ -256,8 +444,22 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
+	// record it while we still know who is logging out
+	app.audit(r, "user.logout", "", nil)
+
+	// forget this device, along with its "remember me" cookie
+	session, err := app.userSessions.Get(app.sessionManager.Token(r.Context()))
+	if err == nil {
+		err = app.revokeSession(session)
+	}
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, err)
+		return
+	}
+	clearPersistentLoginCookie(w)
+
 	// change the session id
-	err := app.sessionManager.RenewToken(r.Context())
+	err = app.sessionManager.RenewToken(r.Context())
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -3,9 +3,12 @@ package main
 import (
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
 )
 
 func TestPing(t *testing.T) {
@@ -107,7 +110,8 @@ func TestUserSignup(t *testing.T) {
 		{
 			name: "Valid submission",
 			userName: validName,
-			userEmail: validEmail,
+			// validEmail is taken by the mock user
+			userEmail: "new@example.com",
 			userPassword: validPassword,
 			csrfToken: validCSRFToken,
 			wantCode: http.StatusSeeOther,
@@ -184,6 +188,10 @@ func TestUserSignup(t *testing.T) {
 			form.Add("password", tt.userPassword)
 			form.Add("csrf_token", tt.csrfToken)
 
+			// each challenge can only be used once, so fetch a new one
+			_, _, page := ts.get(t, "/user/signup")
+			solveChallenge(t, page, form)
+
 			code, _, body := ts.postForm(t, "/user/signup", form)
 
 			assert.Equal(t, code, tt.wantCode)
@@ -194,3 +202,234 @@ func TestUserSignup(t *testing.T) {
 		})
 	}
 }
+
+// directoryAuthenticator stands in for an external authenticator, such
+// as LDAP, that knows a user the local users table doesn't
+type directoryAuthenticator struct{}
+
+func (a directoryAuthenticator) Authenticate(email, password string) (int, error) {
+	if email == "directory@example.com" && password == "directory" {
+		return 1, nil
+	}
+	return 0, models.ErrInvalidCredentials
+}
+
+func TestUserLoginPost(t *testing.T) {
+	app := newTestApplication(t)
+	app.authenticator = &models.Authenticators{List: []models.Authenticator{directoryAuthenticator{}, app.users}}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		userEmail    string
+		userPassword string
+		wantCode     int
+		wantBody     string
+	}{
+		{
+			name:         "Local user",
+			userEmail:    "test@example.com",
+			userPassword: "password",
+			wantCode:     http.StatusSeeOther,
+		},
+		{
+			name:         "Directory user",
+			userEmail:    "directory@example.com",
+			userPassword: "directory",
+			wantCode:     http.StatusSeeOther,
+		},
+		{
+			name:         "Wrong password",
+			userEmail:    "test@example.com",
+			userPassword: "wrong-password",
+			wantCode:     http.StatusUnprocessableEntity,
+			wantBody:     "Email or password is incorrect",
+		},
+		{
+			name:         "Empty email",
+			userEmail:    "",
+			userPassword: "password",
+			wantCode:     http.StatusUnprocessableEntity,
+			wantBody:     "This field cannot be blank",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, body := ts.get(t, "/user/login")
+
+			form := url.Values{}
+			form.Add("email", tt.userEmail)
+			form.Add("password", tt.userPassword)
+			form.Add("csrf_token", extractCSRFToken(t, body))
+			solveChallenge(t, body, form)
+
+			code, _, body := ts.postForm(t, "/user/login", form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetCreateSecrets(t *testing.T) {
+	// put together from pieces so this file doesn't trip secret scanners
+	awsKeyID := "AKIA" + "IOSFODNN7EXAMPLE"
+
+	tests := []struct {
+		name        string
+		title       string
+		content     string
+		visibility  string
+		redact      bool
+		wantCode    int
+		wantBody    string
+		wantContent string
+	}{
+		{
+			name:        "No secrets",
+			title:       "Config",
+			content:     "region = eu-west-1",
+			wantCode:    http.StatusSeeOther,
+			wantContent: "region = eu-west-1",
+		},
+		{
+			name:     "Secret in content",
+			title:    "Config",
+			content:  "region = eu-west-1\naws_access_key_id = " + awsKeyID,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "AWS access key ID on line 2",
+		},
+		{
+			name:     "Secret in title",
+			title:    "Key " + awsKeyID,
+			content:  "region = eu-west-1",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "AWS access key ID on line 1",
+		},
+		{
+			name:        "Redacted",
+			title:       "Config",
+			content:     "region = eu-west-1\naws_access_key_id = " + awsKeyID,
+			redact:      true,
+			wantCode:    http.StatusSeeOther,
+			wantContent: "region = eu-west-1\naws_access_key_id = [REDACTED]",
+		},
+		{
+			name:        "Private",
+			title:       "Config",
+			content:     "aws_access_key_id = " + awsKeyID,
+			visibility:  "private",
+			wantCode:    http.StatusSeeOther,
+			wantContent: "aws_access_key_id = " + awsKeyID,
+		},
+		{
+			name:        "Unlisted",
+			title:       "Config",
+			content:     "aws_access_key_id = " + awsKeyID,
+			visibility:  "unlisted",
+			wantCode:    http.StatusSeeOther,
+			wantContent: "aws_access_key_id = " + awsKeyID,
+		},
+		{
+			name:        "Private and redacted",
+			title:       "Config",
+			content:     "aws_access_key_id = " + awsKeyID,
+			visibility:  "private",
+			redact:      true,
+			wantCode:    http.StatusSeeOther,
+			wantContent: "aws_access_key_id = [REDACTED]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			ts.login(t)
+
+			_, _, body := ts.get(t, "/snippet/create")
+
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", tt.content)
+			form.Add("expires", "7")
+			form.Add("visibility", tt.visibility)
+			form.Add("csrf_token", extractCSRFToken(t, body))
+			if tt.redact {
+				form.Add("redact", "true")
+			}
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			inserted := app.snippets.(*mocks.SnippetModel).Inserted
+
+			if tt.wantCode == http.StatusSeeOther {
+				assert.Equal(t, len(inserted), 1)
+				assert.Equal(t, inserted[0].Content, tt.wantContent)
+				return
+			}
+
+			assert.Equal(t, len(inserted), 0)
+			assert.StringContains(t, body, tt.wantBody)
+			assert.StringContains(t, body, `name="redact"`)
+		})
+	}
+}
+
+func TestSnippetCreateTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     string
+		wantCode int
+		wantTags []string
+	}{
+		{"None", "", http.StatusSeeOther, nil},
+		{"Some", "HTTP, go go", http.StatusSeeOther, []string{"go", "http"}},
+		{"Invalid", "c#", http.StatusUnprocessableEntity, nil},
+		{"Too many", "a b c d e f", http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			ts.login(t)
+
+			_, _, body := ts.get(t, "/snippet/create")
+
+			form := url.Values{}
+			form.Add("title", "Hello")
+			form.Add("content", "fmt.Println(\"hello\")")
+			form.Add("tags", tt.tags)
+			form.Add("expires", "7")
+			form.Add("csrf_token", extractCSRFToken(t, body))
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			tagged := app.snippets.(*mocks.SnippetModel).Tagged
+			assert.Equal(t, strings.Join(tagged[2], ","), strings.Join(tt.wantTags, ","))
+
+			if tt.wantCode != http.StatusSeeOther {
+				assert.StringContains(t, body, "Up to 5 tags")
+				return
+			}
+
+			_, _, body = ts.get(t, "/snippet/view/2")
+			for _, tag := range tt.wantTags {
+				assert.StringContains(t, body, `<a href="/tag/`+tag+`/feed.atom"`)
+			}
+		})
+	}
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"strings"
 	"time"
 
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/secrets"
 	"github.com/justinas/nosurf"
 )
 
@@ -46,15 +51,40 @@ func (app *application) render(w http.ResponseWriter, status int, page string, d
 	buf.WriteTo(w)
 }
 
+// writeJSON() encodes data as JSON and sends it with the given status,
+// for the handful of endpoints that are called from JavaScript
+func (app *application) writeJSON(w http.ResponseWriter, status int, data any) {
+	js, err := json.Marshal(data)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
 func (app *application) newTemplateData(r *http.Request) *templateData {
 	return &templateData{
 		CurrentYear:     time.Now().Year(),
 		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
 		IsAuthenticated: app.isAuthenticated(r),
+		Role:            app.role(r),
 		CSRFToken:       nosurf.Token(r),
+		OIDCEnabled:     app.oidc != nil,
+		Reauthenticated: app.reauthenticated(r),
+		Challenge:       app.challenges.Issue(),
 	}
 }
 
+// role() returns the role of the logged in user, or "" if nobody is
+// logged in
+func (app *application) role(r *http.Request) models.Role {
+	role, _ := r.Context().Value(roleContextKey).(models.Role)
+	return role
+}
+
 func (app *application) isAuthenticated(r *http.Request) bool {
 	isAuthenticated, ok := r.Context().Value(isAuthenticatedContextKey).(bool)
 	if !ok {
@@ -62,3 +92,55 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 	return isAuthenticated
 }
+
+// userID() returns the id of the logged in user, or 0. API requests have
+// no session, so the token middleware puts the id in the context instead.
+func (app *application) userID(r *http.Request) int {
+	if id, ok := r.Context().Value(userIDContextKey).(int); ok {
+		return id
+	}
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
+// remoteIP() returns the address of the other end of the connection,
+// which is the client unless it came through a proxy. Use clientIP()
+// rather than this.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// secretsError() explains what the secret scanner found, for a field error
+func secretsError(findings []secrets.Finding) string {
+	if len(findings) == 0 {
+		return ""
+	}
+
+	found := []string{}
+	for i, f := range findings {
+		if i == 5 {
+			found = append(found, fmt.Sprintf("and %d more", len(findings)-i))
+			break
+		}
+		found = append(found, fmt.Sprintf("%s on line %d", f.Description, f.Line))
+	}
+
+	return fmt.Sprintf("This looks like it contains secrets (%s). Take them out, or tick the box below to have them redacted.", strings.Join(found, ", "))
+}
+
+// background() runs fn in a new goroutine, logging any panic instead of
+// letting it take down the whole server
+func (app *application) background(fn func()) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				app.errorLog.Print(fmt.Errorf("%s", err))
+			}
+		}()
+
+		fn()
+	}()
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/highlight"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// the size of a snippet's image. Its width can be picked between the
+// limits, and its height follows the snippet, up to imageMaxRows rows of
+// text, past which the rest is left out.
+const (
+	imageWidth    = 800
+	imageMinWidth = 320
+	imageMaxWidth = 1600
+	imageMaxRows  = 150
+	imageFontSize = 16
+	imagePadding  = 24
+	imageTabWidth = 4
+)
+
+// monoFont is Go Mono, which is compiled into the binary, so images can
+// be drawn without any fonts installed
+var monoFont = func() *opentype.Font {
+	f, err := opentype.Parse(gomono.TTF)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}()
+
+// imageTheme is the colours an image is drawn in
+type imageTheme struct {
+	Background color.RGBA
+	Gutter     color.RGBA
+	LineNumber color.RGBA
+	Kinds      map[highlight.Kind]color.RGBA
+}
+
+var imageThemes = map[string]*imageTheme{
+	"light": {
+		Background: color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		Gutter:     color.RGBA{0xF7, 0xF9, 0xFA, 0xFF},
+		LineNumber: color.RGBA{0xA0, 0xA4, 0xA8, 0xFF},
+		Kinds: map[highlight.Kind]color.RGBA{
+			highlight.Text:     {0x34, 0x49, 0x5E, 0xFF},
+			highlight.Keyword:  {0x8E, 0x44, 0xAD, 0xFF},
+			highlight.String:   {0x27, 0xAE, 0x60, 0xFF},
+			highlight.Comment:  {0x95, 0xA5, 0xA6, 0xFF},
+			highlight.Number:   {0xD3, 0x54, 0x00, 0xFF},
+			highlight.Inserted: {0x1E, 0x84, 0x49, 0xFF},
+			highlight.Deleted:  {0xC0, 0x39, 0x2B, 0xFF},
+		},
+	},
+	"dark": {
+		Background: color.RGBA{0x28, 0x2C, 0x34, 0xFF},
+		Gutter:     color.RGBA{0x21, 0x25, 0x2B, 0xFF},
+		LineNumber: color.RGBA{0x63, 0x6D, 0x83, 0xFF},
+		Kinds: map[highlight.Kind]color.RGBA{
+			highlight.Text:     {0xAB, 0xB2, 0xBF, 0xFF},
+			highlight.Keyword:  {0xC6, 0x78, 0xDD, 0xFF},
+			highlight.String:   {0x98, 0xC3, 0x79, 0xFF},
+			highlight.Comment:  {0x7F, 0x84, 0x8E, 0xFF},
+			highlight.Number:   {0xD1, 0x9A, 0x66, 0xFF},
+			highlight.Inserted: {0x98, 0xC3, 0x79, 0xFF},
+			highlight.Deleted:  {0xE0, 0x6C, 0x75, 0xFF},
+		},
+	},
+}
+
+// imageCache keeps the most recently used images, by the hash of what
+// they were drawn from, so a snippet shared in a busy chat is only drawn
+// once. The least recently used are dropped once it holds size images.
+type imageCache struct {
+	mu     sync.Mutex
+	size   int
+	images map[string]*list.Element
+	lru    *list.List
+}
+
+type cachedImage struct {
+	key string
+	png []byte
+}
+
+func newImageCache(size int) *imageCache {
+	return &imageCache{
+		size:   size,
+		images: make(map[string]*list.Element),
+		lru:    list.New(),
+	}
+}
+
+func (c *imageCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.images[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cachedImage).png, true
+}
+
+func (c *imageCache) put(key string, png []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size < 1 {
+		return
+	}
+	if e, ok := c.images[key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.images[key] = c.lru.PushFront(&cachedImage{key: key, png: png})
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.images, oldest.Value.(*cachedImage).key)
+	}
+}
+
+// imageKey() is the hash of everything an image is drawn from, used both
+// to cache it and as its ETag
+func imageKey(content, language, theme string, width int) string {
+	h := sha256.New()
+	for _, s := range []string{content, language, theme, strconv.Itoa(width)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// snippetImage() is the snippet drawn as a PNG, highlighted and with line
+// numbers, for slides and chat apps that don't show code. The theme and
+// width are picked with ?theme=light|dark and ?width=<pixels>.
+func (app *application) snippetImage(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	name, ok := strings.CutSuffix(params.ByName("file"), ".png")
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	id, err := strconv.Atoi(name)
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	themeName := query.Get("theme")
+	if themeName == "" {
+		themeName = "light"
+	}
+	theme, ok := imageThemes[themeName]
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	width := imageWidth
+	if v := query.Get("width"); v != "" {
+		width, err = strconv.Atoi(v)
+		if err != nil || width < imageMinWidth || width > imageMaxWidth {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	snippet, status, err := app.embeddableSnippet(id)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			app.serverError(w, err)
+		} else {
+			app.notFound(w)
+		}
+		return
+	}
+
+	key := imageKey(snippet.Content, snippet.Language, themeName, width)
+
+	img, ok := app.images.get(key)
+	if !ok {
+		img, err = drawSnippet(snippet.Content, snippet.Language, theme, width)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		app.images.put(key, img)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("ETag", `"`+key+`"`)
+	// like the feeds, the image may be kept for a few minutes, after which
+	// the ETag tells whether the snippet is still the same
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(img))
+}
+
+// imageRow is one row of text in an image. A line too long for the
+// image is wrapped onto more rows, only the first of which is numbered.
+type imageRow struct {
+	number int
+	tokens []highlight.Token
+}
+
+// imageRows() lays the highlighted lines out in rows of at most columns
+// characters, expanding tabs, and reports whether any were left out
+func imageRows(lines [][]highlight.Token, columns int) ([]imageRow, bool) {
+	rows := []imageRow{}
+
+	for i, line := range lines {
+		row := imageRow{number: i + 1}
+		column := 0
+
+		for _, token := range line {
+			var text strings.Builder
+
+			for _, r := range token.Text {
+				n := 1
+				if r == '\t' {
+					r, n = ' ', imageTabWidth-column%imageTabWidth
+				} else if !unicode.IsPrint(r) {
+					r = utf8.RuneError
+				}
+
+				for range n {
+					if column == columns {
+						if text.Len() > 0 {
+							row.tokens = append(row.tokens, highlight.Token{Kind: token.Kind, Text: text.String()})
+							text.Reset()
+						}
+						rows = append(rows, row)
+						if len(rows) == imageMaxRows {
+							return rows, true
+						}
+						row, column = imageRow{}, 0
+					}
+					text.WriteRune(r)
+					column++
+				}
+			}
+
+			if text.Len() > 0 {
+				row.tokens = append(row.tokens, highlight.Token{Kind: token.Kind, Text: text.String()})
+			}
+		}
+
+		rows = append(rows, row)
+		if len(rows) == imageMaxRows && i < len(lines)-1 {
+			return rows, true
+		}
+	}
+
+	return rows, false
+}
+
+// drawSnippet() draws the content, highlighted for its language, as a PNG
+// of the given width
+func drawSnippet(content, language string, theme *imageTheme, width int) ([]byte, error) {
+	// a face isn't safe for concurrent use, so each image has its own
+	face, err := opentype.NewFace(monoFont, &opentype.FaceOptions{
+		Size:    imageFontSize,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	advance, _ := face.GlyphAdvance('0')
+	charWidth := advance.Ceil()
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil() + 4
+
+	lines := highlight.Lines(strings.TrimRight(content, "\n"), language)
+
+	// the gutter fits the largest line number
+	digits := len(strconv.Itoa(len(lines)))
+	gutter := imagePadding + digits*charWidth + imagePadding/2
+	columns := max(1, (width-gutter-imagePadding*2)/charWidth)
+
+	rows, truncated := imageRows(lines, columns)
+	if truncated {
+		rows = append(rows, imageRow{tokens: []highlight.Token{{Kind: highlight.Comment, Text: "…"}}})
+	}
+
+	height := imagePadding*2 + len(rows)*lineHeight
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(theme.Background), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, gutter, height), image.NewUniform(theme.Gutter), image.Point{}, draw.Src)
+
+	d := &font.Drawer{Dst: img, Face: face}
+
+	for i, row := range rows {
+		baseline := imagePadding + i*lineHeight + metrics.Ascent.Ceil()
+
+		if row.number > 0 {
+			number := strconv.Itoa(row.number)
+			d.Src = image.NewUniform(theme.LineNumber)
+			d.Dot = fixed.P(imagePadding+(digits-len(number))*charWidth, baseline)
+			d.DrawString(number)
+		}
+
+		d.Dot = fixed.P(gutter+imagePadding, baseline)
+		for _, token := range row.tokens {
+			d.Src = image.NewUniform(theme.Kinds[token.Kind])
+			d.DrawString(token.Text)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err = png.Encode(buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/highlight"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+func TestSnippetImage(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Light", func(t *testing.T) {
+		code, header, body := ts.get(t, "/snippet/image/1.png")
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, header.Get("Content-Type"), "image/png")
+		assert.Equal(t, header.Get("ETag"), `"`+imageKey("An old silent pond...", "", "light", imageWidth)+`"`)
+
+		img, err := png.Decode(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, img.Bounds().Dx(), imageWidth)
+
+		r, g, b, _ := img.At(img.Bounds().Dx()-1, 0).RGBA()
+		assert.Equal(t, [3]uint32{r >> 8, g >> 8, b >> 8}, [3]uint32{0xFF, 0xFF, 0xFF})
+	})
+
+	t.Run("Dark and narrow", func(t *testing.T) {
+		code, _, body := ts.get(t, "/snippet/image/1.png?theme=dark&width=400")
+		assert.Equal(t, code, http.StatusOK)
+
+		img, err := png.Decode(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, img.Bounds().Dx(), 400)
+
+		r, g, b, _ := img.At(img.Bounds().Dx()-1, 0).RGBA()
+		assert.Equal(t, [3]uint32{r >> 8, g >> 8, b >> 8}, [3]uint32{0x28, 0x2C, 0x34})
+	})
+
+	t.Run("Linked", func(t *testing.T) {
+		_, _, body := ts.get(t, "/snippet/view/1")
+		assert.StringContains(t, body, `<a href="/snippet/image/1.png">PNG</a>`)
+	})
+
+	t.Run("Not modified", func(t *testing.T) {
+		_, header, _ := ts.get(t, "/snippet/image/1.png")
+
+		code, _, body := ts.getWithHeader(t, "/snippet/image/1.png", http.Header{"If-None-Match": {header.Get("ETag")}})
+		assert.Equal(t, code, http.StatusNotModified)
+		assert.Equal(t, body, "")
+	})
+
+	app.snippets.Insert(1, "Secret plans", "x", "", models.VisibilityPrivate, 7)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+	}{
+		{"Private snippet", "/snippet/image/2.png", http.StatusNotFound},
+		{"Unknown snippet", "/snippet/image/99.png", http.StatusNotFound},
+		{"Not a PNG", "/snippet/image/1.gif", http.StatusNotFound},
+		{"No extension", "/snippet/image/1", http.StatusNotFound},
+		{"Bad ID", "/snippet/image/x.png", http.StatusNotFound},
+		{"Unknown theme", "/snippet/image/1.png?theme=pink", http.StatusBadRequest},
+		{"Too narrow", "/snippet/image/1.png?width=100", http.StatusBadRequest},
+		{"Too wide", "/snippet/image/1.png?width=5000", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestSnippetImageCache(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, first := ts.get(t, "/snippet/image/1.png")
+
+	key := imageKey("An old silent pond...", "", "light", imageWidth)
+	cached, ok := app.images.get(key)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, bytes.Equal(cached, []byte(first)), true)
+
+	// what is served comes from the cache
+	app.images = newImageCache(16)
+	app.images.put(key, []byte("cached"))
+
+	_, _, body := ts.get(t, "/snippet/image/1.png")
+	assert.Equal(t, body, "cached")
+}
+
+func TestImageCache(t *testing.T) {
+	c := newImageCache(2)
+
+	c.put("a", []byte("1"))
+	c.put("b", []byte("2"))
+	c.get("a")
+	c.put("c", []byte("3"))
+
+	// b was the least recently used
+	_, ok := c.get("b")
+	assert.Equal(t, ok, false)
+	_, ok = c.get("a")
+	assert.Equal(t, ok, true)
+	_, ok = c.get("c")
+	assert.Equal(t, ok, true)
+
+	// a cache of no size keeps nothing
+	c = newImageCache(0)
+	c.put("a", []byte("1"))
+	_, ok = c.get("a")
+	assert.Equal(t, ok, false)
+}
+
+func TestImageRows(t *testing.T) {
+	text := func(rows []imageRow) []string {
+		out := []string{}
+		for _, row := range rows {
+			var s strings.Builder
+			for _, token := range row.tokens {
+				s.WriteString(token.Text)
+			}
+			out = append(out, s.String())
+		}
+		return out
+	}
+
+	t.Run("Wrapping", func(t *testing.T) {
+		lines := [][]highlight.Token{
+			{{Kind: highlight.Keyword, Text: "return"}, {Kind: highlight.Text, Text: " x"}},
+			{},
+		}
+
+		rows, truncated := imageRows(lines, 4)
+		assert.Equal(t, truncated, false)
+		assert.Equal(t, strings.Join(text(rows), "|"), "retu|rn x|")
+		assert.Equal(t, rows[0].number, 1)
+		assert.Equal(t, rows[1].number, 0)
+		assert.Equal(t, rows[2].number, 2)
+
+		// a token split over rows keeps its kind
+		assert.Equal(t, rows[1].tokens[0].Kind, highlight.Keyword)
+		assert.Equal(t, rows[1].tokens[0].Text, "rn")
+	})
+
+	t.Run("Tabs", func(t *testing.T) {
+		rows, _ := imageRows([][]highlight.Token{{{Kind: highlight.Text, Text: "a\tb\x00"}}}, 80)
+		assert.Equal(t, text(rows)[0], "a   b�")
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		lines := highlight.Lines(strings.Repeat("x\n", imageMaxRows+10), "")
+
+		rows, truncated := imageRows(lines, 80)
+		assert.Equal(t, truncated, true)
+		assert.Equal(t, len(rows), imageMaxRows)
+
+		rows, truncated = imageRows(lines[:imageMaxRows], 80)
+		assert.Equal(t, truncated, false)
+		assert.Equal(t, len(rows), imageMaxRows)
+	})
+}
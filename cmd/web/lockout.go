@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// lockoutPolicy decides how long logins are refused after a number of
+// failures: the first few failures are free, then the delay doubles with
+// every failure until the key is locked out completely
+type lockoutPolicy struct {
+	freeAttempts int
+	maxAttempts  int
+	baseDelay    time.Duration
+	lockout      time.Duration
+}
+
+func (p lockoutPolicy) delay(failures int) time.Duration {
+	if failures >= p.maxAttempts {
+		return p.lockout
+	}
+	if failures <= p.freeAttempts {
+		return 0
+	}
+
+	delay := p.baseDelay << (failures - p.freeAttempts - 1)
+	if delay > p.lockout {
+		return p.lockout
+	}
+	return delay
+}
+
+var (
+	// an attacker guessing one account's password
+	accountLockout = lockoutPolicy{freeAttempts: 3, maxAttempts: 10, baseDelay: time.Second, lockout: 15 * time.Minute}
+
+	// an attacker spraying guesses across many accounts from one address,
+	// more generous as many users may share an address behind NAT
+	ipLockout = lockoutPolicy{freeAttempts: 20, maxAttempts: 100, baseDelay: time.Second, lockout: 15 * time.Minute}
+)
+
+func accountLoginKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func (app *application) ipLoginKey(r *http.Request) string {
+	return "ip:" + app.clientIP(r)
+}
+
+// loginRetryAfter() returns how long the client has to wait before it
+// may try to log in to the account again, or 0 if it may try now
+func (app *application) loginRetryAfter(r *http.Request, email string) (time.Duration, error) {
+	var wait time.Duration
+
+	for _, key := range []string{app.ipLoginKey(r), accountLoginKey(email)} {
+		attempt, err := app.loginAttempts.Get(key)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				continue
+			}
+			return 0, err
+		}
+
+		if d := time.Until(attempt.LockedUntil); d > wait {
+			wait = d
+		}
+	}
+
+	return wait, nil
+}
+
+// recordLoginFailure() counts a failed login against both the client's
+// address and the account, whether or not the account exists, so that
+// responses don't reveal which emails are registered
+func (app *application) recordLoginFailure(r *http.Request, email string) error {
+	failures, err := app.loginAttempts.Fail(app.ipLoginKey(r))
+	if err != nil {
+		return err
+	}
+	if d := ipLockout.delay(failures); d > 0 {
+		err = app.loginAttempts.Lock(app.ipLoginKey(r), time.Now().Add(d))
+		if err != nil {
+			return err
+		}
+	}
+
+	key := accountLoginKey(email)
+
+	failures, err = app.loginAttempts.Fail(key)
+	if err != nil {
+		return err
+	}
+	if d := accountLockout.delay(failures); d > 0 {
+		err = app.loginAttempts.Lock(key, time.Now().Add(d))
+		if err != nil {
+			return err
+		}
+	}
+
+	// tell the owner the first time their account gets locked out, with
+	// a link that lifts the lockout
+	if failures == accountLockout.maxAttempts {
+		app.audit(r, "user.lockout", "", map[string]any{"email": email})
+		return app.sendUnlockEmail(key, email)
+	}
+
+	return nil
+}
+
+func (app *application) sendUnlockEmail(key, email string) error {
+	user, err := app.users.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return nil
+		}
+		return err
+	}
+
+	token := rand.Text()
+
+	err = app.loginAttempts.SetUnlockToken(key, token, time.Now().Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"There were too many failed attempts to log in to your Snippetbox account, so we have locked it for a while.\n\n"+
+		"If this was you, you can unlock your account straight away by visiting:\n\n%s/user/unlock?token=%s\n\n"+
+		"If it wasn't you, someone may be trying to guess your password.\n",
+		user.Name, app.baseURL, url.QueryEscape(token))
+
+	app.background(func() {
+		err := app.mailer.Send(user.Email, "Your Snippetbox account has been locked", body)
+		if err != nil {
+			app.errorLog.Print(err)
+		}
+	})
+
+	return nil
+}
+
+func (app *application) userUnlock(w http.ResponseWriter, r *http.Request) {
+	err := app.loginAttempts.Unlock(r.URL.Query().Get("token"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.sessionManager.Put(r.Context(), "flash", "This unlock link is invalid or has expired")
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "user.unlock", "", nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "Your account has been unlocked, you can log in again")
+
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	policy := lockoutPolicy{freeAttempts: 3, maxAttempts: 10, baseDelay: time.Second, lockout: 15 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 9, want: 32 * time.Second},
+		{failures: 10, want: 15 * time.Minute},
+		{failures: 50, want: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, policy.delay(tt.failures), tt.want)
+	}
+}
+
+// recordingMailer hands each message it is asked to send to the test
+type recordingMailer struct {
+	sent chan string
+}
+
+func (m *recordingMailer) Send(to, subject, body string) error {
+	m.sent <- body
+	return nil
+}
+
+func postLogin(t *testing.T, ts *testServer, email, password string) (int, http.Header, string) {
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", password)
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	solveChallenge(t, body, form)
+
+	return ts.postForm(t, "/user/login", form)
+}
+
+func TestUserLoginLockout(t *testing.T) {
+	t.Run("Locked account", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.loginAttempts.(*mocks.LoginAttemptModel).Attempts = map[string]*models.LoginAttempt{
+			"account:test@example.com": {Failures: 10, LockedUntil: time.Now().Add(time.Minute)},
+		}
+
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		// even the right password is refused while locked
+		code, headers, body := postLogin(t, ts, "test@example.com", "password")
+		assert.Equal(t, code, http.StatusTooManyRequests)
+		assert.Equal(t, headers.Get("Retry-After") != "", true)
+		assert.StringContains(t, body, "Too many failed login attempts")
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		app := newTestApplication(t)
+
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		// an account that doesn't exist locks the same way, so lockouts
+		// don't reveal which emails are registered
+		for range accountLockout.freeAttempts {
+			code, _, body := postLogin(t, ts, "nobody@example.com", "guess")
+			assert.Equal(t, code, http.StatusUnprocessableEntity)
+			assert.StringContains(t, body, "Email or password is incorrect")
+		}
+
+		code, _, _ := postLogin(t, ts, "nobody@example.com", "guess")
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+
+		code, headers, body := postLogin(t, ts, "nobody@example.com", "guess")
+		assert.Equal(t, code, http.StatusTooManyRequests)
+		assert.Equal(t, headers.Get("Retry-After"), "1")
+		assert.StringContains(t, body, "Too many failed login attempts")
+	})
+
+	t.Run("Unlock by email", func(t *testing.T) {
+		app := newTestApplication(t)
+		mailer := &recordingMailer{sent: make(chan string, 1)}
+		app.mailer = mailer
+		app.loginAttempts.(*mocks.LoginAttemptModel).Attempts = map[string]*models.LoginAttempt{
+			"account:test@example.com": {Failures: accountLockout.maxAttempts - 1},
+		}
+
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		code, _, _ := postLogin(t, ts, "test@example.com", "wrong-password")
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+
+		code, _, _ = postLogin(t, ts, "test@example.com", "password")
+		assert.Equal(t, code, http.StatusTooManyRequests)
+
+		var mail string
+		select {
+		case mail = <-mailer.sent:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no unlock email sent")
+		}
+
+		matches := regexp.MustCompile(`/user/unlock\?token=(\S+)`).FindStringSubmatch(mail)
+		if len(matches) < 2 {
+			t.Fatal("no unlock link in email")
+		}
+
+		code, _, _ = ts.get(t, "/user/unlock?token=wrong")
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, _, _ = postLogin(t, ts, "test@example.com", "password")
+		assert.Equal(t, code, http.StatusTooManyRequests)
+
+		code, headers, _ := ts.get(t, "/user/unlock?token="+matches[1])
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+
+		code, _, _ = postLogin(t, ts, "test@example.com", "password")
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+}
+
+// downAuthenticator stands in for an external authenticator, such as an
+// LDAP server, that can't be reached
+type downAuthenticator struct{}
+
+func (a downAuthenticator) Authenticate(email, password string) (int, error) {
+	return 0, errors.New("ldap: connection refused")
+}
+
+func TestUserLoginBackendDown(t *testing.T) {
+	app := newTestApplication(t)
+	app.authenticator = &models.Authenticators{List: []models.Authenticator{downAuthenticator{}, app.users}}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// a wrong password is still a failed login that counts towards the
+	// lockout, not an error that can be retried for free
+	for i := range accountLockout.freeAttempts + 1 {
+		code, _, body := postLogin(t, ts, "test@example.com", "wrong-password")
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "Email or password is incorrect")
+
+		attempt := app.loginAttempts.(*mocks.LoginAttemptModel).Attempts["account:test@example.com"]
+		assert.Equal(t, attempt.Failures, i+1)
+	}
+
+	code, _, _ := postLogin(t, ts, "test@example.com", "password")
+	assert.Equal(t, code, http.StatusTooManyRequests)
+
+	// with nothing left to check the password against, the failure is
+	// counted all the same
+	app.authenticator = &models.Authenticators{List: []models.Authenticator{downAuthenticator{}}}
+	app.loginAttempts.(*mocks.LoginAttemptModel).Attempts = nil
+
+	code, _, _ = postLogin(t, ts, "test@example.com", "password")
+	assert.Equal(t, code, http.StatusInternalServerError)
+
+	attempt := app.loginAttempts.(*mocks.LoginAttemptModel).Attempts["account:test@example.com"]
+	assert.Equal(t, attempt.Failures, 1)
+}
@@ -1,35 +1,107 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"database/sql"
 	"flag"
 	"html/template"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"time"
 
+	"github.com/PPRAMANIK62/snippetbox/internal/ldapauth"
+	"github.com/PPRAMANIK62/snippetbox/internal/mailer"
 	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/pow"
+	"github.com/PPRAMANIK62/snippetbox/internal/ratelimit"
+	"github.com/PPRAMANIK62/snippetbox/internal/secrets"
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type application struct {
-	infoLog        *log.Logger
-	errorLog       *log.Logger
-	snippets       models.SnippetModelInterface
-	users          models.UserModelInterface
-	templateCache  map[string]*template.Template
-	formDecoder    *form.Decoder
-	sessionManager *scs.SessionManager
+	infoLog          *log.Logger
+	errorLog         *log.Logger
+	snippets         models.SnippetModelInterface
+	users            models.UserModelInterface
+	accounts         models.AccountModelInterface
+	stats            models.StatsModelInterface
+	auditLog         models.AuditModelInterface
+	auditEvents      chan *models.AuditEvent
+	reports          models.ReportModelInterface
+	authenticator    models.Authenticator
+	passkeys         models.PasskeyModelInterface
+	identities       models.IdentityModelInterface
+	loginAttempts    models.LoginAttemptModelInterface
+	userSessions     models.UserSessionModelInterface
+	persistentLogins models.PersistentLoginModelInterface
+	apiTokens        models.APITokenModelInterface
+	devKeys          models.DevKeyModelInterface
+	webhooks         models.WebhookModelInterface
+	webhookSender    *webhookSender
+	mailer           mailer.Mailer
+	secretScanner    *secrets.Scanner
+	challenges       *pow.Issuer
+	rateLimits       rateLimits
+	trustedProxies   []netip.Prefix
+	embedOrigins     []string
+	images           *imageCache
+	baseURL          string
+	templateCache    map[string]*template.Template
+	formDecoder      *form.Decoder
+	sessionManager   *scs.SessionManager
+	webAuthn         *webauthn.WebAuthn
+	oidc             *oidcProvider
 }
 
 func main() {
 	addr := flag.String("addr", ":4000", "HTTP network address")
+	baseURL := flag.String("base-url", "https://localhost:4000", "Public URL of the site, used for links in emails")
+	rpID := flag.String("webauthn-rp-id", "localhost", "WebAuthn relying party ID (the site's domain)")
+	rpOrigin := flag.String("webauthn-origin", "https://localhost:4000", "WebAuthn relying party origin")
+	oidcIssuer := flag.String("oidc-issuer", "", "OpenID Connect issuer URL (single sign-on is disabled if empty)")
+	oidcClientID := flag.String("oidc-client-id", "", "OpenID Connect client ID")
+	oidcRedirectURL := flag.String("oidc-redirect-url", "https://localhost:4000/user/login/oidc/callback", "OpenID Connect redirect URL")
+	ldapURL := flag.String("ldap-url", "", "LDAP server URL, ldap:// or ldaps:// (LDAP login is disabled if empty)")
+	ldapStartTLS := flag.Bool("ldap-start-tls", false, "Upgrade ldap:// connections with StartTLS")
+	ldapBindDN := flag.String("ldap-bind-dn", "", "DN of the LDAP service account used to search for users (anonymous if empty)")
+	ldapBaseDN := flag.String("ldap-base-dn", "", "LDAP base DN to search for users under")
+	ldapUserFilter := flag.String("ldap-user-filter", "(&(objectClass=person)(mail=%s))", "LDAP filter finding a user, %s is the login")
+	ldapNameAttr := flag.String("ldap-name-attr", "cn", "LDAP attribute holding the user's name")
+	ldapEmailAttr := flag.String("ldap-email-attr", "mail", "LDAP attribute holding the user's email")
+	smtpHost := flag.String("smtp-host", "", "SMTP server host (emails are logged instead of sent if empty)")
+	smtpPort := flag.Int("smtp-port", 587, "SMTP server port")
+	smtpUsername := flag.String("smtp-username", "", "SMTP username")
+	smtpSender := flag.String("smtp-sender", "Snippetbox <no-reply@snippetbox.local>", "SMTP sender address")
+	secretRules := flag.String("secret-rules", "", "JSON file of secret detection rules to add to the built-in ones")
+	powDifficulty := flag.Int("pow-difficulty", 16, "Leading zero bits a proof-of-work solution needs on public forms")
+	powMaxDifficulty := flag.Int("pow-max-difficulty", 22, "Most leading zero bits asked for when the site is under load")
+	powSurge := flag.Int("pow-surge", 60, "Form submissions a minute which raise the proof-of-work difficulty by one bit")
+	trustedProxies := flag.String("trusted-proxies", "", "Comma separated addresses or CIDR ranges of reverse proxies whose X-Forwarded-For is believed")
+	rateLimitRead := flag.String("rate-limit-read", "300/1m", "Page views allowed per client, as <requests>/<duration> (unlimited if empty)")
+	rateLimitWrite := flag.String("rate-limit-write", "60/1m", "Form submissions and other writes allowed per client")
+	rateLimitLogin := flag.String("rate-limit-login", "20/1m", "Login attempts allowed per client")
+	rateLimitSignup := flag.String("rate-limit-signup", "5/1h", "Signups allowed per client")
+	rateLimitAnswer := flag.String("rate-limit-answer", "5/10m", "Forms allowed per client with the proof-of-work question answered, without JavaScript")
+	auditRetention := flag.Duration("audit-retention", 90*24*time.Hour, "How long to keep audit log events (kept forever if 0)")
+	webhookTimeout := flag.Duration("webhook-timeout", 10*time.Second, "How long to wait for a webhook receiver to answer")
+	webhookBackoff := flag.Duration("webhook-backoff", time.Minute, "How long before a failed webhook delivery is first retried, doubling each time")
+	webhookAttempts := flag.Int("webhook-attempts", 8, "How many times to try a webhook delivery before giving up")
+	webhookAllowPrivate := flag.Bool("webhook-allow-private", false, "Let users' webhooks reach private, loopback and link-local addresses")
+	embedOrigins := flag.String("embed-origins", "", "Comma separated origins of other sites allowed to embed snippets in an iframe, or * for any")
+	imageCacheSize := flag.Int("image-cache", 256, "How many snippet images to keep in memory")
 	dsn := os.Getenv("MYSQL_DSN")
+	oidcClientSecret := os.Getenv("OIDC_CLIENT_SECRET")
+	ldapBindPassword := os.Getenv("LDAP_BIND_PASSWORD")
+	smtpPassword := os.Getenv("SMTP_PASSWORD")
+	powSecret := os.Getenv("POW_SECRET")
 	flag.Parse()
 
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
@@ -53,16 +125,165 @@ func main() {
 	sessionManager.Lifetime = 12 * time.Hour
 	sessionManager.Cookie.Secure = true
 
+	// the relying party ID and origin must match the address users visit
+	// in the browser, otherwise authenticators will refuse the ceremony
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          *rpID,
+		RPDisplayName: "Snippetbox",
+		RPOrigins:     []string{*rpOrigin},
+	})
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	var oidc *oidcProvider
+	if *oidcIssuer != "" {
+		oidc, err = newOIDCProvider(context.Background(), *oidcIssuer, *oidcClientID, oidcClientSecret, *oidcRedirectURL)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+	}
+
+	users := &models.UserModel{DB: db}
+
+	// LDAP accounts are tried first, falling back to local passwords
+	// for users that only exist in our own users table
+	var authenticator models.Authenticator = users
+	if *ldapURL != "" {
+		directory := &ldapauth.Authenticator{
+			Config: ldapauth.Config{
+				URL:            *ldapURL,
+				StartTLS:       *ldapStartTLS,
+				BindDN:         *ldapBindDN,
+				BindPassword:   ldapBindPassword,
+				BaseDN:         *ldapBaseDN,
+				UserFilter:     *ldapUserFilter,
+				NameAttribute:  *ldapNameAttr,
+				EmailAttribute: *ldapEmailAttr,
+			},
+			Users: users,
+		}
+		// an LDAP outage is logged, and doesn't keep local users out
+		authenticator = &models.Authenticators{
+			List:   []models.Authenticator{directory, users},
+			Logger: errorLog,
+		}
+	}
+
+	rules := secrets.DefaultRules()
+	if *secretRules != "" {
+		extra, err := loadSecretRules(*secretRules)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		rules = append(rules, extra...)
+	}
+
+	// challenges signed with a random key stop working on a restart, and
+	// aren't accepted by other instances of the app
+	powKey := []byte(powSecret)
+	if len(powKey) == 0 {
+		infoLog.Print("POW_SECRET is not set, using a random key for proof-of-work challenges")
+		powKey = make([]byte, 32)
+		rand.Read(powKey)
+	}
+
+	challenges := &pow.Issuer{
+		Key:           powKey,
+		Difficulty:    *powDifficulty,
+		MaxDifficulty: *powMaxDifficulty,
+		Surge:         *powSurge,
+	}
+
+	proxies, err := parseTrustedProxies(*trustedProxies)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	origins, err := parseEmbedOrigins(*embedOrigins)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	var limits rateLimits
+	for _, l := range []struct {
+		limiter **ratelimit.Limiter
+		flag    string
+	}{
+		{&limits.read, *rateLimitRead},
+		{&limits.write, *rateLimitWrite},
+		{&limits.login, *rateLimitLogin},
+		{&limits.signup, *rateLimitSignup},
+		{&limits.answer, *rateLimitAnswer},
+	} {
+		if l.flag == "" {
+			continue
+		}
+		limit, err := ratelimit.ParseLimit(l.flag)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		*l.limiter = ratelimit.New(limit, rateLimitKeys)
+	}
+
+	var mail mailer.Mailer = &mailer.Log{Logger: infoLog}
+	if *smtpHost != "" {
+		mail = &mailer.SMTP{
+			Host:     *smtpHost,
+			Port:     *smtpPort,
+			Username: *smtpUsername,
+			Password: smtpPassword,
+			Sender:   *smtpSender,
+		}
+	}
+
 	app := &application{
-		infoLog:        infoLog,
-		errorLog:       errorLog,
-		snippets:       &models.SnippetModel{DB: db},
-		users:          &models.UserModel{DB: db},
-		templateCache:  templateCache,
-		formDecoder:    formDecoder,
-		sessionManager: sessionManager,
+		infoLog:          infoLog,
+		errorLog:         errorLog,
+		snippets:         &models.SnippetModel{DB: db},
+		users:            users,
+		accounts:         &models.AccountModel{DB: db},
+		stats:            &models.StatsModel{DB: db},
+		auditLog:         &models.AuditModel{DB: db},
+		auditEvents:      make(chan *models.AuditEvent, auditQueueSize),
+		reports:          &models.ReportModel{DB: db},
+		authenticator:    authenticator,
+		passkeys:         &models.PasskeyModel{DB: db},
+		identities:       &models.IdentityModel{DB: db},
+		loginAttempts:    &models.LoginAttemptModel{DB: db},
+		userSessions:     &models.UserSessionModel{DB: db},
+		persistentLogins: &models.PersistentLoginModel{DB: db},
+		apiTokens:        &models.APITokenModel{DB: db},
+		devKeys:          &models.DevKeyModel{DB: db},
+		webhooks:         &models.WebhookModel{DB: db},
+		webhookSender:    newWebhookSender(*webhookTimeout, *webhookBackoff, *webhookAttempts, *webhookAllowPrivate),
+		mailer:           mail,
+		secretScanner:    secrets.New(rules),
+		challenges:       challenges,
+		rateLimits:       limits,
+		trustedProxies:   proxies,
+		embedOrigins:     origins,
+		images:           newImageCache(*imageCacheSize),
+		baseURL:          *baseURL,
+		templateCache:    templateCache,
+		formDecoder:      formDecoder,
+		sessionManager:   sessionManager,
+		webAuthn:         webAuthn,
+		oidc:             oidc,
+	}
+
+	// write audit events in the background, off the request path
+	go app.auditWriter(app.auditEvents)
+
+	if *auditRetention > 0 {
+		go app.pruneAuditLog(*auditRetention, time.Hour)
 	}
 
+	// send webhook deliveries in the background too, retrying the failed
+	// ones, and watch for snippets expiring
+	go app.deliverWebhooks(10 * time.Second)
+	go app.watchExpiry(time.Minute)
+
 	// Initialize a tls.Config struct to hold the non-default TLS settings
 	// In this case only the curve preference value is changed
 	// so that only the elliptic curves with assembly implementations are used
@@ -86,6 +307,16 @@ func main() {
 	errorLog.Fatal(err)
 }
 
+func loadSecretRules(path string) ([]secrets.Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return secrets.ParseRules(f)
+}
+
 func openDB(dsn string) (*sql.DB, error) {
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
 	"github.com/justinas/nosurf"
 )
 
+// contentSecurityPolicy() returns the Content-Security-Policy for pages
+// which may be framed by frameAncestors
+func contentSecurityPolicy(frameAncestors string) string {
+	return "default-src 'self'; style-src 'self' fonts.googleapis.com; font-src fonts.gstatic.com; frame-ancestors " + frameAncestors
+}
+
 func secureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Security-Policy",
-			"default-src 'self'; style-src 'self' fonts.googleapis.com; font-src fonts.gstatic.com")
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy("'none'"))
 		w.Header().Set("Referrer-Policy", "origin-when-cross-origin")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-Frame-Options", "deny")
@@ -23,7 +30,7 @@ func secureHeaders(next http.Handler) http.Handler {
 
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
+		app.infoLog.Printf("%s - %s %s %s %s", app.clientIP(r), app.clientScheme(r), r.Proto, r.Method, r.URL.RequestURI())
 
 		next.ServeHTTP(w, r)
 	})
@@ -59,11 +66,35 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 		// set the "Cache-Control" header so that pages require authentication aren't cached
 		w.Header().Set("Cache-Control", "no-store")
 
+		// a user an admin has asked to change their password may only do
+		// that, or log out
+		mustChange, _ := r.Context().Value(mustChangePasswordContextKey).(bool)
+		if mustChange && r.URL.Path != "/account/password" && r.URL.Path != "/account/reauthenticate" && r.URL.Path != "/user/logout" {
+			http.Redirect(w, r, "/account/password", http.StatusSeeOther)
+			return
+		}
+
 		// call the next handler in the chain
 		next.ServeHTTP(w, r)
 	})
 }
 
+// requireRole() returns middleware that only lets through users whose
+// role includes the given one. Use it after requireAuthentication.
+func (app *application) requireRole(role models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.role(r).Includes(role) {
+				app.audit(r, "access.denied", r.URL.Path, map[string]any{"method": r.Method, "required_role": role})
+				app.clientError(w, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) noSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 	csrfHandler.SetBaseCookie(http.Cookie{
@@ -82,22 +113,55 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// in the chain as normal and return
 		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 		if id == 0 {
-			next.ServeHTTP(w, r)
+			// the session may have expired while a "remember me"
+			// cookie is still good, in which case log the user back in
+			var err error
+			id, err = app.resumePersistentLogin(w, r)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			if id == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		// the session may have been revoked from another device, in which
+		// case it no longer counts as logged in
+		token := app.sessionManager.Token(r.Context())
+
+		_, err := app.userSessions.Get(token)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+				next.ServeHTTP(w, r)
+			} else {
+				app.serverError(w, err)
+			}
 			return
 		}
 
-		// chack if an user with the id exists in the database
-		exists, err := app.users.Exists(id)
+		err = app.userSessions.Touch(token, app.clientIP(r))
 		if err != nil {
 			app.serverError(w, err)
 			return
 		}
 
+		// chack if an user with the id exists in the database
+		user, err := app.users.Get(id)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+
 		// is a matching user is found, create a new copy of the request
-		// (with an isAuthenticatedContextKey value of true in the context)
-		// ans assign it to r
-		if exists {
+		// (with an isAuthenticatedContextKey value of true and the user's
+		// role in the context) ans assign it to r
+		if err == nil && !user.Disabled {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, roleContextKey, user.Role)
+			ctx = context.WithValue(ctx, mustChangePasswordContextKey, user.MustChangePassword)
 			r = r.WithContext(ctx)
 		}
 
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+type snippetReportForm struct {
+	ID                  int    `form:"id"`
+	Reason              string `form:"reason"`
+	Details             string `form:"details"`
+	validator.Validator `form:"-"`
+	challengeForm
+}
+
+// snippetReportPost() lets any visitor, logged in or not, report a
+// snippet to the moderators
+func (app *application) snippetReportPost(w http.ResponseWriter, r *http.Request) {
+	var form snippetReportForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	snippet, err := app.snippets.Get(form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if !app.canSee(r, snippet) {
+		app.notFound(w)
+		return
+	}
+
+	// there's nothing left to report once it has been hidden
+	if snippet.Hidden {
+		app.clientError(w, http.StatusUnavailableForLegalReasons)
+		return
+	}
+
+	app.checkChallenge(r, form.challengeForm, &form.Validator)
+	if form.Trapped {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.PermittedValue(form.Reason, "spam", "secret", "abuse", "other"), "reason", "Please choose a reason")
+	form.CheckField(form.Validator.MaxChars(form.Details, 1000), "details", "This field cannot be more than 1000 characters long")
+	if form.Reason == "other" {
+		form.CheckField(form.Validator.NotBlank(form.Details), "details", "Please tell us what is wrong")
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "view.html", data)
+		return
+	}
+
+	reporterID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	id, err := app.reports.Insert(snippet.ID, reporterID, form.Reason, form.Details)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "snippet.report", fmt.Sprintf("snippet:%d", snippet.ID), map[string]any{"report": id, "reason": form.Reason})
+
+	app.sessionManager.Put(r.Context(), "flash", "Thanks, a moderator will look at your report")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+func (app *application) moderation(w http.ResponseWriter, r *http.Request) {
+	reports, err := app.reports.Open()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Reports = reports
+
+	app.render(w, http.StatusOK, "moderation.html", data)
+}
+
+type moderationForm struct {
+	ID int `form:"id"`
+}
+
+// moderationReport() returns the open report the moderation form is
+// about, having sent an error response if there isn't one
+func (app *application) moderationReport(w http.ResponseWriter, r *http.Request) (*models.Report, bool) {
+	var form moderationForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return nil, false
+	}
+
+	report, err := app.reports.Get(form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return report, true
+}
+
+// moderationResolve() closes the reports about the snippet, records the
+// decision in the audit log and returns to the queue
+func (app *application) moderationResolve(w http.ResponseWriter, r *http.Request, report *models.Report, resolution, flash string) {
+	moderatorID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	_, err := app.reports.Resolve(report.SnippetID, moderatorID, resolution)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "moderation."+resolution, fmt.Sprintf("snippet:%d", report.SnippetID), map[string]any{"report": report.ID, "reason": report.Reason})
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+
+	http.Redirect(w, r, "/moderation", http.StatusSeeOther)
+}
+
+func (app *application) moderationHidePost(w http.ResponseWriter, r *http.Request) {
+	report, ok := app.moderationReport(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.SetHidden(report.SnippetID, true)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// snippets can't be edited, so being hidden is the only update there
+	// is to tell the webhooks about
+	snippet, err := app.snippets.Get(report.SnippetID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.webhookEvent(models.EventSnippetUpdated, snippet)
+
+	app.moderationResolve(w, r, report, "hide", "Snippet hidden")
+}
+
+func (app *application) moderationDeletePost(w http.ResponseWriter, r *http.Request) {
+	report, ok := app.moderationReport(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := app.getSnippets([]int{report.SnippetID})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	_, err = app.snippets.DeleteMany([]int{report.SnippetID})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.snippetsDeleted(deleted)
+
+	app.moderationResolve(w, r, report, "delete", "Snippet deleted")
+}
+
+func (app *application) moderationDismissPost(w http.ResponseWriter, r *http.Request) {
+	report, ok := app.moderationReport(w, r)
+	if !ok {
+		return
+	}
+
+	app.moderationResolve(w, r, report, "dismiss", "Report dismissed")
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+// reportSnippet() reports the snippet from its page, as any visitor can
+func reportSnippet(t *testing.T, ts *testServer, form url.Values) (int, string) {
+	_, _, body := ts.get(t, "/snippet/view/1")
+	form.Set("csrf_token", extractCSRFToken(t, body))
+	solveChallenge(t, body, form)
+
+	code, _, body := ts.postForm(t, "/snippet/report", form)
+	return code, body
+}
+
+func TestSnippetReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     url.Values
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid report",
+			form:     url.Values{"id": {"1"}, "reason": {"secret"}, "details": {"There's an API key in it"}},
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "No details needed",
+			form:     url.Values{"id": {"1"}, "reason": {"spam"}},
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Missing reason",
+			form:     url.Values{"id": {"1"}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Please choose a reason",
+		},
+		{
+			name:     "Unknown reason",
+			form:     url.Values{"id": {"1"}, "reason": {"boring"}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Please choose a reason",
+		},
+		{
+			name:     "Other without details",
+			form:     url.Values{"id": {"1"}, "reason": {"other"}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Please tell us what is wrong",
+		},
+		{
+			name:     "Details too long",
+			form:     url.Values{"id": {"1"}, "reason": {"other"}, "details": {strings.Repeat("a", 1001)}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be more than 1000 characters long",
+		},
+		{
+			name:     "Non-existent snippet",
+			form:     url.Values{"id": {"2"}, "reason": {"spam"}},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			code, body := reportSnippet(t, ts, tt.form)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+
+			reports := app.reports.(*mocks.ReportModel).Reports
+			if tt.wantCode == http.StatusSeeOther {
+				assert.Equal(t, len(reports), 1)
+				assert.Equal(t, reports[0].SnippetID, 1)
+				assert.Equal(t, reports[0].ReporterID, 0)
+				assert.Equal(t, reports[0].Reason, tt.form.Get("reason"))
+			} else {
+				assert.Equal(t, len(reports), 0)
+			}
+		})
+	}
+}
+
+func TestModerationAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantCode int
+	}{
+		{
+			name:     "User",
+			email:    "test@example.com",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Moderator",
+			email:    "moderator@example.com",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Admin",
+			email:    "admin@example.com",
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			app.reports.Insert(1, 0, "spam", "Buy cheap watches")
+
+			ts.loginAs(t, tt.email)
+
+			code, _, body := ts.get(t, "/moderation")
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantCode == http.StatusOK {
+				assert.StringContains(t, body, "Buy cheap watches")
+			}
+		})
+	}
+}
+
+func TestModerationHide(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	anonymous := device(t, ts)
+	code, _ := reportSnippet(t, ts, url.Values{"id": {"1"}, "reason": {"spam"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	device(t, ts)
+	ts.loginAs(t, "moderator@example.com")
+
+	code = moderationPost(t, ts, "/moderation/hide", 1)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	assert.Equal(t, app.snippets.(*mocks.SnippetModel).Hidden[1], true)
+	assert.Equal(t, app.reports.(*mocks.ReportModel).Resolved[1], "hide")
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	last := events[len(events)-1]
+	assert.Equal(t, last.Action, "moderation.hide")
+	assert.Equal(t, last.Target, "snippet:1")
+	assert.Equal(t, last.ActorID, 2)
+
+	// the report has been dealt with, so it can't be acted on again
+	code = moderationPost(t, ts, "/moderation/dismiss", 1)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	// moderators can still look at it
+	code, _, body := ts.get(t, "/snippet/view/1")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "hidden by a moderator")
+
+	// everybody else is told it's gone, and it's off the home page
+	ts.Client().Jar = anonymous
+	code, _, _ = ts.get(t, "/snippet/view/1")
+	assert.Equal(t, code, http.StatusUnavailableForLegalReasons)
+
+	_, _, body = ts.get(t, "/")
+	if strings.Contains(body, "An old silent pond") {
+		t.Errorf("hidden snippet is listed on the home page")
+	}
+}
+
+func TestModerationDeleteAndDismiss(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	reports := app.reports.(*mocks.ReportModel)
+	reports.Insert(1, 0, "spam", "")
+	reports.Insert(1, 1, "abuse", "")
+
+	ts.loginAs(t, "moderator@example.com")
+
+	code := moderationPost(t, ts, "/moderation/dismiss", 2)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	// dismissing one report closes every report about the snippet
+	assert.Equal(t, reports.Resolved[1], "dismiss")
+	assert.Equal(t, reports.Resolved[2], "dismiss")
+	assert.Equal(t, len(app.snippets.(*mocks.SnippetModel).Deleted), 0)
+
+	reports.Insert(1, 0, "secret", "")
+
+	code = moderationPost(t, ts, "/moderation/delete", 3)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	assert.Equal(t, reports.Resolved[3], "delete")
+	assert.Equal(t, app.snippets.(*mocks.SnippetModel).Deleted[0], 1)
+}
+
+func moderationPost(t *testing.T, ts *testServer, urlPath string, id int) int {
+	_, _, body := ts.get(t, "/moderation")
+
+	form := url.Values{}
+	form.Add("id", strconv.Itoa(id))
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := ts.postForm(t, urlPath, form)
+	return code
+}
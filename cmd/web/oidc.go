@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// oidcProvider holds everything needed to run the authorization code
+// flow against the configured OpenID Connect identity provider
+type oidcProvider struct {
+	issuer   string
+	verifier *oidc.IDTokenVerifier
+	config   oauth2.Config
+}
+
+// newOIDCProvider() fetches the provider's discovery document, which
+// tells us its endpoints and where to find the keys that sign ID tokens
+func newOIDCProvider(ctx context.Context, issuer, clientID, clientSecret, redirectURL string) (*oidcProvider, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcProvider{
+		issuer:   issuer,
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  redirectURL,
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+	}, nil
+}
+
+// reauthenticateFor is how long after logging in again at the identity
+// provider a user may change their password or delete their account
+// without typing the current password
+const reauthenticateFor = 5 * time.Minute
+
+// the claims we use from the ID token
+type oidcClaims struct {
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	AuthTime      int64  `json:"auth_time"`
+}
+
+func (app *application) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "oidcReauthenticate")
+	app.oidcRedirect(w, r)
+}
+
+// accountReauthenticate() sends a logged in user back to the identity
+// provider to log in again, which confirms who they are before changing
+// their password or deleting their account when they have no password
+// they know, as accounts provisioned by single sign-on don't
+func (app *application) accountReauthenticate(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w)
+		return
+	}
+
+	next := r.URL.Query().Get("next")
+	if next != "/account/delete" && next != "/account/password" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "oidcReauthenticate", next)
+
+	// ask the provider to make the user log in again, rather than let
+	// them straight through on the session they have there
+	app.oidcRedirect(w, r, oauth2.SetAuthURLParam("prompt", "login"), oauth2.SetAuthURLParam("max_age", "0"))
+}
+
+// oidcRedirect() sends the user to the provider to log in, to come back
+// to oidcCallback()
+func (app *application) oidcRedirect(w http.ResponseWriter, r *http.Request, opts ...oauth2.AuthCodeOption) {
+	// state protects the callback against CSRF, the nonce ties the ID
+	// token to this login attempt and the PKCE verifier ensures only we
+	// can redeem the authorization code
+	state := rand.Text()
+	nonce := rand.Text()
+	verifier := oauth2.GenerateVerifier()
+
+	app.sessionManager.Put(r.Context(), "oidcState", state)
+	app.sessionManager.Put(r.Context(), "oidcNonce", nonce)
+	app.sessionManager.Put(r.Context(), "oidcVerifier", verifier)
+
+	opts = append(opts, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	url := app.oidc.config.AuthCodeURL(state, opts...)
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+func (app *application) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w)
+		return
+	}
+
+	state := app.sessionManager.PopString(r.Context(), "oidcState")
+	nonce := app.sessionManager.PopString(r.Context(), "oidcNonce")
+	verifier := app.sessionManager.PopString(r.Context(), "oidcVerifier")
+	reauthenticate := app.sessionManager.PopString(r.Context(), "oidcReauthenticate")
+
+	query := r.URL.Query()
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// the user may have declined to sign in at the provider
+	if query.Get("error") != "" {
+		app.sessionManager.Put(r.Context(), "flash", "Single sign-on was cancelled")
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	token, err := app.oidc.config.Exchange(r.Context(), query.Get("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	// check the signature against the provider's JWKS, and the issuer,
+	// audience and expiry claims
+	idToken, err := app.oidc.verifier.Verify(r.Context(), rawIDToken)
+	if err != nil {
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	var claims oidcClaims
+	err = idToken.Claims(&claims)
+	if err != nil {
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	if reauthenticate != "" {
+		app.oidcReauthenticated(w, r, idToken, claims, reauthenticate)
+		return
+	}
+
+	// only a verified email is trusted to identify a local account,
+	// otherwise anyone could claim someone else's address at the provider
+	if claims.Email == "" || !claims.EmailVerified {
+		form := userLoginForm{}
+		form.AddNonFieldError("Your identity provider account doesn't have a verified email address")
+
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusForbidden, "login.html", data)
+		return
+	}
+
+	userID, err := app.linkIdentity(idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.startSession(r, userID, "")
+	if err != nil {
+		if errors.Is(err, models.ErrAccountDisabled) {
+			app.audit(r, "user.login_failed", fmt.Sprintf("user:%d", userID), map[string]any{"reason": "disabled"})
+
+			form := userLoginForm{}
+			form.AddNonFieldError("This account has been disabled")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, http.StatusForbidden, "login.html", data)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "user.login", fmt.Sprintf("user:%d", userID), map[string]any{"method": "oidc", "issuer": idToken.Issuer})
+
+	app.sessionManager.Put(r.Context(), "flash", "You've been logged in successfully!")
+
+	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
+}
+
+// oidcReauthenticated() finishes what accountReauthenticate() started, once
+// the ID token has been verified. The token has to be for the identity
+// linked to the logged in user, who has to have logged in at the provider
+// just now.
+func (app *application) oidcReauthenticated(w http.ResponseWriter, r *http.Request, idToken *oidc.IDToken, claims oidcClaims, next string) {
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	identity, err := app.identities.Get(idToken.Issuer, idToken.Subject)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, err)
+		return
+	}
+
+	if err != nil || identity.UserID != userID || time.Since(time.Unix(claims.AuthTime, 0)) > reauthenticateFor {
+		app.audit(r, "user.reauthenticate_failed", fmt.Sprintf("user:%d", userID), map[string]any{"method": "oidc", "issuer": idToken.Issuer})
+
+		app.sessionManager.Put(r.Context(), "flash", "Your identity provider didn't confirm it's you, please try again")
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "reauthenticated", time.Now().Unix())
+
+	app.audit(r, "user.reauthenticate", fmt.Sprintf("user:%d", userID), map[string]any{"method": "oidc", "issuer": idToken.Issuer})
+
+	app.sessionManager.Put(r.Context(), "flash", "You've confirmed it's you")
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// reauthenticated() reports whether the user confirmed who they are at the
+// identity provider recently enough to go without typing their password
+func (app *application) reauthenticated(r *http.Request) bool {
+	at := app.sessionManager.GetInt64(r.Context(), "reauthenticated")
+	return time.Since(time.Unix(at, 0)) < reauthenticateFor
+}
+
+// linkIdentity() returns the local user linked to the external identity,
+// finding or provisioning one by email the first time it is seen
+func (app *application) linkIdentity(issuer, subject string, claims oidcClaims) (int, error) {
+	identity, err := app.identities.Get(issuer, subject)
+	if err == nil {
+		return identity.UserID, nil
+	} else if !errors.Is(err, models.ErrNoRecord) {
+		return 0, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+
+	userID, err := app.users.Provision(name, claims.Email)
+	if err != nil {
+		return 0, err
+	}
+
+	err = app.identities.Insert(userID, issuer, subject, claims.Email)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+	"github.com/go-jose/go-jose/v4"
+	"golang.org/x/oauth2"
+)
+
+// fakeProvider is an in-process OpenID Connect provider. Instead of a
+// login page, tests call authorize() to obtain a code for the claims they
+// want the ID token to contain.
+type fakeProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	// forgeKey, if set, signs ID tokens instead of the published key
+	forgeKey *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeGrant
+}
+
+type fakeGrant struct {
+	challenge string
+	claims    map[string]any
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &fakeProvider{key: key, codes: map[string]fakeGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &p.key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", p.token)
+
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// authorize() plays the part of the provider's login page: it takes the
+// authorization URL we were redirected to and returns a code
+func (p *fakeProvider) authorize(t *testing.T, authURL string, claims map[string]any) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+
+	assert.Equal(t, q.Get("code_challenge_method"), "S256")
+
+	// a test case may set its own nonce to simulate a replayed token
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = q.Get("nonce")
+	}
+
+	code = rand.Text()
+	p.mu.Lock()
+	p.codes[code] = fakeGrant{challenge: q.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+
+	return code, q.Get("state")
+}
+
+func (p *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	p.mu.Lock()
+	grant, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || oauth2.S256ChallengeFromVerifier(r.PostForm.Get("code_verifier")) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]any{
+		"iss": p.URL,
+		"aud": "snippetbox",
+		"sub": "user-1234",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     p.sign(claims),
+	})
+}
+
+func (p *fakeProvider) sign(claims map[string]any) string {
+	key := p.key
+	if p.forgeKey != nil {
+		key = p.forgeKey
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		panic(err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		panic(err)
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newFakeProvider(t)
+	defer provider.Close()
+
+	app := newTestApplication(t)
+
+	var err error
+	app.oidc, err = newOIDCProvider(context.Background(), provider.URL, "snippetbox", "secret", "https://localhost:4000/user/login/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		badState bool
+		forged   bool
+		wantCode int
+	}{
+		{
+			name:     "Verified email",
+			claims:   map[string]any{"email": "alice@example.com", "email_verified": true, "name": "Alice"},
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Unverified email",
+			claims:   map[string]any{"email": "alice@example.com", "email_verified": false},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Wrong audience",
+			claims:   map[string]any{"email": "alice@example.com", "email_verified": true, "aud": "someone-else"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Wrong nonce",
+			claims:   map[string]any{"email": "alice@example.com", "email_verified": true, "nonce": "replayed"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Forged signature",
+			claims:   map[string]any{"email": "alice@example.com", "email_verified": true},
+			forged:   true,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Wrong state",
+			claims:   map[string]any{"email": "alice@example.com", "email_verified": true},
+			badState: true,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, _ := ts.get(t, "/user/login/oidc")
+			assert.Equal(t, code, http.StatusFound)
+
+			authCode, state := provider.authorize(t, headers.Get("Location"), tt.claims)
+			if tt.badState {
+				state = "forged"
+			}
+			if tt.forged {
+				provider.forgeKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+				defer func() { provider.forgeKey = nil }()
+			}
+
+			code, _, _ = ts.get(t, "/user/login/oidc/callback?code="+url.QueryEscape(authCode)+"&state="+url.QueryEscape(state))
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+
+	t.Run("Identity is linked once", func(t *testing.T) {
+		identities := app.identities.(*mocks.IdentityModel).Identities
+		assert.Equal(t, len(identities), 1)
+		assert.Equal(t, identities[0].Subject, "user-1234")
+		assert.Equal(t, identities[0].Issuer, provider.URL)
+	})
+
+	t.Run("Replayed callback", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/user/login/oidc")
+		assert.Equal(t, code, http.StatusFound)
+
+		authCode, state := provider.authorize(t, headers.Get("Location"), map[string]any{"email": "alice@example.com", "email_verified": true})
+		path := "/user/login/oidc/callback?code=" + url.QueryEscape(authCode) + "&state=" + url.QueryEscape(state)
+
+		code, _, _ = ts.get(t, path)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, _, _ = ts.get(t, path)
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/user/login/oidc")
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestOIDCReauthenticate(t *testing.T) {
+	provider := newFakeProvider(t)
+	defer provider.Close()
+
+	app := newTestApplication(t)
+
+	var err error
+	app.oidc, err = newOIDCProvider(context.Background(), provider.URL, "snippetbox", "secret", "https://localhost:4000/user/login/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// callback() completes a trip to the provider started at urlPath,
+	// returning where the user is sent back to
+	callback := func(t *testing.T, urlPath string, claims map[string]any) string {
+		code, headers, _ := ts.get(t, urlPath)
+		assert.Equal(t, code, http.StatusFound)
+
+		authCode, state := provider.authorize(t, headers.Get("Location"), claims)
+
+		code, headers, _ = ts.get(t, "/user/login/oidc/callback?code="+url.QueryEscape(authCode)+"&state="+url.QueryEscape(state))
+		assert.Equal(t, code, http.StatusSeeOther)
+		return headers.Get("Location")
+	}
+
+	// the user is provisioned by single sign-on, so has no password they
+	// could type in
+	callback(t, "/user/login/oidc", map[string]any{"email": "alice@example.com", "email_verified": true})
+
+	_, _, body := ts.get(t, "/account/delete")
+	assert.StringContains(t, body, `<a href="/account/reauthenticate?next=/account/delete">`)
+
+	form := url.Values{}
+	form.Add("snippets", "delete")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, body := ts.postForm(t, "/account/delete", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "This field cannot be blank")
+
+	t.Run("Prompts a new login", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/account/reauthenticate?next=/account/delete")
+		assert.Equal(t, code, http.StatusFound)
+
+		u, err := url.Parse(headers.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, u.Query().Get("prompt"), "login")
+		assert.Equal(t, u.Query().Get("max_age"), "0")
+	})
+
+	t.Run("Unknown next page", func(t *testing.T) {
+		code, _, _ := ts.get(t, "/account/reauthenticate?next=https://example.com/")
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+
+	t.Run("Old login at the provider", func(t *testing.T) {
+		next := callback(t, "/account/reauthenticate?next=/account/password", map[string]any{"auth_time": time.Now().Add(-time.Hour).Unix()})
+		assert.Equal(t, next, "/account/password")
+
+		_, _, body := ts.get(t, "/account/password")
+		assert.StringContains(t, body, "Your identity provider didn&#39;t confirm it&#39;s you")
+		assert.StringContains(t, body, `name="current_password"`)
+	})
+
+	t.Run("Someone else's identity", func(t *testing.T) {
+		callback(t, "/account/reauthenticate?next=/account/password", map[string]any{"sub": "user-5678", "auth_time": time.Now().Unix()})
+
+		_, _, body := ts.get(t, "/account/password")
+		assert.StringContains(t, body, `name="current_password"`)
+	})
+
+	t.Run("Change password", func(t *testing.T) {
+		callback(t, "/account/reauthenticate?next=/account/password", map[string]any{"auth_time": time.Now().Unix()})
+
+		_, _, body := ts.get(t, "/account/password")
+		assert.StringContains(t, body, "You&#39;ve confirmed it&#39;s you")
+
+		form := url.Values{}
+		form.Add("new_password", "new-password")
+		form.Add("new_password_confirmation", "new-password")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		code, _, _ := ts.postForm(t, "/account/password", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, app.users.(*mocks.UserModel).Passwords[1], "new-password")
+
+		// confirming it's you counts for one change
+		_, _, body = ts.get(t, "/account/password")
+		assert.StringContains(t, body, `name="current_password"`)
+	})
+
+	t.Run("Delete account", func(t *testing.T) {
+		callback(t, "/account/reauthenticate?next=/account/delete", map[string]any{"auth_time": time.Now().Unix()})
+
+		_, _, body := ts.get(t, "/account/delete")
+		form.Set("csrf_token", extractCSRFToken(t, body))
+
+		code, _, _ := ts.postForm(t, "/account/delete", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, len(app.accounts.(*mocks.AccountModel).Deleted), 1)
+	})
+
+	actions := []string{}
+	for _, e := range app.auditLog.(*mocks.AuditModel).Events {
+		actions = append(actions, e.Action)
+	}
+	assert.StringContains(t, strings.Join(actions, " "), "user.reauthenticate_failed user.reauthenticate_failed user.reauthenticate")
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/ui"
+)
+
+// openAPISpec is as much of the OpenAPI document as the tests look at
+type openAPISpec struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Enum       []string                   `json:"enum"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func readOpenAPISpec(t *testing.T) openAPISpec {
+	data, err := ui.Files.ReadFile("api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec openAPISpec
+	err = json.Unmarshal(data, &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+// documentedRoutes are the routes outside /api/ which are for programs
+// rather than browsers, and so are documented too. The pages, feeds,
+// embeds and images aren't.
+var documentedRoutes = []string{"POST /", "GET /oembed"}
+
+// apiRoutes() reads the routes under /api/ that routes() registers, and
+// the documentedRoutes, straight from its source, as "METHOD /path" with
+// httprouter's ":id" written the OpenAPI way as "{id}"
+func apiRoutes(t *testing.T) []string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "routes.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param := regexp.MustCompile(`:(\w+)`)
+	routes := []string{}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+
+		fn, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || fn.Sel.Name != "Handler" && fn.Sel.Name != "HandlerFunc" {
+			return true
+		}
+		if receiver, ok := fn.X.(*ast.Ident); !ok || receiver.Name != "router" {
+			return true
+		}
+
+		method, ok := call.Args[0].(*ast.SelectorExpr)
+		if !ok {
+			t.Fatalf("%s: can't read the method of a route", fset.Position(call.Pos()))
+		}
+		lit, ok := call.Args[1].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			t.Fatalf("%s: can't read the path of a route", fset.Position(call.Pos()))
+		}
+		path, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		route := strings.ToUpper(strings.TrimPrefix(method.Sel.Name, "Method")) + " " + param.ReplaceAllString(path, "{$1}")
+		if strings.HasPrefix(path, "/api/") || slices.Contains(documentedRoutes, route) {
+			routes = append(routes, route)
+		}
+		return true
+	})
+
+	slices.Sort(routes)
+	return routes
+}
+
+func specRoutes(spec openAPISpec) []string {
+	routes := []string{}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	slices.Sort(routes)
+	return routes
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	spec := readOpenAPISpec(t)
+	assert.Equal(t, spec.OpenAPI, "3.1.0")
+
+	registered := apiRoutes(t)
+	documented := specRoutes(spec)
+
+	if len(registered) == 0 {
+		t.Fatal("no API routes found in routes.go")
+	}
+
+	for _, route := range registered {
+		if !slices.Contains(documented, route) {
+			t.Errorf("%s is registered in routes() but missing from ui/api/openapi.json", route)
+		}
+	}
+	for _, route := range documented {
+		if !slices.Contains(registered, route) {
+			t.Errorf("%s is in ui/api/openapi.json but not registered in routes()", route)
+		}
+	}
+
+	for _, route := range documentedRoutes {
+		if !slices.Contains(registered, route) {
+			t.Errorf("%s is in documentedRoutes but not registered in routes()", route)
+		}
+	}
+
+	// and the router really does have them, not just the source. The
+	// oEmbed provider answers 404 without a snippet to embed.
+	app := newTestApplication(t)
+	routes := app.routes()
+
+	for _, route := range documented {
+		method, path, _ := strings.Cut(route, " ")
+		path = strings.ReplaceAll(path, "{id}", "1")
+		if path == "/oembed" {
+			path += "?url=" + url.QueryEscape(app.baseURL+"/snippet/view/1")
+		}
+
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+
+		if rr.Code == http.StatusNotFound || rr.Code == http.StatusMethodNotAllowed {
+			t.Errorf("%s: got %d", route, rr.Code)
+		}
+	}
+}
+
+// TestOpenAPISchemas checks the schemas have the same fields as the types
+// the handlers send
+func TestOpenAPISchemas(t *testing.T) {
+	spec := readOpenAPISpec(t)
+
+	tests := []struct {
+		schema string
+		value  any
+	}{
+		{schema: "Snippet", value: apiSnippet{}},
+		{schema: "ErrorBody", value: apiErrorBody{}},
+		{schema: "Error", value: apiError{}},
+		{schema: "OEmbed", value: oEmbedResponse{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			schema, ok := spec.Components.Schemas[tt.schema]
+			if !ok {
+				t.Fatalf("no %s schema", tt.schema)
+			}
+
+			properties := []string{}
+			for name := range schema.Properties {
+				properties = append(properties, name)
+			}
+			slices.Sort(properties)
+
+			assert.Equal(t, strings.Join(properties, ","), strings.Join(jsonFields(tt.value), ","))
+		})
+	}
+}
+
+func TestOpenAPIEnums(t *testing.T) {
+	spec := readOpenAPISpec(t)
+
+	languages := append([]string{""}, models.Languages...)
+	assert.Equal(t, strings.Join(spec.Components.Schemas["Language"].Enum, ","), strings.Join(languages, ","))
+
+	for _, v := range spec.Components.Schemas["Visibility"].Enum {
+		assert.Equal(t, models.Visibility(v).Valid(), true)
+	}
+	assert.Equal(t, len(spec.Components.Schemas["Visibility"].Enum), 3)
+}
+
+// jsonFields() returns the names v's fields have in JSON, sorted
+func jsonFields(v any) []string {
+	fields := []string{}
+
+	typ := reflect.TypeOf(v)
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	slices.Sort(fields)
+	return fields
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, body := ts.get(t, "/api/openapi.json")
+
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, header.Get("Content-Type"), "application/json")
+	assert.StringContains(t, body, `"openapi": "3.1.0"`)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// passkeyUser adapts a user and their registered passkeys to the
+// webauthn.User interface expected by the ceremonies
+type passkeyUser struct {
+	user     *models.User
+	passkeys []*models.Passkey
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return passkeyUserHandle(u.user.ID)
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.passkeys))
+	for i, p := range u.passkeys {
+		credentials[i] = p.Credential
+	}
+	return credentials
+}
+
+// the user handle is the big-endian user id, which lets a discoverable
+// login find the account without the user typing their email
+func passkeyUserHandle(id int) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(id))
+	return handle
+}
+
+func (app *application) loadPasskeyUser(id int) (*passkeyUser, error) {
+	user, err := app.users.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := app.passkeys.GetByUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &passkeyUser{user: user, passkeys: passkeys}, nil
+}
+
+// putPasskeySession() stores the ceremony state (challenge etc.) in the
+// user's session until the browser posts the authenticator response
+func (app *application) putPasskeySession(r *http.Request, key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), key, data)
+	return nil
+}
+
+// popPasskeySession() retrieves and removes the ceremony state, so that
+// every challenge can only be answered once
+func (app *application) popPasskeySession(r *http.Request, key string) (*webauthn.SessionData, bool) {
+	data := app.sessionManager.PopBytes(r.Context(), key)
+	if data == nil {
+		return nil, false
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, false
+	}
+
+	return &session, true
+}
+
+func (app *application) accountPasskeys(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	passkeys, err := app.passkeys.GetByUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Passkeys = passkeys
+
+	app.render(w, http.StatusOK, "passkeys.html", data)
+}
+
+func (app *application) passkeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	user, err := app.loadPasskeyUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// exclude the credentials already registered, so that the same
+	// authenticator isn't registered twice
+	exclusions := webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()
+
+	options, session, err := app.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.putPasskeySession(r, "passkeyRegistration", session)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, options)
+}
+
+func (app *application) passkeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	session, ok := app.popPasskeySession(r, "passkeyRegistration")
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	user, err := app.loadPasskeyUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	credential, err := app.webAuthn.FinishRegistration(user, *session, r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// the name is chosen by the user in the browser and passed along
+	// in the query string, as the body is the authenticator response
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		name = "Passkey"
+	}
+
+	err = app.passkeys.Insert(id, name, credential)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "passkey.register", "", map[string]any{"name": name})
+
+	app.sessionManager.Put(r.Context(), "flash", "Passkey registered successfully!")
+
+	app.writeJSON(w, http.StatusOK, map[string]string{"redirect": "/account/passkeys"})
+}
+
+type passkeyRevokeForm struct {
+	ID int `form:"id"`
+}
+
+func (app *application) passkeyRevokePost(w http.ResponseWriter, r *http.Request) {
+	var form passkeyRevokeForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	err = app.passkeys.Delete(id, form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "passkey.revoke", fmt.Sprintf("passkey:%d", form.ID), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "Passkey revoked successfully!")
+
+	http.Redirect(w, r, "/account/passkeys", http.StatusSeeOther)
+}
+
+func (app *application) passkeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	// a discoverable login doesn't need to know who the user is up front,
+	// the authenticator tells us through the user handle
+	options, session, err := app.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationPreferred),
+	)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.putPasskeySession(r, "passkeyLogin", session)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, options)
+}
+
+func (app *application) passkeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	session, ok := app.popPasskeySession(r, "passkeyLogin")
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var user *passkeyUser
+
+	lookup := func(rawID, userHandle []byte) (webauthn.User, error) {
+		if len(userHandle) != 8 {
+			return nil, models.ErrInvalidCredentials
+		}
+
+		var err error
+		user, err = app.loadPasskeyUser(int(binary.BigEndian.Uint64(userHandle)))
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	_, credential, err := app.webAuthn.FinishPasskeyLogin(lookup, *session, r)
+	if err != nil {
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	// a signature counter that didn't move forward means the credential
+	// private key may have been cloned, so refuse to log the user in
+	if credential.Authenticator.CloneWarning {
+		app.errorLog.Printf("passkey sign counter check failed for user %d", user.user.ID)
+		app.audit(r, "user.login_failed", fmt.Sprintf("user:%d", user.user.ID), map[string]any{"reason": "passkey_clone_warning"})
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	err = app.passkeys.UpdateCredential(credential)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.startSession(r, user.user.ID, "")
+	if err != nil {
+		if errors.Is(err, models.ErrAccountDisabled) {
+			app.audit(r, "user.login_failed", fmt.Sprintf("user:%d", user.user.ID), map[string]any{"reason": "disabled"})
+			app.clientError(w, http.StatusForbidden)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "user.login", fmt.Sprintf("user:%d", user.user.ID), map[string]any{"method": "passkey"})
+
+	app.sessionManager.Put(r.Context(), "flash", "You've been logged in successfully!")
+
+	app.writeJSON(w, http.StatusOK, map[string]string{"redirect": "/snippet/create"})
+}
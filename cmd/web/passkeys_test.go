@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// softAuthenticator is a software WebAuthn authenticator holding a single
+// ES256 credential. It produces the same JSON our passkeys.js posts.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+var b64 = base64.RawURLEncoding
+
+type ceremonyOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		User      struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"publicKey"`
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, typ, challenge string) []byte {
+	clientData, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": challenge,
+		"origin":    testRPOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientData
+}
+
+// authData builds the authenticator data: rpIdHash, flags and counter,
+// optionally followed by the attested credential data
+func (a *softAuthenticator) authData(t *testing.T, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	// user present and user verified
+	flags := byte(0x01 | 0x04)
+	if attested {
+		flags |= 0x40
+	}
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		point, err := a.key.PublicKey.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		coseKey, err := cbor.Marshal(map[int]any{
+			1:  2,  // kty: EC2
+			3:  -7, // alg: ES256
+			-1: 1,  // crv: P-256
+			-2: point[1:33],
+			-3: point[33:],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, coseKey...)
+	}
+
+	return data
+}
+
+func (a *softAuthenticator) create(t *testing.T, optionsJSON string) []byte {
+	var options ceremonyOptions
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		t.Fatal(err)
+	}
+
+	userHandle, err := b64.DecodeString(options.PublicKey.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.userHandle = userHandle
+
+	attestationObject, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"id":    b64.EncodeToString(a.credentialID),
+		"rawId": b64.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64.EncodeToString(a.clientData(t, "webauthn.create", options.PublicKey.Challenge)),
+			"attestationObject": b64.EncodeToString(attestationObject),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func (a *softAuthenticator) get(t *testing.T, optionsJSON string) []byte {
+	var options ceremonyOptions
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		t.Fatal(err)
+	}
+
+	clientData := a.clientData(t, "webauthn.get", options.PublicKey.Challenge)
+	authData := a.authData(t, false)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"id":    b64.EncodeToString(a.credentialID),
+		"rawId": b64.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64.EncodeToString(clientData),
+			"authenticatorData": b64.EncodeToString(authData),
+			"signature":         b64.EncodeToString(signature),
+			"userHandle":        b64.EncodeToString(a.userHandle),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// passkeyLogin() runs a full login ceremony with a fresh cookie jar and
+// returns the status code of the finish request
+func passkeyLogin(t *testing.T, ts *testServer, authenticator *softAuthenticator) int {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+
+	_, _, body := ts.get(t, "/user/login")
+	csrfToken := extractCSRFToken(t, body)
+
+	code, _, options := ts.postJSON(t, "/user/login/passkey/begin", csrfToken, nil)
+	assert.Equal(t, code, http.StatusOK)
+
+	code, _, _ = ts.postJSON(t, "/user/login/passkey/finish", csrfToken, authenticator.get(t, options))
+	return code
+}
+
+func TestPasskeys(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	authenticator := newSoftAuthenticator(t)
+
+	t.Run("Register", func(t *testing.T) {
+		ts.login(t)
+
+		_, _, body := ts.get(t, "/account/passkeys")
+		csrfToken := extractCSRFToken(t, body)
+
+		code, _, options := ts.postJSON(t, "/account/passkeys/register/begin", csrfToken, nil)
+		assert.Equal(t, code, http.StatusOK)
+
+		code, _, _ = ts.postJSON(t, "/account/passkeys/register/finish?name=Laptop", csrfToken, authenticator.create(t, options))
+		assert.Equal(t, code, http.StatusOK)
+
+		_, _, body = ts.get(t, "/account/passkeys")
+		assert.StringContains(t, body, "Laptop")
+	})
+
+	t.Run("Finish without begin", func(t *testing.T) {
+		_, _, body := ts.get(t, "/account/passkeys")
+		csrfToken := extractCSRFToken(t, body)
+
+		code, _, _ := ts.postJSON(t, "/account/passkeys/register/finish", csrfToken, newSoftAuthenticator(t).create(t, `{}`))
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		authenticator.signCount++
+		code := passkeyLogin(t, ts, authenticator)
+		assert.Equal(t, code, http.StatusOK)
+
+		code, _, _ = ts.get(t, "/account/passkeys")
+		assert.Equal(t, code, http.StatusOK)
+	})
+
+	t.Run("Stale sign counter", func(t *testing.T) {
+		code := passkeyLogin(t, ts, authenticator)
+		assert.Equal(t, code, http.StatusUnauthorized)
+
+		code, _, _ = ts.get(t, "/account/passkeys")
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+
+	t.Run("Unknown credential", func(t *testing.T) {
+		other := newSoftAuthenticator(t)
+		other.userHandle = authenticator.userHandle
+		other.signCount = 100
+
+		code := passkeyLogin(t, ts, other)
+		assert.Equal(t, code, http.StatusUnauthorized)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		ts.login(t)
+
+		_, _, body := ts.get(t, "/account/passkeys")
+
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		form.Add("id", "1")
+		code, _, _ := ts.postForm(t, "/account/passkeys/revoke", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, len(app.passkeys.(*mocks.PasskeyModel).Passkeys), 0)
+
+		authenticator.signCount++
+		code = passkeyLogin(t, ts, authenticator)
+		assert.Equal(t, code, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+// maxPasteBytes is the most the paste endpoint reads, counting any
+// multipart framing
+const maxPasteBytes = 1 << 20
+
+// pasteOptions can be given as query parameters, X-Snippet-* headers or,
+// in a multipart body, as fields of their own
+var pasteOptions = []string{"title", "language", "expires", "visibility", "redact"}
+
+// paste() creates a snippet from the body of a request made by curl or
+// similar, such as `curl -F 'f=<-'` or `curl --data-binary @file`, and
+// answers with its URL in plain text. The body is either multipart, when
+// the first part that isn't an option is the content, or the content
+// itself.
+func (app *application) paste(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasteBytes)
+
+	content, filename, fields, err := readPaste(r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, fmt.Sprintf("The paste must not be larger than %d bytes", maxBytesError.Limit), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	// the query string beats headers, which beat multipart fields
+	options := url.Values{}
+	for _, name := range pasteOptions {
+		switch {
+		case r.URL.Query().Get(name) != "":
+			options.Set(name, r.URL.Query().Get(name))
+		case r.Header.Get("X-Snippet-"+name) != "":
+			options.Set(name, r.Header.Get("X-Snippet-"+name))
+		default:
+			options.Set(name, fields.Get(name))
+		}
+	}
+
+	v := validator.Validator{}
+
+	title := options.Get("title")
+	if title == "" {
+		title = filename
+	}
+	if title == "" {
+		title = models.FirstLine(content)
+	}
+
+	language := options.Get("language")
+	if language == "" {
+		language = models.LanguageFor(filename)
+	}
+
+	visibility := models.Visibility(options.Get("visibility"))
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+
+	expires := readInt(&v, options, "expires", 365)
+
+	redact := false
+	if s := options.Get("redact"); s != "" {
+		redact, err = strconv.ParseBool(s)
+		v.CheckField(err == nil, "redact", "This field must be true or false")
+	}
+
+	v.CheckField(utf8.ValidString(content), "content", "This field must be UTF-8 text")
+
+	redacted, found := app.checkSnippet(&v, &title, &content, language, visibility, expires, redact)
+
+	if !v.Valid() {
+		app.failedValidationText(w, v, found)
+		return
+	}
+
+	id, err := app.snippets.Insert(app.userID(r), title, content, language, visibility, expires)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	metadata := map[string]any{"via": "paste"}
+	if redacted > 0 {
+		metadata["secrets_redacted"] = redacted
+	}
+	app.audit(r, "snippet.create", fmt.Sprintf("snippet:%d", id), metadata)
+	app.snippetCreated(id)
+
+	snippetURL := fmt.Sprintf("%s/snippet/view/%d", app.baseURL, id)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Location", snippetURL)
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintln(w, snippetURL)
+}
+
+// readPaste() returns the content of a paste, the name of the file it came
+// from if the client sent one, and any option fields of a multipart body
+func readPaste(r *http.Request) (string, string, url.Values, error) {
+	fields := url.Values{}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return string(content), "", fields, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	var content, filename string
+	found := false
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", "", nil, err
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return "", "", nil, err
+		}
+
+		switch {
+		case slices.Contains(pasteOptions, part.FormName()):
+			fields.Set(part.FormName(), string(data))
+		case found:
+			return "", "", nil, errors.New("The body must contain a single paste")
+		default:
+			content, filename, found = string(data), part.FileName(), true
+		}
+	}
+
+	if !found {
+		return "", "", nil, errors.New("The body must contain a paste")
+	}
+	return content, filename, fields, nil
+}
+
+// failedValidationText() is the paste endpoint's version of
+// failedValidationJSON, with a line for each field that was wrong
+func (app *application) failedValidationText(w http.ResponseWriter, v validator.Validator, secretsFound bool) {
+	lines := slices.Clone(v.NonFieldErrors)
+	for _, field := range slices.Sorted(maps.Keys(v.FieldErrors)) {
+		lines = append(lines, fmt.Sprintf("%s: %s", field, v.FieldErrors[field]))
+	}
+	if secretsFound {
+		lines = append(lines, "Add ?redact=true to have the secrets redacted.")
+	}
+
+	http.Error(w, strings.Join(lines, "\n"), http.StatusUnprocessableEntity)
+}
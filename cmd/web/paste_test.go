@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+// paste() posts body to the paste endpoint with the token, if any, and
+// the extra headers, without cookies, the way curl would
+func (ts *testServer) paste(t *testing.T, urlPath, token string, body io.Reader, header http.Header) (int, http.Header, string) {
+	req, err := http.NewRequest(http.MethodPost, ts.URL+urlPath, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rs, err := ts.Client().Transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	respBody, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(respBody)
+}
+
+// multipartBody() builds a form like curl -F does, with a file part if
+// filename isn't empty and a plain field otherwise, followed by fields
+func multipartBody(t *testing.T, name, filename, content string, fields map[string]string) (io.Reader, http.Header) {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+
+	var part io.Writer
+	var err error
+	if filename != "" {
+		part, err = mw.CreateFormFile(name, filename)
+	} else {
+		part, err = mw.CreateFormField(name)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, content)
+
+	for key, value := range fields {
+		mw.WriteField(key, value)
+	}
+
+	err = mw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body, http.Header{"Content-Type": {mw.FormDataContentType()}}
+}
+
+func lastInserted(app *application) *models.Snippet {
+	inserted := app.snippets.(*mocks.SnippetModel).Inserted
+	return inserted[len(inserted)-1]
+}
+
+func TestPaste(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	token := newAPIToken(t, app, 1, models.ScopeWrite)
+
+	t.Run("Raw body", func(t *testing.T) {
+		code, header, body := ts.paste(t, "/", token, strings.NewReader("\n  Climb Mount Fuji  \nslowly\n"),
+			http.Header{"Content-Type": {"application/x-www-form-urlencoded"}})
+
+		assert.Equal(t, code, http.StatusCreated)
+		assert.Equal(t, body, "https://localhost:4000/snippet/view/2\n")
+		assert.Equal(t, header.Get("Location"), "https://localhost:4000/snippet/view/2")
+		assert.StringContains(t, header.Get("Content-Type"), "text/plain")
+
+		s := lastInserted(app)
+		assert.Equal(t, s.UserID, 1)
+		assert.Equal(t, s.Title, "Climb Mount Fuji")
+		assert.Equal(t, s.Content, "\n  Climb Mount Fuji  \nslowly\n")
+		assert.Equal(t, s.Language, "")
+		assert.Equal(t, s.Visibility, models.VisibilityPublic)
+
+		events := app.auditLog.(*mocks.AuditModel).Events
+		event := events[len(events)-1]
+		assert.Equal(t, event.Action, "snippet.create")
+		assert.Equal(t, event.Metadata["via"], "paste")
+	})
+
+	t.Run("Multipart file", func(t *testing.T) {
+		body, header := multipartBody(t, "f", "main.go", "package main\n", nil)
+
+		code, _, _ := ts.paste(t, "/", token, body, header)
+		assert.Equal(t, code, http.StatusCreated)
+
+		s := lastInserted(app)
+		assert.Equal(t, s.Title, "main.go")
+		assert.Equal(t, s.Language, "go")
+	})
+
+	t.Run("Multipart field with options", func(t *testing.T) {
+		body, header := multipartBody(t, "f", "", "SELECT 1;\n", map[string]string{
+			"title":    "A query",
+			"language": "sql",
+			"expires":  "1",
+		})
+
+		code, _, _ := ts.paste(t, "/", token, body, header)
+		assert.Equal(t, code, http.StatusCreated)
+
+		s := lastInserted(app)
+		assert.Equal(t, s.Title, "A query")
+		assert.Equal(t, s.Language, "sql")
+	})
+
+	t.Run("Options from headers and query", func(t *testing.T) {
+		body, header := multipartBody(t, "f", "", "content", map[string]string{"title": "From the form"})
+		header.Set("X-Snippet-Title", "From a header")
+		header.Set("X-Snippet-Visibility", "private")
+
+		code, _, _ := ts.paste(t, "/?visibility=unlisted", token, body, header)
+		assert.Equal(t, code, http.StatusCreated)
+
+		s := lastInserted(app)
+		assert.Equal(t, s.Title, "From a header")
+		assert.Equal(t, s.Visibility, models.VisibilityUnlisted)
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		code, header, body := ts.paste(t, "/?expires=2&visibility=secret&redact=maybe", token, strings.NewReader("content"), nil)
+
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, header.Get("Content-Type"), "text/plain")
+		assert.StringContains(t, body, "expires: This field must equal 1, 7 or 365")
+		assert.StringContains(t, body, "redact: This field must be true or false")
+		assert.StringContains(t, body, "visibility: This field must be public, unlisted or private")
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		code, _, body := ts.paste(t, "/", token, strings.NewReader(""), nil)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "content: This field cannot be blank")
+	})
+
+	t.Run("Not text", func(t *testing.T) {
+		code, _, body := ts.paste(t, "/", token, strings.NewReader("title\n\xff\xfe"), nil)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "content: This field must be UTF-8 text")
+	})
+
+	t.Run("Two pastes", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		mw.WriteField("f", "one")
+		mw.WriteField("g", "two")
+		mw.Close()
+
+		code, _, respBody := ts.paste(t, "/", token, body, http.Header{"Content-Type": {mw.FormDataContentType()}})
+		assert.Equal(t, code, http.StatusBadRequest)
+		assert.StringContains(t, respBody, "The body must contain a single paste")
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		code, _, body := ts.paste(t, "/", token, strings.NewReader(strings.Repeat("a", maxPasteBytes+1)), nil)
+		assert.Equal(t, code, http.StatusRequestEntityTooLarge)
+		assert.StringContains(t, body, "The paste must not be larger than 1048576 bytes")
+	})
+}
+
+func TestPasteSecrets(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	token := newAPIToken(t, app, 1, models.ScopeWrite)
+	content := "export GITHUB_TOKEN=ghp_" + "R8yQm2Vx4Lk9Pz7Tw3Nc6Hb1Jd5Fg0Sa2Ue8"
+
+	code, _, body := ts.paste(t, "/", token, strings.NewReader(content), nil)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "GitHub token on line 1")
+	assert.StringContains(t, body, "Add ?redact=true")
+
+	code, _, _ = ts.paste(t, "/?redact=true", token, strings.NewReader(content), nil)
+	assert.Equal(t, code, http.StatusCreated)
+	assert.Equal(t, lastInserted(app).Content, "export GITHUB_TOKEN=[REDACTED]")
+}
+
+func TestPasteAuthentication(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "No token",
+			wantCode: http.StatusUnauthorized,
+			wantBody: "This endpoint needs an API token",
+		},
+		{
+			name:     "Invalid token",
+			token:    models.APITokenPrefix + strings.Repeat("A", 26),
+			wantCode: http.StatusUnauthorized,
+			wantBody: "The API token is invalid or has been revoked",
+		},
+		{
+			name:     "Read-only token",
+			token:    newAPIToken(t, app, 1, models.ScopeRead),
+			wantCode: http.StatusForbidden,
+			wantBody: "The API token needs the write scope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.paste(t, "/", tt.token, strings.NewReader("content"), nil)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, header.Get("Content-Type"), "text/plain")
+			assert.StringContains(t, header.Get("WWW-Authenticate"), "Bearer")
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+
+	assert.Equal(t, len(app.snippets.(*mocks.SnippetModel).Inserted), 0)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+// pastebinExpiry maps Pastebin's api_paste_expire_date values to the days
+// a snippet can last, rounding up since none are shorter than a day.
+// Snippets always expire, so "N" (never) gets the longest there is.
+var pastebinExpiry = map[string]int{
+	"":    365,
+	"N":   365,
+	"10M": 1,
+	"1H":  1,
+	"1D":  1,
+	"1W":  7,
+	"2W":  365,
+	"1M":  365,
+	"6M":  365,
+	"1Y":  365,
+}
+
+// pastebinVisibility maps api_paste_private to visibilities, by position
+var pastebinVisibility = []models.Visibility{models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate}
+
+// pastebinFormats maps the Pastebin formats whose names differ from our
+// languages. Other formats are used as they are if we have them, and
+// anything else is plain text, as clients send formats we don't know.
+var pastebinFormats = map[string]string{
+	"text":        "",
+	"html4strict": "html",
+	"html5":       "html",
+	"c_mac":       "c",
+	"cpp-qt":      "cpp",
+	"cpp-winapi":  "cpp",
+	"mysql":       "sql",
+	"postgresql":  "sql",
+	"tsql":        "sql",
+}
+
+func pastebinLanguage(format string) string {
+	if language, ok := pastebinFormats[format]; ok {
+		return language
+	}
+	if models.ValidLanguage(format) {
+		return format
+	}
+	return ""
+}
+
+// pastebinError() answers the way Pastebin does, with 200 OK and a message
+// starting "Bad API request, ", which is what clients look for
+func pastebinError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "Bad API request, "+message)
+}
+
+// authenticateDevKey() takes the user from the api_dev_key form field of
+// a request to the Pastebin compatible API. Unlike the JSON API a key is
+// always needed, and it only ever acts as an ordinary user.
+func (app *application) authenticateDevKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the id always goes in the context, so nothing falls back to
+		// looking for a session that isn't there
+		r = r.WithContext(context.WithValue(r.Context(), userIDContextKey, 0))
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxPasteBytes)
+
+		// clients send either kind of form
+		err := r.ParseMultipartForm(maxPasteBytes)
+		if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				pastebinError(w, fmt.Sprintf("the request must not be larger than %d bytes", maxBytesError.Limit))
+			} else {
+				pastebinError(w, "the form could not be read")
+			}
+			return
+		}
+
+		key := r.PostForm.Get("api_dev_key")
+		if key == "" {
+			pastebinError(w, "invalid api_dev_key")
+			return
+		}
+
+		userID, err := app.devKeys.Authenticate(key)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				pastebinError(w, "invalid api_dev_key")
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		user, err := app.users.Get(userID)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				pastebinError(w, "invalid api_dev_key")
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		if user.Disabled {
+			pastebinError(w, "invalid api_dev_key")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, roleContextKey, models.RoleUser)
+		ctx = context.WithValue(ctx, userIDContextKey, userID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// pastebinPost() is Pastebin's api_post.php, which does what api_option
+// says. The developer key identifies the user, so api_user_key isn't
+// needed and is ignored.
+func (app *application) pastebinPost(w http.ResponseWriter, r *http.Request) {
+	switch r.PostForm.Get("api_option") {
+	case "paste":
+		app.pastebinPaste(w, r)
+	case "list":
+		app.pastebinList(w, r)
+	case "delete":
+		app.pastebinDelete(w, r)
+	default:
+		pastebinError(w, "invalid api_option")
+	}
+}
+
+func (app *application) pastebinPaste(w http.ResponseWriter, r *http.Request) {
+	content := r.PostForm.Get("api_paste_code")
+	if strings.TrimSpace(content) == "" {
+		pastebinError(w, "api_paste_code was empty")
+		return
+	}
+
+	expires, ok := pastebinExpiry[r.PostForm.Get("api_paste_expire_date")]
+	if !ok {
+		pastebinError(w, "invalid api_paste_expire_date")
+		return
+	}
+
+	private := r.PostForm.Get("api_paste_private")
+	if private == "" {
+		private = "0"
+	}
+	n, err := strconv.Atoi(private)
+	if err != nil || n < 0 || n >= len(pastebinVisibility) {
+		pastebinError(w, "invalid api_paste_private")
+		return
+	}
+	visibility := pastebinVisibility[n]
+
+	// Pastebin allows longer names than our titles
+	title := models.FirstLine(r.PostForm.Get("api_paste_name"))
+	if title == "" {
+		title = models.FirstLine(content)
+	}
+
+	language := pastebinLanguage(r.PostForm.Get("api_paste_format"))
+
+	v := validator.Validator{}
+	app.checkSnippet(&v, &title, &content, language, visibility, expires, false)
+
+	// with everything else checked above, only secrets can be left
+	if !v.Valid() {
+		if message, ok := v.FieldErrors["content"]; ok {
+			pastebinError(w, "api_paste_code: "+message)
+		} else {
+			pastebinError(w, "api_paste_name: "+v.FieldErrors["title"])
+		}
+		return
+	}
+
+	id, err := app.snippets.Insert(app.userID(r), title, content, language, visibility, expires)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "snippet.create", fmt.Sprintf("snippet:%d", id), map[string]any{"via": "pastebin"})
+	app.snippetCreated(id)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "%s/snippet/view/%d", app.baseURL, id)
+}
+
+// pastebinList() lists the user's snippets, newest first, in Pastebin's
+// XML-like format
+func (app *application) pastebinList(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.PostForm.Get("api_results_limit"))
+	if err != nil {
+		limit = 50
+	}
+	limit = max(1, min(limit, 1000))
+
+	snippets, _, err := app.snippets.Page("", app.userID(r), limit, 0)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if len(snippets) == 0 {
+		fmt.Fprint(w, "No pastes found.")
+		return
+	}
+
+	for _, s := range snippets {
+		short, long := s.Language, s.Language
+		if s.Language == "" {
+			short, long = "text", "None"
+		}
+
+		fmt.Fprintf(w, "<paste>\n")
+		fmt.Fprintf(w, "\t<paste_key>%d</paste_key>\n", s.ID)
+		fmt.Fprintf(w, "\t<paste_date>%d</paste_date>\n", s.Created.Unix())
+		fmt.Fprintf(w, "\t<paste_title>%s</paste_title>\n", html.EscapeString(s.Title))
+		fmt.Fprintf(w, "\t<paste_size>%d</paste_size>\n", len(s.Content))
+		fmt.Fprintf(w, "\t<paste_expire_date>%d</paste_expire_date>\n", s.Expires.Unix())
+		fmt.Fprintf(w, "\t<paste_private>%d</paste_private>\n", pastebinPrivate(s.Visibility))
+		fmt.Fprintf(w, "\t<paste_format_long>%s</paste_format_long>\n", long)
+		fmt.Fprintf(w, "\t<paste_format_short>%s</paste_format_short>\n", short)
+		fmt.Fprintf(w, "\t<paste_url>%s/snippet/view/%d</paste_url>\n", app.baseURL, s.ID)
+		fmt.Fprintf(w, "\t<paste_hits>0</paste_hits>\n")
+		fmt.Fprintf(w, "</paste>\n")
+	}
+}
+
+func pastebinPrivate(visibility models.Visibility) int {
+	for i, v := range pastebinVisibility {
+		if v == visibility {
+			return i
+		}
+	}
+	return 0
+}
+
+// pastebinDelete() deletes one of the user's snippets, whose id is the
+// api_paste_key
+func (app *application) pastebinDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PostForm.Get("api_paste_key"))
+	if err != nil || id < 1 {
+		pastebinError(w, "invalid permission to remove paste")
+		return
+	}
+
+	deleted, err := app.getSnippets([]int{id})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.snippets.Delete(app.userID(r), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			pastebinError(w, "invalid permission to remove paste")
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "snippet.delete", fmt.Sprintf("snippet:%d", id), map[string]any{"via": "pastebin"})
+	app.snippetsDeleted(deleted)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "Paste Removed")
+}
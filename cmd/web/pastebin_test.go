@@ -0,0 +1,258 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+	"github.com/PPRAMANIK62/snippetbox/internal/ratelimit"
+)
+
+// pastebin() posts the form to api_post.php the way a Pastebin client
+// would, without cookies
+func (ts *testServer) pastebin(t *testing.T, form url.Values) (int, string) {
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/api_post.php", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rs, err := ts.Client().Transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, string(body)
+}
+
+func newDevKey(t *testing.T, app *application, userID int) string {
+	key, err := app.devKeys.Generate(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestPastebinPaste(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	key := newDevKey(t, app, 1)
+
+	t.Run("Valid", func(t *testing.T) {
+		code, body := ts.pastebin(t, url.Values{
+			"api_dev_key":           {key},
+			"api_option":            {"paste"},
+			"api_paste_code":        {"<?php echo 'hi';"},
+			"api_paste_name":        {"Greeting"},
+			"api_paste_format":      {"php"},
+			"api_paste_private":     {"1"},
+			"api_paste_expire_date": {"1W"},
+		})
+
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, body, "https://localhost:4000/snippet/view/2")
+
+		s := lastInserted(app)
+		assert.Equal(t, s.UserID, 1)
+		assert.Equal(t, s.Title, "Greeting")
+		assert.Equal(t, s.Content, "<?php echo 'hi';")
+		assert.Equal(t, s.Language, "php")
+		assert.Equal(t, s.Visibility, models.VisibilityUnlisted)
+		assert.Equal(t, s.Expires.Sub(s.Created).Round(time.Hour), 7*24*time.Hour)
+
+		events := app.auditLog.(*mocks.AuditModel).Events
+		assert.Equal(t, events[len(events)-1].Metadata["via"], "pastebin")
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		code, _ := ts.pastebin(t, url.Values{
+			"api_dev_key":    {key},
+			"api_option":     {"paste"},
+			"api_paste_code": {"\nFirst line\nsecond"},
+		})
+		assert.Equal(t, code, http.StatusOK)
+
+		s := lastInserted(app)
+		assert.Equal(t, s.Title, "First line")
+		assert.Equal(t, s.Language, "")
+		assert.Equal(t, s.Visibility, models.VisibilityPublic)
+		assert.Equal(t, s.Expires.Sub(s.Created).Round(time.Hour), 365*24*time.Hour)
+	})
+
+	t.Run("Formats", func(t *testing.T) {
+		for format, want := range map[string]string{"html5": "html", "text": "", "lua": "", "go": "go"} {
+			ts.pastebin(t, url.Values{
+				"api_dev_key":      {key},
+				"api_option":       {"paste"},
+				"api_paste_code":   {"x"},
+				"api_paste_format": {format},
+			})
+			assert.Equal(t, lastInserted(app).Language, want)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		wantBody string
+	}{
+		{
+			name:     "No key",
+			form:     url.Values{"api_option": {"paste"}, "api_paste_code": {"x"}},
+			wantBody: "Bad API request, invalid api_dev_key",
+		},
+		{
+			name:     "Wrong key",
+			form:     url.Values{"api_dev_key": {"0123456789abcdef0123456789abcdef"}, "api_option": {"paste"}, "api_paste_code": {"x"}},
+			wantBody: "Bad API request, invalid api_dev_key",
+		},
+		{
+			name:     "Bad option",
+			form:     url.Values{"api_dev_key": {key}, "api_option": {"userdetails"}},
+			wantBody: "Bad API request, invalid api_option",
+		},
+		{
+			name:     "Empty",
+			form:     url.Values{"api_dev_key": {key}, "api_option": {"paste"}, "api_paste_code": {"  \n"}},
+			wantBody: "Bad API request, api_paste_code was empty",
+		},
+		{
+			name:     "Bad expiry",
+			form:     url.Values{"api_dev_key": {key}, "api_option": {"paste"}, "api_paste_code": {"x"}, "api_paste_expire_date": {"3D"}},
+			wantBody: "Bad API request, invalid api_paste_expire_date",
+		},
+		{
+			name:     "Bad privacy",
+			form:     url.Values{"api_dev_key": {key}, "api_option": {"paste"}, "api_paste_code": {"x"}, "api_paste_private": {"3"}},
+			wantBody: "Bad API request, invalid api_paste_private",
+		},
+		{
+			name:     "Secrets",
+			form:     url.Values{"api_dev_key": {key}, "api_option": {"paste"}, "api_paste_code": {"export GITHUB_TOKEN=ghp_" + "R8yQm2Vx4Lk9Pz7Tw3Nc6Hb1Jd5Fg0Sa2Ue8"}},
+			wantBody: "Bad API request, api_paste_code: This looks like it contains secrets (GitHub token on line 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inserted := len(app.snippets.(*mocks.SnippetModel).Inserted)
+
+			code, body := ts.pastebin(t, tt.form)
+			assert.Equal(t, code, http.StatusOK)
+			assert.StringContains(t, body, tt.wantBody)
+			assert.Equal(t, len(app.snippets.(*mocks.SnippetModel).Inserted), inserted)
+		})
+	}
+}
+
+func TestPastebinList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, body := ts.pastebin(t, url.Values{"api_dev_key": {newDevKey(t, app, 1)}, "api_option": {"list"}})
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<paste>\n\t<paste_key>1</paste_key>\n")
+	assert.StringContains(t, body, "<paste_title>An old silent pond</paste_title>")
+	assert.StringContains(t, body, "<paste_private>0</paste_private>")
+	assert.StringContains(t, body, "<paste_format_short>text</paste_format_short>")
+	assert.StringContains(t, body, "<paste_url>https://localhost:4000/snippet/view/1</paste_url>")
+
+	_, body = ts.pastebin(t, url.Values{"api_dev_key": {newDevKey(t, app, 2)}, "api_option": {"list"}})
+	assert.Equal(t, body, "No pastes found.")
+}
+
+func TestPastebinDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, body := ts.pastebin(t, url.Values{"api_dev_key": {newDevKey(t, app, 2)}, "api_option": {"delete"}, "api_paste_key": {"1"}})
+	assert.Equal(t, body, "Bad API request, invalid permission to remove paste")
+
+	_, body = ts.pastebin(t, url.Values{"api_dev_key": {newDevKey(t, app, 1)}, "api_option": {"delete"}, "api_paste_key": {"1"}})
+	assert.Equal(t, body, "Paste Removed")
+	assert.Equal(t, len(app.snippets.(*mocks.SnippetModel).Deleted), 1)
+}
+
+func TestAccountDevKey(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/account/tokens")
+	assert.StringContains(t, body, "You don't have a developer key.")
+	csrfToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("csrf_token", csrfToken)
+
+	// revoking a key that doesn't exist is a 404
+	code, _, _ := ts.postForm(t, "/account/devkey/revoke", form)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, header, body := ts.postForm(t, "/account/devkey", form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("Cache-Control"), "no-store")
+
+	var key string
+	for k := range app.devKeys.(*mocks.DevKeyModel).Keys {
+		key = k
+	}
+	assert.StringContains(t, body, key)
+
+	_, body = ts.pastebin(t, url.Values{"api_dev_key": {key}, "api_option": {"list"}})
+	assert.StringContains(t, body, "<paste_key>1</paste_key>")
+
+	// a new key replaces the old one
+	ts.postForm(t, "/account/devkey", form)
+	_, body = ts.pastebin(t, url.Values{"api_dev_key": {key}, "api_option": {"list"}})
+	assert.Equal(t, body, "Bad API request, invalid api_dev_key")
+
+	_, _, body = ts.get(t, "/account/tokens")
+	assert.StringContains(t, body, "Your key was created")
+
+	code, _, _ = ts.postForm(t, "/account/devkey/revoke", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, len(app.devKeys.(*mocks.DevKeyModel).Keys), 0)
+
+	actions := []string{}
+	for _, e := range app.auditLog.(*mocks.AuditModel).Events {
+		actions = append(actions, e.Action)
+	}
+	assert.StringContains(t, strings.Join(actions, " "), "dev_key.create dev_key.create dev_key.revoke")
+}
+
+func TestPastebinRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	app.rateLimits.write = ratelimit.New(ratelimit.Limit{Requests: 1, Per: time.Minute}, 10)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	form := url.Values{"api_dev_key": {"wrong"}, "api_option": {"list"}}
+
+	code, body := ts.pastebin(t, form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, "Bad API request, invalid api_dev_key")
+
+	// guessing keys is limited by address, before they are checked
+	code, _ = ts.pastebin(t, form)
+	assert.Equal(t, code, http.StatusTooManyRequests)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// realClient() works out who the request came from, and whether they
+// used HTTPS, before anything else looks at the request. The answers are
+// kept in the request context for clientIP() and clientScheme().
+func (app *application) realClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, scheme := app.resolveClient(r)
+
+		ctx := context.WithValue(r.Context(), clientIPContextKey, ip)
+		ctx = context.WithValue(ctx, clientSchemeContextKey, scheme)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIP() returns the address the request came from, as found by the
+// realClient middleware
+func (app *application) clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+
+	ip, _ := app.resolveClient(r)
+	return ip
+}
+
+// clientScheme() returns "https" or "http", whichever the client used to
+// reach us or the first of our proxies
+func (app *application) clientScheme(r *http.Request) string {
+	if scheme, ok := r.Context().Value(clientSchemeContextKey).(string); ok {
+		return scheme
+	}
+
+	_, scheme := app.resolveClient(r)
+	return scheme
+}
+
+// hop is one proxy's record of where it got the request from
+type hop struct {
+	addr   netip.Addr
+	scheme string
+}
+
+// resolveClient() returns the client's address and scheme. A request
+// from a trusted proxy is taken to come from the address the proxy
+// forwarded it for, using the first of the Forwarded, X-Forwarded-For and
+// X-Real-IP headers that is present. Forwarding headers are lists each
+// proxy adds to, so they are read from the right, stopping at the first
+// address that isn't a trusted proxy: anything to the left of it was sent
+// by the client and can't be believed. Headers from untrusted peers are
+// ignored altogether.
+func (app *application) resolveClient(r *http.Request) (string, string) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	ip := remoteIP(r)
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !app.trustedProxy(addr) {
+		return ip, scheme
+	}
+
+	var hops []hop
+
+	switch {
+	case len(r.Header.Values("Forwarded")) > 0:
+		hops = forwardedHops(r.Header.Values("Forwarded"))
+	case len(r.Header.Values("X-Forwarded-For")) > 0:
+		hops = xForwardedHops(r.Header.Values("X-Forwarded-For"))
+		hops[len(hops)-1].scheme = lastListValue(r.Header.Values("X-Forwarded-Proto"))
+	case r.Header.Get("X-Real-IP") != "":
+		hops = []hop{{addr: parseNode(strings.TrimSpace(r.Header.Get("X-Real-IP")))}}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		h := hops[i]
+
+		// a garbled entry can't be trusted, and nor can anything
+		// further back
+		if !h.addr.IsValid() {
+			break
+		}
+
+		addr = h.addr
+		if h.scheme == "http" || h.scheme == "https" {
+			scheme = h.scheme
+		}
+
+		if !app.trustedProxy(addr) {
+			return addr.String(), scheme
+		}
+	}
+
+	// every hop was a trusted proxy, or the header couldn't be read
+	// past the last good one
+	return addr.String(), scheme
+}
+
+// forwardedHops() reads RFC 7239 Forwarded headers, such as
+//
+//	Forwarded: for=192.0.2.43;proto=https, for="[2001:db8::17]:4711"
+//
+// An element with a "for" that isn't an IP address, such as "unknown" or
+// an obfuscated "_hidden", gives a hop with an invalid address.
+func forwardedHops(headers []string) []hop {
+	hops := []hop{}
+
+	for _, header := range headers {
+		for _, element := range splitQuoted(header, ',') {
+			h := hop{}
+
+			for _, pair := range splitQuoted(element, ';') {
+				key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+
+				switch strings.ToLower(key) {
+				case "for":
+					h.addr = parseNode(value)
+				case "proto":
+					h.scheme = strings.ToLower(value)
+				}
+			}
+
+			hops = append(hops, h)
+		}
+	}
+
+	return hops
+}
+
+// xForwardedHops() reads X-Forwarded-For headers, lists of bare addresses
+func xForwardedHops(headers []string) []hop {
+	hops := []hop{}
+
+	for _, header := range headers {
+		for _, field := range strings.Split(header, ",") {
+			hops = append(hops, hop{addr: parseNode(strings.TrimSpace(field))})
+		}
+	}
+
+	return hops
+}
+
+// parseNode() parses an address which may have a port, and IPv6
+// addresses in brackets, returning the zero Addr if it isn't one
+func parseNode(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// splitQuoted() splits s at sep, except inside double quotes
+func splitQuoted(s string, sep rune) []string {
+	parts := []string{}
+
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// lastListValue() returns the right-most value of a comma separated
+// header, the one added by the nearest proxy
+func lastListValue(headers []string) string {
+	if len(headers) == 0 {
+		return ""
+	}
+
+	fields := strings.Split(headers[len(headers)-1], ",")
+	return strings.ToLower(strings.TrimSpace(fields[len(fields)-1]))
+}
+
+func (app *application) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range app.trustedProxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies() reads a comma separated list of addresses and
+// CIDR ranges, such as "10.0.0.0/8,192.0.2.1"
+func parseTrustedProxies(s string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", field, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", field, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
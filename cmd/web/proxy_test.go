@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+func TestResolveClient(t *testing.T) {
+	app := newTestApplication(t)
+
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 192.0.2.1,2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.trustedProxies = proxies
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		tls        bool
+		wantIP     string
+		wantScheme string
+	}{
+		{
+			name:       "Direct",
+			remoteAddr: "203.0.113.7:1234",
+			tls:        true,
+			wantIP:     "203.0.113.7",
+			wantScheme: "https",
+		},
+		{
+			name:       "Untrusted peer's X-Forwarded-For",
+			remoteAddr: "203.0.113.7:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Proto": {"http"}},
+			tls:        true,
+			wantIP:     "203.0.113.7",
+			wantScheme: "https",
+		},
+		{
+			name:       "Untrusted peer's Forwarded",
+			remoteAddr: "203.0.113.7:1234",
+			headers:    map[string][]string{"Forwarded": {"for=198.51.100.1;proto=http"}},
+			tls:        true,
+			wantIP:     "203.0.113.7",
+			wantScheme: "https",
+		},
+		{
+			name:       "Untrusted peer's X-Real-IP",
+			remoteAddr: "203.0.113.7:1234",
+			headers:    map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			wantIP:     "203.0.113.7",
+			wantScheme: "http",
+		},
+		{
+			name:       "X-Forwarded-For",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Proto": {"https"}},
+			wantIP:     "198.51.100.1",
+			wantScheme: "https",
+		},
+		{
+			name:       "X-Forwarded-For through several proxies",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, 192.0.2.1", "10.9.9.9"}},
+			wantIP:     "198.51.100.1",
+			wantScheme: "http",
+		},
+		{
+			name:       "Spoofed X-Forwarded-For",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"127.0.0.1, 10.0.0.1, 198.51.100.1"}},
+			wantIP:     "198.51.100.1",
+			wantScheme: "http",
+		},
+		{
+			name:       "Garbled X-Forwarded-For",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, unknown"}},
+			wantIP:     "10.1.2.3",
+			wantScheme: "http",
+		},
+		{
+			name:       "Forwarded",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"Forwarded": {`for=198.51.100.1;proto=https;by=10.1.2.3`}},
+			wantIP:     "198.51.100.1",
+			wantScheme: "https",
+		},
+		{
+			name:       "Forwarded with IPv6 and ports",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"Forwarded": {`for="[2001:db9::2]:4711";proto=https, for="10.0.0.5:80";proto=http`}},
+			wantIP:     "2001:db9::2",
+			wantScheme: "https",
+		},
+		{
+			name:       "Spoofed Forwarded",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"Forwarded": {"for=127.0.0.1;proto=http", "for=198.51.100.1;proto=https"}},
+			wantIP:     "198.51.100.1",
+			wantScheme: "https",
+		},
+		{
+			name:       "Obfuscated Forwarded",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"Forwarded": {"for=_hidden, for=10.0.0.5"}},
+			wantIP:     "10.0.0.5",
+			wantScheme: "http",
+		},
+		{
+			name:       "Forwarded wins over X-Forwarded-For",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"Forwarded": {"for=198.51.100.1"}, "X-Forwarded-For": {"198.51.100.2"}},
+			wantIP:     "198.51.100.1",
+			wantScheme: "http",
+		},
+		{
+			name:       "X-Real-IP",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			wantIP:     "198.51.100.1",
+			wantScheme: "http",
+		},
+		{
+			name:       "Trusted proxy without headers",
+			remoteAddr: "10.1.2.3:1234",
+			wantIP:     "10.1.2.3",
+			wantScheme: "http",
+		},
+		{
+			name:       "IPv6 proxy",
+			remoteAddr: "[2001:db8::1]:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"2001:db9::2"}},
+			wantIP:     "2001:db9::2",
+			wantScheme: "http",
+		},
+		{
+			name:       "Bogus scheme",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Proto": {"gopher"}},
+			tls:        true,
+			wantIP:     "198.51.100.1",
+			wantScheme: "https",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				for _, v := range values {
+					r.Header.Add(name, v)
+				}
+			}
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			ip, scheme := app.resolveClient(r)
+			assert.Equal(t, ip, tt.wantIP)
+			assert.Equal(t, scheme, tt.wantScheme)
+		})
+	}
+}
+
+func TestRealClient(t *testing.T) {
+	app := newTestApplication(t)
+	app.trustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	var logged bytes.Buffer
+	app.infoLog = log.New(&logged, "", 0)
+
+	r := httptest.NewRequest(http.MethodGet, "/snippet/view/1", nil)
+	r.RemoteAddr = "10.1.2.3:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, r)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	// the request is logged as coming from the client, not the proxy
+	assert.StringContains(t, logged.String(), "198.51.100.1 - https HTTP/1.1 GET /snippet/view/1")
+
+	// and it's who is recorded in the audit log
+	r = httptest.NewRequest(http.MethodGet, "/user/login", nil)
+	r.RemoteAddr = "10.1.2.3:1234"
+	r.Header.Set("Forwarded", "for=198.51.100.9")
+
+	app.realClient(app.sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.audit(r, "test.event", "", nil)
+	}))).ServeHTTP(httptest.NewRecorder(), r)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	assert.Equal(t, events[len(events)-1].IP, "198.51.100.9")
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies("")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(proxies), 0)
+
+	proxies, err = parseTrustedProxies("::ffff:192.0.2.1, 10.1.2.3/8")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, proxies[0], netip.MustParsePrefix("192.0.2.1/32"))
+	assert.Equal(t, proxies[1], netip.MustParsePrefix("10.0.0.0/8"))
+
+	_, err = parseTrustedProxies("10.0.0.0/33")
+	assert.Equal(t, err != nil, true)
+
+	_, err = parseTrustedProxies("proxy.internal")
+	assert.Equal(t, err != nil, true)
+}
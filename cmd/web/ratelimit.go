@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/ratelimit"
+)
+
+// rateLimitKeys is the most clients each limiter keeps track of, which
+// bounds the memory used however many addresses requests come from
+const rateLimitKeys = 100000
+
+// rateLimits holds a limiter for each kind of request. A nil limiter lets
+// everything through.
+type rateLimits struct {
+	read   *ratelimit.Limiter
+	write  *ratelimit.Limiter
+	login  *ratelimit.Limiter
+	signup *ratelimit.Limiter
+	// answers to the proof-of-work question, which cost a bot far less
+	// than solving the challenge, so are limited more strictly
+	answer *ratelimit.Limiter
+}
+
+// limiter() returns the limiter for the request, and the name it is known
+// by in the RateLimit-Policy header
+func (l rateLimits) limiter(r *http.Request) (*ratelimit.Limiter, string) {
+	switch {
+	case r.Method == http.MethodPost && (r.URL.Path == "/user/login" || strings.HasPrefix(r.URL.Path, "/user/login/")):
+		return l.login, "login"
+	case r.Method == http.MethodPost && r.URL.Path == "/user/signup":
+		return l.signup, "signup"
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return l.read, "read"
+	default:
+		return l.write, "write"
+	}
+}
+
+// rateLimit() throttles each client, answering 429 Too Many Requests once
+// it has used up its limit. Logged in users are limited by account, so
+// people sharing an address don't use up each other's requests, and
+// everybody else by IP address. It comes after authenticate in the chain
+// so that it knows who is logged in, except where authenticating is costly
+// enough that it should be limited too.
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter, name := app.rateLimits.limiter(r)
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result := limiter.Allow(app.rateLimitKey(r))
+
+		limit := limiter.Limit()
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;name=%q", limit.Requests, ceilSeconds(limit.Per), name))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			if isAPIRequest(r) {
+				app.clientErrorJSON(w, http.StatusTooManyRequests)
+			} else {
+				app.clientError(w, http.StatusTooManyRequests)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey() is who the request is counted against: the account when
+// logged in, and the IP address otherwise
+func (app *application) rateLimitKey(r *http.Request) string {
+	if app.isAuthenticated(r) {
+		return fmt.Sprintf("user:%d", app.userID(r))
+	}
+	return "ip:" + app.clientIP(r)
+}
+
+// ceilSeconds() rounds d up to whole seconds, so clients told to wait
+// don't come back a moment too soon
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/ratelimit"
+)
+
+func TestRateLimitLogin(t *testing.T) {
+	app := newTestApplication(t)
+	app.rateLimits.login = ratelimit.New(ratelimit.Limit{Requests: 2, Per: time.Minute}, 10)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	for i := range 2 {
+		code, headers, _ := postLogin(t, ts, "test@example.com", "wrong-password")
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.Equal(t, headers.Get("RateLimit-Policy"), `2;w=60;name="login"`)
+		assert.Equal(t, headers.Get("RateLimit-Limit"), "2")
+		assert.Equal(t, headers.Get("RateLimit-Remaining"), []string{"1", "0"}[i])
+	}
+
+	code, headers, _ := postLogin(t, ts, "test@example.com", "password")
+	assert.Equal(t, code, http.StatusTooManyRequests)
+	assert.Equal(t, headers.Get("Retry-After"), "30")
+	assert.Equal(t, headers.Get("RateLimit-Remaining"), "0")
+	assert.Equal(t, headers.Get("RateLimit-Reset"), "60")
+
+	// the login page itself is a read, which isn't limited here
+	code, _, _ = ts.get(t, "/user/login")
+	assert.Equal(t, code, http.StatusOK)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	// logged in users have a bucket of their own, apart from the address
+	// they share with whoever else
+	app.rateLimits.read = ratelimit.New(ratelimit.Limit{Requests: 2, Per: time.Minute}, 10)
+
+	for range 2 {
+		code, _, _ := ts.get(t, "/account")
+		assert.Equal(t, code, http.StatusOK)
+	}
+	code, _, _ := ts.get(t, "/account")
+	assert.Equal(t, code, http.StatusTooManyRequests)
+
+	device(t, ts)
+
+	code, _, _ = ts.get(t, "/")
+	assert.Equal(t, code, http.StatusOK)
+}
+
+func TestRateLimitConcurrent(t *testing.T) {
+	app := newTestApplication(t)
+	app.rateLimits.read = ratelimit.New(ratelimit.Limit{Requests: 20, Per: time.Hour}, 10)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	codes := map[int]int{}
+
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				rs, err := ts.Client().Get(ts.URL + "/")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				rs.Body.Close()
+
+				mu.Lock()
+				codes[rs.StatusCode]++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, codes[http.StatusOK], 20)
+	assert.Equal(t, codes[http.StatusTooManyRequests], 30)
+}
+
+func TestRateLimitSpoofedClient(t *testing.T) {
+	app := newTestApplication(t)
+	app.rateLimits.read = ratelimit.New(ratelimit.Limit{Requests: 1, Per: time.Minute}, 10)
+
+	// a client that isn't one of our proxies can't get a fresh allowance
+	// by claiming to be forwarding for someone else
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "203.0.113.7:1234"
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, r)
+		assert.Equal(t, rr.Code, want)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+const (
+	persistentLoginCookie   = "remember_me"
+	persistentLoginLifetime = 30 * 24 * time.Hour
+)
+
+// issuePersistentLogin() adds a new token to the "remember me" series
+// and hands it to the browser as "selector:validator"
+func (app *application) issuePersistentLogin(w http.ResponseWriter, userID int, series string) error {
+	selector := rand.Text()
+	validator := rand.Text()
+	expires := time.Now().Add(persistentLoginLifetime)
+
+	err := app.persistentLogins.Insert(userID, series, selector, validator, expires)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     persistentLoginCookie,
+		Value:    selector + ":" + validator,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(persistentLoginLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+func clearPersistentLoginCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     persistentLoginCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// resumePersistentLogin() logs the user back in from their "remember me"
+// cookie once their session has gone, rotating the token as it does. It
+// returns the id of the user logged in, or 0 if the cookie is missing or
+// no good.
+func (app *application) resumePersistentLogin(w http.ResponseWriter, r *http.Request) (int, error) {
+	cookie, err := r.Cookie(persistentLoginCookie)
+	if err != nil {
+		return 0, nil
+	}
+
+	selector, validator, ok := strings.Cut(cookie.Value, ":")
+	if !ok {
+		clearPersistentLoginCookie(w)
+		return 0, nil
+	}
+
+	login, err := app.persistentLogins.Get(selector)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			clearPersistentLoginCookie(w)
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	// a token that has already been rotated can only be presented again
+	// by whoever copied it, so the whole series is considered stolen
+	if !login.Matches(validator) || login.Rotated {
+		app.audit(r, "user.remember_me_reuse", fmt.Sprintf("user:%d", login.UserID), nil)
+		clearPersistentLoginCookie(w)
+		return 0, app.revokeSeries(login)
+	}
+
+	if time.Now().After(login.Expires) {
+		clearPersistentLoginCookie(w)
+		return 0, app.persistentLogins.DeleteSeries(login.Series)
+	}
+
+	// Rotate() only succeeds once, so two requests racing to use the
+	// same token can't both win
+	err = app.persistentLogins.Rotate(login.Selector)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.audit(r, "user.remember_me_reuse", fmt.Sprintf("user:%d", login.UserID), nil)
+			clearPersistentLoginCookie(w)
+			return 0, app.revokeSeries(login)
+		}
+		return 0, err
+	}
+
+	err = app.issuePersistentLogin(w, login.UserID, login.Series)
+	if err != nil {
+		return 0, err
+	}
+
+	err = app.startSession(r, login.UserID, login.Series)
+	if err != nil {
+		if errors.Is(err, models.ErrAccountDisabled) {
+			clearPersistentLoginCookie(w)
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	app.audit(r, "user.login", fmt.Sprintf("user:%d", login.UserID), map[string]any{"method": "remember_me"})
+
+	return login.UserID, nil
+}
+
+// revokeSeries() ends a "remember me" series that has been stolen, and
+// logs out every session it has been used to start
+func (app *application) revokeSeries(login *models.PersistentLogin) error {
+	app.infoLog.Printf("revoking remember me tokens of user %d after token reuse", login.UserID)
+
+	sessions, err := app.userSessions.GetByUser(login.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if s.Series == login.Series {
+			err = app.revokeSession(s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return app.persistentLogins.DeleteSeries(login.Series)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+// rememberMeCookie() picks the "remember me" cookie out of a response
+func rememberMeCookie(t *testing.T, headers http.Header) *http.Cookie {
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		if cookie.Name == persistentLoginCookie {
+			return cookie
+		}
+	}
+	t.Fatal("no remember me cookie set")
+	return nil
+}
+
+// withoutSession() switches the client to a browser whose session has
+// expired, holding nothing but the "remember me" cookie
+func withoutSession(t *testing.T, ts *testServer, cookie *http.Cookie) {
+	jar := device(t, ts)
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(u, []*http.Cookie{{Name: cookie.Name, Value: cookie.Value}})
+}
+
+func TestRememberMe(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	logins := app.persistentLogins.(*mocks.PersistentLoginModel)
+
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", "test@example.com")
+	form.Add("password", "password")
+	form.Add("remember_me", "true")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	solveChallenge(t, body, form)
+
+	code, headers, _ := ts.postForm(t, "/user/login", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	first := rememberMeCookie(t, headers)
+	assert.Equal(t, len(logins.Logins), 1)
+
+	var second *http.Cookie
+
+	t.Run("Resume expired session", func(t *testing.T) {
+		withoutSession(t, ts, first)
+
+		code, headers, _ := ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusOK)
+
+		// the token is rotated on use
+		second = rememberMeCookie(t, headers)
+		assert.Equal(t, second.Value != first.Value, true)
+		assert.Equal(t, len(logins.Logins), 2)
+	})
+
+	t.Run("Reused token revokes the series", func(t *testing.T) {
+		resumed := ts.Client().Jar
+
+		withoutSession(t, ts, first)
+
+		code, _, _ := ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, len(logins.Logins), 0)
+
+		// the session the stolen token was used for is logged out too,
+		// and the newest token no longer works
+		ts.Client().Jar = resumed
+		code, _, _ = ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		withoutSession(t, ts, second)
+		code, _, _ = ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+
+	t.Run("Forged validator", func(t *testing.T) {
+		ts.login(t)
+
+		_, _, body := ts.get(t, "/user/login")
+		form.Set("csrf_token", extractCSRFToken(t, body))
+		solveChallenge(t, body, form)
+		_, headers, _ := ts.postForm(t, "/user/login", form)
+		cookie := rememberMeCookie(t, headers)
+
+		selector, _, _ := strings.Cut(cookie.Value, ":")
+		withoutSession(t, ts, &http.Cookie{Name: cookie.Name, Value: selector + ":AAAAAAAAAAAAAAAAAAAAAAAAAA"})
+
+		code, _, _ := ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, len(logins.Logins), 0)
+	})
+
+	t.Run("Logout ends the series", func(t *testing.T) {
+		device(t, ts)
+
+		_, _, body := ts.get(t, "/user/login")
+		form.Set("csrf_token", extractCSRFToken(t, body))
+		solveChallenge(t, body, form)
+		_, headers, _ := ts.postForm(t, "/user/login", form)
+		cookie := rememberMeCookie(t, headers)
+		assert.Equal(t, len(logins.Logins), 1)
+
+		_, _, body = ts.get(t, "/account/sessions")
+		code, _, _ := ts.postForm(t, "/user/logout", url.Values{"csrf_token": {extractCSRFToken(t, body)}})
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, len(logins.Logins), 0)
+
+		withoutSession(t, ts, cookie)
+		code, _, _ = ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+
+	t.Run("Without remember me", func(t *testing.T) {
+		device(t, ts)
+		ts.login(t)
+		assert.Equal(t, len(logins.Logins), 0)
+	})
+}
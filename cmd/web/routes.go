@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
 	"github.com/PPRAMANIK62/snippetbox/ui"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
@@ -12,17 +13,59 @@ func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
 	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRequest(r) {
+			app.notFoundJSON(w)
+			return
+		}
 		app.notFound(w)
 	})
 
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRequest(r) {
+			app.clientErrorJSON(w, http.StatusMethodNotAllowed)
+			return
+		}
+		app.clientError(w, http.StatusMethodNotAllowed)
+	})
+
 	// route for static files (using embedded file system)
 	fileServer := http.FileServer(http.FS(ui.Files))
 	router.Handler(http.MethodGet, "/static/*filepath", fileServer)
 
+	// the API's OpenAPI document, for generating clients and docs
+	router.Handler(http.MethodGet, "/api/openapi.json", fileServer)
+
 	router.HandlerFunc(http.MethodGet, "/ping", ping)
 
+	// feeds are fetched by feed readers, which have no session.
+	// httprouter won't have /user/:id next to /user/login and the rest, so
+	// a user's feed is at /user/feed/:id.atom, and the handler takes the
+	// extension off.
+	feeds := alice.New(app.rateLimit)
+
+	router.Handler(http.MethodGet, "/feed.atom", feeds.ThenFunc(app.siteFeed))
+	router.Handler(http.MethodGet, "/feed.rss", feeds.ThenFunc(app.siteFeed))
+	router.Handler(http.MethodGet, "/user/feed/:file", feeds.ThenFunc(app.userFeed))
+	router.Handler(http.MethodGet, "/language/:language/feed.atom", feeds.ThenFunc(app.languageFeed))
+	router.Handler(http.MethodGet, "/language/:language/feed.rss", feeds.ThenFunc(app.languageFeed))
+	router.Handler(http.MethodGet, "/tag/:tag/feed.atom", feeds.ThenFunc(app.tagFeed))
+	router.Handler(http.MethodGet, "/tag/:tag/feed.rss", feeds.ThenFunc(app.tagFeed))
+
+	// embedded snippets are shown in iframes on other sites, which have no
+	// session either, and may be framed by the sites the operator allows.
+	// oEmbed consumers are servers asking how to embed, so need neither.
+	embed := alice.New(app.allowFraming, app.rateLimit)
+
+	router.Handler(http.MethodGet, "/snippet/embed/:id", embed.ThenFunc(app.snippetEmbed))
+	router.Handler(http.MethodGet, "/oembed", feeds.ThenFunc(app.oEmbed))
+
+	// images of snippets are shown by chat apps and slides, without a
+	// session either. httprouter can't match "/snippet/image/:id.png", so
+	// the handler takes the .png off.
+	router.Handler(http.MethodGet, "/snippet/image/:file", feeds.ThenFunc(app.snippetImage))
+
 	// middleware chain specific to our dynamic application routes (unprotected)
-	dynamic := alice.New(app.sessionManager.LoadAndSave, app.noSurf, app.authenticate)
+	dynamic := alice.New(app.sessionManager.LoadAndSave, app.noSurf, app.authenticate, app.rateLimit)
 
 	// routes using appropriate methods, patterns and handlers
 	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
@@ -31,6 +74,12 @@ func (app *application) routes() http.Handler {
 	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.userSignupPost))
 	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
 	router.Handler(http.MethodPost, "/user/login", dynamic.ThenFunc(app.userLoginPost))
+	router.Handler(http.MethodPost, "/user/login/passkey/begin", dynamic.ThenFunc(app.passkeyLoginBegin))
+	router.Handler(http.MethodPost, "/user/login/passkey/finish", dynamic.ThenFunc(app.passkeyLoginFinish))
+	router.Handler(http.MethodGet, "/user/login/oidc", dynamic.ThenFunc(app.oidcLogin))
+	router.Handler(http.MethodGet, "/user/login/oidc/callback", dynamic.ThenFunc(app.oidcCallback))
+	router.Handler(http.MethodGet, "/user/unlock", dynamic.ThenFunc(app.userUnlock))
+	router.Handler(http.MethodPost, "/snippet/report", dynamic.ThenFunc(app.snippetReportPost))
 
 	// protected routes, using the new "protected" middleware chain
 	protected := dynamic.Append(app.requireAuthentication)
@@ -38,9 +87,85 @@ func (app *application) routes() http.Handler {
 	router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
 	router.Handler(http.MethodPost, "/snippet/create", protected.ThenFunc(app.snippetCreatePost))
 	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
+	router.Handler(http.MethodGet, "/account", protected.ThenFunc(app.account))
+	router.Handler(http.MethodGet, "/account/export", protected.ThenFunc(app.accountExport))
+	router.Handler(http.MethodGet, "/account/delete", protected.ThenFunc(app.accountDelete))
+	router.Handler(http.MethodPost, "/account/delete", protected.ThenFunc(app.accountDeletePost))
+	router.Handler(http.MethodGet, "/account/password", protected.ThenFunc(app.accountPassword))
+	router.Handler(http.MethodPost, "/account/password", protected.ThenFunc(app.accountPasswordPost))
+	router.Handler(http.MethodGet, "/account/reauthenticate", protected.ThenFunc(app.accountReauthenticate))
+	router.Handler(http.MethodGet, "/account/passkeys", protected.ThenFunc(app.accountPasskeys))
+	router.Handler(http.MethodPost, "/account/passkeys/register/begin", protected.ThenFunc(app.passkeyRegisterBegin))
+	router.Handler(http.MethodPost, "/account/passkeys/register/finish", protected.ThenFunc(app.passkeyRegisterFinish))
+	router.Handler(http.MethodPost, "/account/passkeys/revoke", protected.ThenFunc(app.passkeyRevokePost))
+	router.Handler(http.MethodGet, "/account/sessions", protected.ThenFunc(app.accountSessions))
+	router.Handler(http.MethodPost, "/account/sessions/revoke", protected.ThenFunc(app.sessionRevokePost))
+	router.Handler(http.MethodPost, "/account/sessions/revoke-all", protected.ThenFunc(app.sessionRevokeAllPost))
+	router.Handler(http.MethodGet, "/account/tokens", protected.ThenFunc(app.accountTokens))
+	router.Handler(http.MethodPost, "/account/tokens", protected.ThenFunc(app.accountTokensPost))
+	router.Handler(http.MethodPost, "/account/tokens/revoke", protected.ThenFunc(app.tokenRevokePost))
+	router.Handler(http.MethodPost, "/account/devkey", protected.ThenFunc(app.devKeyPost))
+	router.Handler(http.MethodPost, "/account/devkey/revoke", protected.ThenFunc(app.devKeyRevokePost))
+	router.Handler(http.MethodGet, "/account/webhooks", protected.ThenFunc(app.webhooksPage))
+	router.Handler(http.MethodPost, "/account/webhooks", protected.ThenFunc(app.webhooksPost))
+	router.Handler(http.MethodGet, "/account/webhooks/view/:id", protected.ThenFunc(app.webhookView))
+	router.Handler(http.MethodPost, "/account/webhooks/delete", protected.ThenFunc(app.webhookDeletePost))
+	router.Handler(http.MethodPost, "/account/webhooks/redeliver", protected.ThenFunc(app.webhookRedeliverPost))
+
+	// moderation routes, for moderators and admins
+	moderator := protected.Append(app.requireRole(models.RoleModerator))
+
+	router.Handler(http.MethodGet, "/moderation", moderator.ThenFunc(app.moderation))
+	router.Handler(http.MethodPost, "/moderation/hide", moderator.ThenFunc(app.moderationHidePost))
+	router.Handler(http.MethodPost, "/moderation/delete", moderator.ThenFunc(app.moderationDeletePost))
+	router.Handler(http.MethodPost, "/moderation/dismiss", moderator.ThenFunc(app.moderationDismissPost))
+
+	// admin routes, only for users with the admin role
+	admin := protected.Append(app.requireRole(models.RoleAdmin))
+
+	router.Handler(http.MethodGet, "/admin", admin.ThenFunc(app.adminDashboard))
+	router.Handler(http.MethodGet, "/admin/users", admin.ThenFunc(app.adminUsers))
+	router.Handler(http.MethodPost, "/admin/users/role", admin.ThenFunc(app.adminUserRolePost))
+	router.Handler(http.MethodPost, "/admin/users/disable", admin.ThenFunc(app.adminUserDisablePost))
+	router.Handler(http.MethodPost, "/admin/users/reset-password", admin.ThenFunc(app.adminUserResetPasswordPost))
+	router.Handler(http.MethodPost, "/admin/users/unlock", admin.ThenFunc(app.adminUserUnlockPost))
+	router.Handler(http.MethodGet, "/admin/snippets", admin.ThenFunc(app.adminSnippets))
+	router.Handler(http.MethodPost, "/admin/snippets/delete", admin.ThenFunc(app.adminSnippetsDeletePost))
+	router.Handler(http.MethodGet, "/admin/audit", admin.ThenFunc(app.adminAudit))
+	router.Handler(http.MethodGet, "/admin/audit/export", admin.ThenFunc(app.adminAuditExport))
+	router.Handler(http.MethodGet, "/admin/webhooks", admin.ThenFunc(app.webhooksPage))
+	router.Handler(http.MethodPost, "/admin/webhooks", admin.ThenFunc(app.webhooksPost))
+	router.Handler(http.MethodGet, "/admin/webhooks/view/:id", admin.ThenFunc(app.webhookView))
+	router.Handler(http.MethodPost, "/admin/webhooks/delete", admin.ThenFunc(app.webhookDeletePost))
+	router.Handler(http.MethodPost, "/admin/webhooks/redeliver", admin.ThenFunc(app.webhookRedeliverPost))
+
+	// the JSON API authenticates with tokens rather than session cookies,
+	// so it needs neither sessions nor CSRF protection
+	api := alice.New(app.authenticateToken, app.rateLimit)
+
+	// reading needs no token, but a token used to read must allow it
+	read := api.Append(app.requireScope(models.ScopeRead))
+	write := api.Append(app.requireToken, app.requireScope(models.ScopeWrite))
+
+	router.Handler(http.MethodGet, "/api/v1/snippets", read.ThenFunc(app.apiSnippetList))
+	router.Handler(http.MethodPost, "/api/v1/snippets", write.ThenFunc(app.apiSnippetCreate))
+	router.Handler(http.MethodGet, "/api/v1/snippets/:id", read.ThenFunc(app.apiSnippetGet))
+	router.Handler(http.MethodDelete, "/api/v1/snippets/:id", write.ThenFunc(app.apiSnippetDelete))
+
+	// pasting with curl, which takes the same tokens and so needs no CSRF
+	// protection either
+	router.Handler(http.MethodPost, "/", write.ThenFunc(app.paste))
+
+	// Pastebin's API, for tools that speak it, which authenticates with a
+	// developer key sent in the form. Reading the form and checking the key
+	// is work enough to be worth flooding, so clients are limited by IP
+	// address before it is done.
+	pastebin := alice.New(app.rateLimit, app.authenticateDevKey)
+
+	router.Handler(http.MethodPost, "/api/api_post.php", pastebin.ThenFunc(app.pastebinPost))
 
 	// middleware chain
-	standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
+	standard := alice.New(app.recoverPanic, app.realClient, app.logRequest, secureHeaders)
 
 	// wrap the router with the middleware and return
 	return standard.Then(router)
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+// startSession() logs the user in on the current session, recording the
+// device so that it shows up on the sessions page. series is the
+// "remember me" series the session belongs to, if any.
+func (app *application) startSession(r *http.Request, userID int, series string) error {
+	user, err := app.users.Get(userID)
+	if err != nil {
+		return err
+	}
+	if user.Disabled {
+		return models.ErrAccountDisabled
+	}
+
+	// change the session id (when the auth state changes or privilege
+	// level changes for the user) to prevent session fixation
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", userID)
+
+	token := app.sessionManager.Token(r.Context())
+
+	return app.userSessions.Insert(userID, token, series, app.clientIP(r), r.UserAgent())
+}
+
+// revokeSession() forgets the session and destroys its data in the
+// session store, logging out whoever holds it. The device's "remember me"
+// series ends too, so that it can't simply log back in.
+func (app *application) revokeSession(s *models.UserSession) error {
+	err := app.userSessions.Delete(s.Token)
+	if err != nil {
+		return err
+	}
+
+	if s.Series != "" {
+		err = app.persistentLogins.DeleteSeries(s.Series)
+		if err != nil {
+			return err
+		}
+	}
+
+	return app.sessionManager.Store.Delete(s.Token)
+}
+
+func (app *application) accountSessions(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	sessions, err := app.userSessions.GetByUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Sessions = sessions
+	data.CurrentSessionToken = app.sessionManager.Token(r.Context())
+
+	app.render(w, http.StatusOK, "sessions.html", data)
+}
+
+type sessionRevokeForm struct {
+	ID                  int `form:"id"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) sessionRevokePost(w http.ResponseWriter, r *http.Request) {
+	var form sessionRevokeForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	sessions, err := app.userSessions.GetByUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// only look among the user's own sessions, so nobody can revoke
+	// someone else's by guessing ids
+	for _, s := range sessions {
+		if s.ID != form.ID {
+			continue
+		}
+
+		err = app.revokeSession(s)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		app.audit(r, "session.revoke", fmt.Sprintf("session:%d", s.ID), nil)
+
+		if s.Token == app.sessionManager.Token(r.Context()) {
+			app.logOut(w, r, "You've been logged out")
+			return
+		}
+
+		app.sessionManager.Put(r.Context(), "flash", "Session revoked successfully!")
+		http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+		return
+	}
+
+	app.notFound(w)
+}
+
+func (app *application) sessionRevokeAllPost(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	err := app.revokeAllSessions(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "session.revoke_all", fmt.Sprintf("user:%d", id), nil)
+
+	app.logOut(w, r, "You've been logged out on all your devices")
+}
+
+// revokeAllSessions() logs the user out everywhere, "remember me"
+// cookies included
+func (app *application) revokeAllSessions(userID int) error {
+	sessions, err := app.userSessions.GetByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		err = app.revokeSession(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return app.persistentLogins.DeleteByUser(userID)
+}
+
+// logOut() starts a fresh, logged out session for the current request
+// and sends the user to the login page
+func (app *application) logOut(w http.ResponseWriter, r *http.Request, flash string) {
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	clearPersistentLoginCookie(w)
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+// device gives the test server client a cookie jar of its own, so that
+// a test can act as several logged in browsers
+func device(t *testing.T, ts *testServer) *cookiejar.Jar {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+	return jar
+}
+
+func TestAccountSessions(t *testing.T) {
+	app := newTestApplication(t)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	sessions := app.userSessions.(*mocks.UserSessionModel)
+
+	laptop := device(t, ts)
+	ts.login(t)
+	phone := device(t, ts)
+	ts.login(t)
+
+	assert.Equal(t, len(sessions.Sessions), 2)
+
+	t.Run("List", func(t *testing.T) {
+		ts.Client().Jar = laptop
+
+		code, _, body := ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "(this device)")
+		assert.StringContains(t, body, "Go-http-client")
+	})
+
+	t.Run("Revoke another device", func(t *testing.T) {
+		ts.Client().Jar = laptop
+
+		_, _, body := ts.get(t, "/account/sessions")
+
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		form.Add("id", strconv.Itoa(sessions.Sessions[1].ID))
+		code, _, _ := ts.postForm(t, "/account/sessions/revoke", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, len(sessions.Sessions), 1)
+
+		// the laptop is still logged in, the phone isn't
+		code, _, _ = ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusOK)
+
+		ts.Client().Jar = phone
+		code, headers, _ := ts.get(t, "/account/sessions")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	t.Run("Revoke unknown session", func(t *testing.T) {
+		ts.Client().Jar = laptop
+
+		_, _, body := ts.get(t, "/account/sessions")
+
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		form.Add("id", "999")
+		code, _, _ := ts.postForm(t, "/account/sessions/revoke", form)
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	t.Run("Log out everywhere", func(t *testing.T) {
+		ts.Client().Jar = phone
+		ts.login(t)
+		assert.Equal(t, len(sessions.Sessions), 2)
+
+		_, _, body := ts.get(t, "/account/sessions")
+
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		code, _, _ := ts.postForm(t, "/account/sessions/revoke-all", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, len(sessions.Sessions), 0)
+
+		for _, jar := range []*cookiejar.Jar{laptop, phone} {
+			ts.Client().Jar = jar
+			code, _, _ := ts.get(t, "/account/sessions")
+			assert.Equal(t, code, http.StatusSeeOther)
+		}
+	})
+}
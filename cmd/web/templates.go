@@ -3,10 +3,11 @@ package main
 import (
 	"html/template"
 	"io/fs"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/pow"
 	"github.com/PPRAMANIK62/snippetbox/ui"
 )
 
@@ -14,10 +15,54 @@ type templateData struct {
 	CurrentYear     int
 	Snippet         *models.Snippet
 	Snippets        []*models.Snippet
+	Passkeys        []*models.Passkey
+	Sessions        []*models.UserSession
+	Users           []*models.User
+	Stats           *models.Stats
+	AuditEvents     []*models.AuditEvent
+	Reports         []*models.Report
+	APITokens       []*models.APIToken
+	DevKey          *models.DevKey
+	Webhook         *models.Webhook
+	Webhooks        []*models.Webhook
+	Languages       []string
+	Tags            []string
+	AuditExportURL  string
 	Form            any
 	Flash           string
 	IsAuthenticated bool
+	Role            models.Role
 	CSRFToken       string
+	OIDCEnabled     bool
+	Reauthenticated bool
+	Challenge       pow.Challenge
+
+	// linked from the page's head for feed readers and oEmbed consumers to
+	// find, along with the site's feeds
+	AlternateLinks []alternateLink
+
+	// the feed of the user the page is about, without its extension
+	FeedURL string
+
+	// lets the sessions page mark the one in use
+	CurrentSessionToken string
+
+	// a token or developer key just created, shown this once
+	NewAPIToken string
+	NewDevKey   string
+
+	// the webhook pages are shared by users and admins, at different paths
+	WebhookBase       string
+	WebhookEvents     []string
+	WebhookDeliveries []*models.WebhookDelivery
+}
+
+// alternateLink is a <link rel="alternate"> in a page's head, to a feed
+// about the page or one of its oEmbed endpoints
+type alternateLink struct {
+	Title string
+	Type  string
+	URL   string
 }
 
 func humanDate(t time.Time) string {
@@ -39,8 +84,16 @@ func newTemplateCache() (map[string]*template.Template, error) {
 		return nil, err
 	}
 
+	// the admin console pages are cached as "admin/<name>.html"
+	adminPages, err := fs.Glob(ui.Files, "html/admin/*.html")
+	if err != nil {
+		return nil, err
+	}
+	pages = append(pages, adminPages...)
+
 	for _, page := range pages {
-		name := filepath.Base(page)
+		name := strings.TrimPrefix(page, "html/pages/")
+		name = strings.TrimPrefix(name, "html/")
 
 		patterns := []string{
 			"html/base.html",
@@ -56,5 +109,12 @@ func newTemplateCache() (map[string]*template.Template, error) {
 		cache[name] = ts
 	}
 
+	// an embedded snippet is a page of its own, without the site around it
+	ts, err := template.New("embed.html").Funcs(functions).ParseFS(ui.Files, "html/embed.html")
+	if err != nil {
+		return nil, err
+	}
+	cache["embed.html"] = ts
+
 	return cache, nil
 }
@@ -10,12 +10,23 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/PPRAMANIK62/snippetbox/internal/mailer"
 	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+	"github.com/PPRAMANIK62/snippetbox/internal/pow"
+	"github.com/PPRAMANIK62/snippetbox/internal/secrets"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// the relying party the software authenticator in the tests talks to
+const (
+	testRPID     = "localhost"
+	testRPOrigin = "https://localhost:4000"
 )
 
 // returns an instance of our application struct
@@ -34,14 +45,48 @@ func newTestApplication(t *testing.T) *application {
 	sessionManager.Lifetime = 12 * time.Hour
 	sessionManager.Cookie.Secure = true
 
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "Snippetbox",
+		RPOrigins:     []string{testRPOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &mocks.UserModel{}
+
 	return &application{
 		errorLog: log.New(io.Discard, "", 0),
 		infoLog: log.New(io.Discard, "", 0),
 		snippets: &mocks.SnippetModel{},
-		users: &mocks.UserModel{},
+		users: users,
+		accounts: &mocks.AccountModel{},
+		stats: &mocks.StatsModel{},
+		auditLog: &mocks.AuditModel{},
+		reports: &mocks.ReportModel{},
+		authenticator: users,
+		passkeys: &mocks.PasskeyModel{},
+		identities: &mocks.IdentityModel{},
+		loginAttempts: &mocks.LoginAttemptModel{},
+		userSessions: &mocks.UserSessionModel{},
+		persistentLogins: &mocks.PersistentLoginModel{},
+		apiTokens: &mocks.APITokenModel{},
+		devKeys: &mocks.DevKeyModel{},
+		webhooks: &mocks.WebhookModel{},
+		// the receivers in the tests are on loopback addresses
+		webhookSender: newWebhookSender(5*time.Second, time.Minute, 3, true),
+		mailer: &mailer.Log{Logger: log.New(io.Discard, "", 0)},
+		secretScanner: secrets.New(secrets.DefaultRules()),
+		// easy challenges, so the tests don't spend long solving them
+		challenges: &pow.Issuer{Key: []byte("test-challenge-key"), Difficulty: 4, MaxDifficulty: 4},
+		baseURL: "https://localhost:4000",
+		embedOrigins: []string{"https://wiki.example.com"},
+		images: newImageCache(16),
 		templateCache: templateCache,
 		formDecoder: formDecoder,
 		sessionManager: sessionManager,
+		webAuthn: webAuthn,
 	}
 }
 
@@ -108,8 +153,38 @@ func extractCSRFToken(t *testing.T, body string) string {
 	return html.UnescapeString(string(matches[1]))
 }
 
+var challengeTokenRegex = regexp.MustCompile(`<input type="hidden" name="pow_token" value="([^"]+)"`)
+
+// solveChallenge() solves the proof-of-work challenge on the page, as
+// pow.js does in the browser, and adds the solution to the form
+func solveChallenge(t *testing.T, body string, form url.Values) {
+	matches := challengeTokenRegex.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("no challenge found in body")
+	}
+	token := html.UnescapeString(matches[1])
+
+	solution, err := pow.Solve(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form.Set("pow_token", token)
+	form.Set("pow_solution", solution)
+}
+
 func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (int, http.Header, string) {
-	rs, err := ts.Client().PostForm(ts.URL + urlPath, form)
+	req, err := http.NewRequest(http.MethodPost, ts.URL + urlPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// nosurf rejects cross-origin POSTs, so send the Origin header
+	// a browser would send for a same-origin form submission
+	req.Header.Set("Origin", ts.URL)
+
+	rs, err := ts.Client().Do(req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -124,3 +199,51 @@ func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (i
 
 	return rs.StatusCode, rs.Header, string(body)
 }
+
+// postJSON() sends a JSON body the way the fetch() calls in our
+// JavaScript do, with the CSRF token in a header
+func (ts *testServer) postJSON(t *testing.T, urlPath, csrfToken string, body []byte) (int, http.Header, string) {
+	req, err := http.NewRequest(http.MethodPost, ts.URL + urlPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", ts.URL)
+	req.Header.Set("X-CSRF-Token", csrfToken)
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+
+	respBody, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(respBody)
+}
+
+// login() logs the test server client in as the mock user with id 1
+func (ts *testServer) login(t *testing.T) {
+	ts.loginAs(t, "test@example.com")
+}
+
+// loginAs() logs the test server client in as the mock user with the
+// given email
+func (ts *testServer) loginAs(t *testing.T, email string) {
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	solveChallenge(t, body, form)
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login failed with status %d", code)
+	}
+}
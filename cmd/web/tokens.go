@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+)
+
+type tokenCreateForm struct {
+	Name                string         `form:"name"`
+	Scopes              []models.Scope `form:"scopes"`
+	Expires             int            `form:"expires"`
+	validator.Validator `form:"-"`
+}
+
+// HasScope() reports whether the scope's box is ticked
+func (f tokenCreateForm) HasScope(scope models.Scope) bool {
+	return slices.Contains(f.Scopes, scope)
+}
+
+type tokenRevokeForm struct {
+	ID                  int `form:"id"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountTokens(w http.ResponseWriter, r *http.Request) {
+	app.renderTokens(w, r, http.StatusOK, newTokenCreateForm(), "", "")
+}
+
+// newTokenCreateForm() returns the form as first shown, for a read-only
+// token that lasts 30 days
+func newTokenCreateForm() tokenCreateForm {
+	return tokenCreateForm{
+		Scopes:  []models.Scope{models.ScopeRead},
+		Expires: 30,
+	}
+}
+
+// renderTokens() shows the user's API tokens and developer key, along
+// with newToken or newDevKey if one was just created. That is the only
+// time the token or key itself is shown.
+func (app *application) renderTokens(w http.ResponseWriter, r *http.Request, status int, form tokenCreateForm, newToken, newDevKey string) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	tokens, err := app.apiTokens.List(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	devKey, err := app.devKeys.Get(id)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.APITokens = tokens
+	data.DevKey = devKey
+	data.NewAPIToken = newToken
+	data.NewDevKey = newDevKey
+	data.Form = form
+
+	if newToken != "" || newDevKey != "" {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+
+	app.render(w, status, "tokens.html", data)
+}
+
+func (app *application) accountTokensPost(w http.ResponseWriter, r *http.Request) {
+	var form tokenCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(form.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(form.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(len(form.Scopes) > 0, "scopes", "Choose at least one scope")
+	for _, scope := range form.Scopes {
+		form.CheckField(scope.Valid(), "scopes", "This field must be read, write or admin")
+	}
+	form.CheckField(!form.HasScope(models.ScopeAdmin) || app.role(r).Includes(models.RoleModerator), "scopes", "Only moderators and admins can create tokens with the admin scope")
+	form.CheckField(validator.PermittedValue(form.Expires, 0, 7, 30, 90, 365), "expires", "This field must equal 0, 7, 30, 90 or 365")
+
+	if !form.Valid() {
+		app.renderTokens(w, r, http.StatusUnprocessableEntity, form, "", "")
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	tokenID, token, err := app.apiTokens.Insert(id, form.Name, form.Scopes, form.Expires)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "api_token.create", fmt.Sprintf("api_token:%d", tokenID), map[string]any{
+		"name":    form.Name,
+		"scopes":  form.Scopes,
+		"expires": form.Expires,
+	})
+
+	// render rather than redirect, so the token never has to be stored
+	// in the session to survive the redirect
+	app.renderTokens(w, r, http.StatusOK, newTokenCreateForm(), token, "")
+}
+
+func (app *application) tokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	var form tokenRevokeForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	err = app.apiTokens.Delete(id, form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "api_token.revoke", fmt.Sprintf("api_token:%d", form.ID), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "API token revoked successfully!")
+
+	http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+}
+
+// devKeyPost() gives the user a new developer key for the Pastebin
+// compatible API, replacing any they had
+func (app *application) devKeyPost(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	key, err := app.devKeys.Generate(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "dev_key.create", fmt.Sprintf("user:%d", id), nil)
+
+	app.renderTokens(w, r, http.StatusOK, newTokenCreateForm(), "", key)
+}
+
+func (app *application) devKeyRevokePost(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	err := app.devKeys.Delete(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "dev_key.revoke", fmt.Sprintf("user:%d", id), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "Developer key revoked successfully!")
+
+	http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxWebhooks is how many webhooks a user, or the site, may have
+const maxWebhooks = 10
+
+// maxWebhookResponse is how much of a receiver's response is kept in the
+// delivery log
+const maxWebhookResponse = 1024
+
+// webhookSender holds what the webhook worker needs to send deliveries
+type webhookSender struct {
+	// global webhooks are set up by admins, and may reach any address;
+	// users' webhooks may only reach public ones
+	client     *http.Client
+	userClient *http.Client
+
+	// a failed delivery is tried again after backoff, then twice as long
+	// after each further failure, until it has had maxAttempts
+	backoff     time.Duration
+	maxAttempts int
+
+	// wakes the worker when there are new deliveries to send
+	wake chan struct{}
+}
+
+// newWebhookSender() returns a sender whose users' webhooks are refused
+// private, loopback and link-local addresses unless allowPrivate is set
+func newWebhookSender(timeout, backoff time.Duration, maxAttempts int, allowPrivate bool) *webhookSender {
+	// receivers answer the request themselves, so redirects are returned
+	// as they are, and can't be used to reach another address
+	noRedirects := func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		// the address is checked as it is dialled, after the name has
+		// been resolved, and no proxy can be asked to go there instead
+		dialer := &net.Dialer{Timeout: timeout, Control: refusePrivateAddress}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+
+	return &webhookSender{
+		client:      &http.Client{Timeout: timeout, CheckRedirect: noRedirects},
+		userClient:  &http.Client{Timeout: timeout, CheckRedirect: noRedirects, Transport: transport},
+		backoff:     backoff,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// wakeUp() tells the worker there are new deliveries, unless it has
+// already been told
+func (s *webhookSender) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+var errPrivateAddress = errors.New("webhooks may not be sent to private addresses")
+
+func refusePrivateAddress(network, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() || addr.IsMulticast() {
+		return errPrivateAddress
+	}
+
+	return nil
+}
+
+// webhookSignature() signs a delivery's timestamp and payload with the
+// webhook's secret, the way receivers are told to check it
+func webhookSignature(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookPayload struct {
+	Event   string     `json:"event"`
+	Created time.Time  `json:"created"`
+	Snippet apiSnippet `json:"snippet"`
+}
+
+// webhookEvent() queues a delivery of the event to each webhook that wants
+// it. Failing to queue them is logged rather than failing the request,
+// which has already done what it was asked to.
+func (app *application) webhookEvent(event string, s *models.Snippet) {
+	webhooks, err := app.webhooks.Subscribed(event, s.UserID, s.Visibility == models.VisibilityPublic)
+	if err != nil {
+		app.errorLog.Print(err)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		return
+	}
+
+	// a deleted snippet's tags have gone with it
+	tags, err := app.snippets.Tags(s.ID)
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+
+	snippet := app.apiSnippet(s, tags)
+	// moderators hid it for a reason, so its content isn't passed on
+	if s.Hidden {
+		snippet.Content = ""
+	}
+
+	payload, err := json.Marshal(webhookPayload{Event: event, Created: time.Now().UTC(), Snippet: snippet})
+	if err != nil {
+		app.errorLog.Print(err)
+		return
+	}
+
+	for _, h := range webhooks {
+		_, err := app.webhooks.Enqueue(h.ID, event, payload)
+		if err != nil {
+			app.errorLog.Print(err)
+		}
+	}
+
+	app.webhookSender.wakeUp()
+}
+
+// snippetCreated() sends the snippet.created event for a snippet just
+// inserted
+func (app *application) snippetCreated(id int) {
+	s, err := app.snippets.Get(id)
+	if err != nil {
+		app.errorLog.Print(err)
+		return
+	}
+
+	app.webhookEvent(models.EventSnippetCreated, s)
+}
+
+// getSnippets() fetches those of the snippets that exist, so the webhooks
+// can be told about them once they are deleted
+func (app *application) getSnippets(ids []int) ([]*models.Snippet, error) {
+	snippets := []*models.Snippet{}
+	for _, id := range ids {
+		s, err := app.snippets.Get(id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				continue
+			}
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	return snippets, nil
+}
+
+// snippetsDeleted() sends the snippet.deleted event for each snippet
+func (app *application) snippetsDeleted(snippets []*models.Snippet) {
+	for _, s := range snippets {
+		app.webhookEvent(models.EventSnippetDeleted, s)
+	}
+}
+
+// deliverWebhooks() sends the deliveries that are due, whenever there are
+// new ones and every interval for the retries, forever
+func (app *application) deliverWebhooks(interval time.Duration) {
+	for {
+		app.sendDueWebhooks()
+
+		select {
+		case <-app.webhookSender.wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// sendDueWebhooks() sends every delivery that is due, a batch at a time
+func (app *application) sendDueWebhooks() {
+	for {
+		deliveries, err := app.webhooks.Due(time.Now(), 100)
+		if err != nil {
+			app.errorLog.Print(err)
+			return
+		}
+
+		if len(deliveries) == 0 {
+			return
+		}
+
+		for _, d := range deliveries {
+			// the delivery would only come straight back if it couldn't
+			// be recorded, so that waits for the next time round
+			err := app.sendWebhook(d)
+			if err != nil {
+				app.errorLog.Print(err)
+				return
+			}
+		}
+	}
+}
+
+// sendWebhook() makes one attempt at a delivery and records how it went.
+// Anything but a 2xx response is a failure, to be tried again later.
+func (app *application) sendWebhook(d *models.WebhookDelivery) error {
+	client := app.webhookSender.userClient
+	if d.Global {
+		client = app.webhookSender.client
+	}
+
+	a := &models.WebhookAttempt{DeliveryID: d.ID, Attempted: time.Now()}
+
+	var err error
+	a.StatusCode, a.Response, err = postWebhook(client, d, a.Attempted)
+	if err != nil {
+		a.Error = err.Error()
+	}
+	a.Duration = time.Since(a.Attempted)
+
+	status, next := models.DeliverySucceeded, time.Time{}
+	if !a.Succeeded() {
+		if d.Attempts+1 >= app.webhookSender.maxAttempts {
+			status = models.DeliveryFailed
+		} else {
+			status = models.DeliveryPending
+			next = a.Attempted.Add(app.webhookSender.backoff << d.Attempts)
+		}
+	}
+
+	return app.webhooks.RecordAttempt(a, status, next)
+}
+
+// postWebhook() posts the delivery's payload, signed with the time it was
+// sent, returning the response's status code and the start of its body
+func postWebhook(client *http.Client, d *models.WebhookDelivery, sent time.Time) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := strconv.FormatInt(sent.Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Snippetbox-Webhooks/1.0")
+	req.Header.Set("X-Snippetbox-Event", d.Event)
+	req.Header.Set("X-Snippetbox-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Snippetbox-Timestamp", timestamp)
+	req.Header.Set("X-Snippetbox-Signature", webhookSignature(d.Secret, timestamp, d.Payload))
+
+	rs, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rs.Body.Close()
+
+	// the status code is what matters, so a body that can't be read is
+	// just left out of the log
+	body, _ := io.ReadAll(io.LimitReader(rs.Body, maxWebhookResponse))
+
+	return rs.StatusCode, strings.ToValidUTF8(string(body), ""), nil
+}
+
+// watchExpiry() sends the snippet.expired event for snippets as they
+// expire, looking every interval, forever. Snippets which expire while the
+// app isn't running aren't announced.
+func (app *application) watchExpiry(interval time.Duration) {
+	from := time.Now()
+	for {
+		time.Sleep(interval)
+
+		to := time.Now()
+		if app.announceExpired(from, to) {
+			from = to
+		}
+	}
+}
+
+// announceExpired() sends the snippet.expired event for the snippets
+// which expired after from and by to, reporting whether it could
+func (app *application) announceExpired(from, to time.Time) bool {
+	snippets, err := app.snippets.Expired(from, to)
+	if err != nil {
+		app.errorLog.Print(err)
+		return false
+	}
+
+	for _, s := range snippets {
+		app.webhookEvent(models.EventSnippetExpired, s)
+	}
+
+	return true
+}
+
+// The webhook pages are the same for a user's own webhooks, under
+// /account/webhooks, and the global ones, under /admin/webhooks.
+
+// webhookOwner() returns the user whose webhooks the page is about, or 0
+// for the global ones, along with where the pages are
+func (app *application) webhookOwner(r *http.Request) (int, string) {
+	if strings.HasPrefix(r.URL.Path, "/admin/") {
+		return 0, "/admin/webhooks"
+	}
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), "/account/webhooks"
+}
+
+type webhookCreateForm struct {
+	URL                 string   `form:"url"`
+	Events              []string `form:"events"`
+	validator.Validator `form:"-"`
+}
+
+// HasEvent() reports whether the event's box is ticked
+func (f webhookCreateForm) HasEvent(event string) bool {
+	return slices.Contains(f.Events, event)
+}
+
+type webhookIDForm struct {
+	ID                  int `form:"id"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) webhooksPage(w http.ResponseWriter, r *http.Request) {
+	app.renderWebhooks(w, r, http.StatusOK, webhookCreateForm{Events: models.WebhookEvents})
+}
+
+func (app *application) renderWebhooks(w http.ResponseWriter, r *http.Request, status int, form webhookCreateForm) {
+	owner, base := app.webhookOwner(r)
+
+	webhooks, err := app.webhooks.List(owner)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Webhooks = webhooks
+	data.WebhookEvents = models.WebhookEvents
+	data.WebhookBase = base
+	data.Form = form
+
+	app.render(w, status, "webhooks.html", data)
+}
+
+func (app *application) webhooksPost(w http.ResponseWriter, r *http.Request) {
+	var form webhookCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	owner, base := app.webhookOwner(r)
+
+	u, err := url.Parse(form.URL)
+	form.CheckField(form.NotBlank(form.URL), "url", "This field cannot be blank")
+	form.CheckField(form.MaxChars(form.URL, 2000), "url", "This field cannot be more than 2000 characters long")
+	form.CheckField(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "", "url", "This field must be an http or https URL")
+	form.CheckField(len(form.Events) > 0, "events", "Choose at least one event")
+	for _, event := range form.Events {
+		form.CheckField(slices.Contains(models.WebhookEvents, event), "events", "This field must be one of "+strings.Join(models.WebhookEvents, ", "))
+	}
+
+	webhooks, err := app.webhooks.List(owner)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	form.CheckField(len(webhooks) < maxWebhooks, "url", fmt.Sprintf("There can't be more than %d webhooks", maxWebhooks))
+
+	if !form.Valid() {
+		app.renderWebhooks(w, r, http.StatusUnprocessableEntity, form)
+		return
+	}
+
+	id, err := app.webhooks.Insert(owner, form.URL, form.Events)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "webhook.create", fmt.Sprintf("webhook:%d", id), map[string]any{
+		"url":    form.URL,
+		"events": form.Events,
+		"global": owner == 0,
+	})
+
+	app.sessionManager.Put(r.Context(), "flash", "Webhook created successfully!")
+
+	http.Redirect(w, r, fmt.Sprintf("%s/view/%d", base, id), http.StatusSeeOther)
+}
+
+// webhook() fetches the webhook, answering 404 if it isn't one of those
+// the page is about
+func (app *application) webhook(w http.ResponseWriter, r *http.Request, id int) (*models.Webhook, bool) {
+	owner, _ := app.webhookOwner(r)
+
+	h, err := app.webhooks.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	if h.UserID != owner {
+		app.notFound(w)
+		return nil, false
+	}
+
+	return h, true
+}
+
+// webhookView() shows the webhook's secret and its latest deliveries
+func (app *application) webhookView(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	h, ok := app.webhook(w, r, id)
+	if !ok {
+		return
+	}
+
+	deliveries, err := app.webhooks.Deliveries(h.ID, 50)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	_, base := app.webhookOwner(r)
+
+	data := app.newTemplateData(r)
+	data.Webhook = h
+	data.WebhookDeliveries = deliveries
+	data.WebhookBase = base
+
+	// the page shows the secret
+	w.Header().Set("Cache-Control", "no-store")
+
+	app.render(w, http.StatusOK, "webhook.html", data)
+}
+
+func (app *application) webhookDeletePost(w http.ResponseWriter, r *http.Request) {
+	var form webhookIDForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	owner, base := app.webhookOwner(r)
+
+	err = app.webhooks.Delete(owner, form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, "webhook.delete", fmt.Sprintf("webhook:%d", form.ID), nil)
+
+	app.sessionManager.Put(r.Context(), "flash", "Webhook deleted successfully!")
+
+	http.Redirect(w, r, base, http.StatusSeeOther)
+}
+
+// webhookRedeliverPost() sends a delivery's payload again, as a new
+// delivery
+func (app *application) webhookRedeliverPost(w http.ResponseWriter, r *http.Request) {
+	var form webhookIDForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	d, err := app.webhooks.GetDelivery(form.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	h, ok := app.webhook(w, r, d.WebhookID)
+	if !ok {
+		return
+	}
+
+	id, err := app.webhooks.Redeliver(d.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, "webhook.redeliver", fmt.Sprintf("webhook:%d", h.ID), map[string]any{"delivery": d.ID, "redelivery": id})
+
+	app.webhookSender.wakeUp()
+
+	app.sessionManager.Put(r.Context(), "flash", "The delivery will be sent again shortly")
+
+	_, base := app.webhookOwner(r)
+	http.Redirect(w, r, fmt.Sprintf("%s/view/%d", base, h.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/PPRAMANIK62/snippetbox/internal/models/mocks"
+)
+
+// webhookReceiver is an httptest server standing in for a webhook
+// receiver, which answers with the status codes it is given in turn,
+// then 200s
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	rcv := &webhookReceiver{statuses: statuses}
+
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+
+		rcv.requests = append(rcv.requests, r)
+		rcv.bodies = append(rcv.bodies, body)
+
+		status := http.StatusOK
+		if len(rcv.statuses) > 0 {
+			status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(rcv.Close)
+
+	return rcv
+}
+
+func (rcv *webhookReceiver) received() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	rcv := newWebhookReceiver(t)
+	hookID, _ := app.webhooks.Insert(1, rcv.URL, []string{models.EventSnippetCreated})
+	hook, _ := app.webhooks.Get(hookID)
+
+	code, _, _ := ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", newAPIToken(t, app, 1, models.ScopeWrite),
+		`{"title": "Hook", "content": "Line", "expires": 7}`)
+	assert.Equal(t, code, http.StatusCreated)
+
+	app.sendDueWebhooks()
+	assert.Equal(t, rcv.received(), 1)
+
+	req, body := rcv.requests[0], rcv.bodies[0]
+	assert.Equal(t, req.Method, http.MethodPost)
+	assert.Equal(t, req.Header.Get("Content-Type"), "application/json")
+	assert.Equal(t, req.Header.Get("X-Snippetbox-Event"), models.EventSnippetCreated)
+	assert.Equal(t, req.Header.Get("X-Snippetbox-Delivery"), "1")
+
+	// the receiver can check the signature with nothing but the secret
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write([]byte(req.Header.Get("X-Snippetbox-Timestamp") + "."))
+	mac.Write(body)
+	assert.Equal(t, req.Header.Get("X-Snippetbox-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	var payload struct {
+		Event   string `json:"event"`
+		Snippet struct {
+			ID    int    `json:"id"`
+			Title string `json:"title"`
+			URL   string `json:"url"`
+		} `json:"snippet"`
+	}
+	err := json.Unmarshal(body, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, payload.Event, models.EventSnippetCreated)
+	assert.Equal(t, payload.Snippet.ID, 2)
+	assert.Equal(t, payload.Snippet.Title, "Hook")
+	assert.Equal(t, payload.Snippet.URL, "https://localhost:4000/snippet/view/2")
+
+	d, _ := app.webhooks.GetDelivery(1)
+	assert.Equal(t, d.Status, models.DeliverySucceeded)
+	assert.Equal(t, d.Attempts, 1)
+	assert.Equal(t, d.AttemptLog[0].StatusCode, http.StatusOK)
+	assert.Equal(t, d.AttemptLog[0].Response, "OK")
+
+	// nothing is sent twice
+	app.sendDueWebhooks()
+	assert.Equal(t, rcv.received(), 1)
+}
+
+func TestWebhookRetries(t *testing.T) {
+	app := newTestApplication(t)
+
+	// makeDue() brings the delivery's next attempt forward to now
+	makeDue := func(id int) {
+		d, _ := app.webhooks.GetDelivery(id)
+		d.NextAttempt = time.Now()
+	}
+
+	t.Run("Backoff", func(t *testing.T) {
+		rcv := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+		hookID, _ := app.webhooks.Insert(1, rcv.URL, models.WebhookEvents)
+		id, _ := app.webhooks.Enqueue(hookID, models.EventSnippetCreated, []byte(`{}`))
+
+		app.sendDueWebhooks()
+		d, _ := app.webhooks.GetDelivery(id)
+		assert.Equal(t, d.Status, models.DeliveryPending)
+		assert.Equal(t, d.NextAttempt.Sub(d.AttemptLog[0].Attempted), time.Minute)
+
+		// it isn't due again yet
+		app.sendDueWebhooks()
+		assert.Equal(t, rcv.received(), 1)
+
+		makeDue(id)
+		app.sendDueWebhooks()
+		assert.Equal(t, d.Status, models.DeliveryPending)
+		assert.Equal(t, d.AttemptLog[1].StatusCode, http.StatusBadGateway)
+		assert.Equal(t, d.NextAttempt.Sub(d.AttemptLog[1].Attempted), 2*time.Minute)
+
+		makeDue(id)
+		app.sendDueWebhooks()
+		assert.Equal(t, d.Status, models.DeliverySucceeded)
+		assert.Equal(t, d.Attempts, 3)
+		assert.Equal(t, d.NextAttempt.IsZero(), true)
+	})
+
+	t.Run("Gives up", func(t *testing.T) {
+		rcv := newWebhookReceiver(t, http.StatusNotFound, http.StatusNotFound, http.StatusNotFound)
+		hookID, _ := app.webhooks.Insert(1, rcv.URL, models.WebhookEvents)
+		id, _ := app.webhooks.Enqueue(hookID, models.EventSnippetCreated, []byte(`{}`))
+
+		for range 3 {
+			makeDue(id)
+			app.sendDueWebhooks()
+		}
+
+		d, _ := app.webhooks.GetDelivery(id)
+		assert.Equal(t, d.Status, models.DeliveryFailed)
+		assert.Equal(t, d.Attempts, 3)
+
+		makeDue(id)
+		app.sendDueWebhooks()
+		assert.Equal(t, rcv.received(), 3)
+	})
+
+	t.Run("No answer", func(t *testing.T) {
+		rcv := newWebhookReceiver(t)
+		rcv.Close()
+
+		hookID, _ := app.webhooks.Insert(1, rcv.URL, models.WebhookEvents)
+		id, _ := app.webhooks.Enqueue(hookID, models.EventSnippetCreated, []byte(`{}`))
+
+		app.sendDueWebhooks()
+		d, _ := app.webhooks.GetDelivery(id)
+		assert.Equal(t, d.Status, models.DeliveryPending)
+		assert.Equal(t, d.AttemptLog[0].StatusCode, 0)
+		assert.StringContains(t, d.AttemptLog[0].Error, "connection refused")
+	})
+}
+
+func TestWebhookPrivateAddresses(t *testing.T) {
+	app := newTestApplication(t)
+	app.webhookSender = newWebhookSender(5*time.Second, time.Minute, 3, false)
+
+	rcv := newWebhookReceiver(t)
+
+	// users can't have the site make requests on its own network
+	userHook, _ := app.webhooks.Insert(1, rcv.URL, models.WebhookEvents)
+	id, _ := app.webhooks.Enqueue(userHook, models.EventSnippetCreated, []byte(`{}`))
+
+	app.sendDueWebhooks()
+	d, _ := app.webhooks.GetDelivery(id)
+	assert.StringContains(t, d.AttemptLog[0].Error, errPrivateAddress.Error())
+	assert.Equal(t, rcv.received(), 0)
+
+	// but admins can
+	globalHook, _ := app.webhooks.Insert(0, rcv.URL, models.WebhookEvents)
+	id, _ = app.webhooks.Enqueue(globalHook, models.EventSnippetCreated, []byte(`{}`))
+
+	app.sendDueWebhooks()
+	d, _ = app.webhooks.GetDelivery(id)
+	assert.Equal(t, d.Status, models.DeliverySucceeded)
+	assert.Equal(t, rcv.received(), 1)
+}
+
+func TestWebhookEvents(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	hooks := app.webhooks.(*mocks.WebhookModel)
+	own, _ := hooks.Insert(1, "https://example.com/own", []string{models.EventSnippetCreated, models.EventSnippetDeleted})
+	global, _ := hooks.Insert(0, "https://example.com/global", models.WebhookEvents)
+	other, _ := hooks.Insert(2, "https://example.com/other", models.WebhookEvents)
+
+	// sent() returns the events queued for the webhook since next() was
+	// last called
+	seen := 0
+	sent := func(webhookID int) []string {
+		events := []string{}
+		for _, d := range hooks.Queue[seen:] {
+			if d.WebhookID == webhookID {
+				events = append(events, d.Event)
+			}
+		}
+		return events
+	}
+	next := func() { seen = len(hooks.Queue) }
+
+	token := newAPIToken(t, app, 1, models.ScopeWrite)
+
+	ts.apiRequest(t, http.MethodPost, "/api/v1/snippets", token, `{"title": "Mine", "content": "x", "expires": 7, "visibility": "private"}`)
+	assert.Equal(t, len(sent(own)), 1)
+	// global webhooks only hear about public snippets
+	assert.Equal(t, len(sent(global)), 0)
+	assert.Equal(t, len(sent(other)), 0)
+	next()
+
+	ts.apiRequest(t, http.MethodDelete, "/api/v1/snippets/1", token, "")
+	assert.Equal(t, sent(own)[0], models.EventSnippetDeleted)
+	assert.Equal(t, sent(global)[0], models.EventSnippetDeleted)
+	assert.Equal(t, len(sent(other)), 0)
+	next()
+
+	// hiding is the only update, and hidden snippets' content is left out
+	app.reports.Insert(1, 0, "spam", "")
+	ts.loginAs(t, "moderator@example.com")
+	moderationPost(t, ts, "/moderation/hide", 1)
+	assert.Equal(t, len(sent(own)), 0)
+	assert.Equal(t, sent(global)[0], models.EventSnippetUpdated)
+
+	var payload struct {
+		Snippet struct {
+			Content string `json:"content"`
+			Hidden  bool   `json:"hidden"`
+		} `json:"snippet"`
+	}
+	json.Unmarshal(hooks.Queue[len(hooks.Queue)-1].Payload, &payload)
+	assert.Equal(t, payload.Snippet.Content, "")
+	assert.Equal(t, payload.Snippet.Hidden, true)
+	next()
+
+	// snippet 2 expires in a week
+	app.announceExpired(time.Now(), time.Now().AddDate(0, 0, 8))
+	assert.Equal(t, len(sent(own)), 0)
+	assert.Equal(t, len(sent(global)), 0)
+
+	app.webhooks.Insert(1, "https://example.com/expired", []string{models.EventSnippetExpired})
+	app.announceExpired(time.Now(), time.Now().AddDate(0, 0, 8))
+	assert.Equal(t, hooks.Queue[len(hooks.Queue)-1].Event, models.EventSnippetExpired)
+}
+
+func TestWebhookPages(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	hooks := app.webhooks.(*mocks.WebhookModel)
+	rcv := newWebhookReceiver(t)
+
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/account/webhooks")
+	assert.StringContains(t, body, "There aren't any webhooks yet.")
+	csrfToken := extractCSRFToken(t, body)
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, form := range []url.Values{
+			{"url": {"ftp://example.com"}, "events": {models.EventSnippetCreated}},
+			{"url": {rcv.URL}},
+			{"url": {rcv.URL}, "events": {"snippet.viewed"}},
+		} {
+			form.Set("csrf_token", csrfToken)
+			code, _, _ := ts.postForm(t, "/account/webhooks", form)
+			assert.Equal(t, code, http.StatusUnprocessableEntity)
+		}
+		assert.Equal(t, len(hooks.Webhooks), 0)
+	})
+
+	code, header, _ := ts.postForm(t, "/account/webhooks", url.Values{
+		"csrf_token": {csrfToken},
+		"url":        {rcv.URL},
+		"events":     {models.EventSnippetCreated, models.EventSnippetExpired},
+	})
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/account/webhooks/view/1")
+
+	hook := hooks.Webhooks[0]
+	assert.Equal(t, hook.UserID, 1)
+
+	id, _ := app.webhooks.Enqueue(hook.ID, models.EventSnippetCreated, []byte(`{"event": "snippet.created"}`))
+	app.sendDueWebhooks()
+
+	code, header, body = ts.get(t, "/account/webhooks/view/1")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("Cache-Control"), "no-store")
+	assert.StringContains(t, body, hook.Secret)
+	assert.StringContains(t, body, "succeeded")
+
+	code, _, _ = ts.postForm(t, "/account/webhooks/redeliver", url.Values{"csrf_token": {csrfToken}, "id": {"1"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	app.sendDueWebhooks()
+	assert.Equal(t, rcv.received(), 2)
+	assert.Equal(t, string(rcv.bodies[1]), string(rcv.bodies[0]))
+	assert.Equal(t, rcv.requests[1].Header.Get("X-Snippetbox-Delivery"), "2")
+
+	// other people's webhooks and deliveries, and global ones, are 404s
+	otherHook, _ := app.webhooks.Insert(2, rcv.URL, models.WebhookEvents)
+	otherDelivery, _ := app.webhooks.Enqueue(otherHook, models.EventSnippetCreated, []byte(`{}`))
+	globalHook, _ := app.webhooks.Insert(0, rcv.URL, models.WebhookEvents)
+
+	for _, id := range []int{otherHook, globalHook, 99} {
+		code, _, _ = ts.get(t, fmt.Sprintf("/account/webhooks/view/%d", id))
+		assert.Equal(t, code, http.StatusNotFound)
+
+		code, _, _ = ts.postForm(t, "/account/webhooks/delete", url.Values{"csrf_token": {csrfToken}, "id": {strconv.Itoa(id)}})
+		assert.Equal(t, code, http.StatusNotFound)
+	}
+
+	code, _, _ = ts.postForm(t, "/account/webhooks/redeliver", url.Values{"csrf_token": {csrfToken}, "id": {strconv.Itoa(otherDelivery)}})
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, _, _ = ts.postForm(t, "/account/webhooks/delete", url.Values{"csrf_token": {csrfToken}, "id": {"1"}})
+	assert.Equal(t, code, http.StatusSeeOther)
+	_, err := app.webhooks.GetDelivery(id)
+	assert.Equal(t, err, models.ErrNoRecord)
+
+	// only admins manage the global webhooks
+	code, _, _ = ts.get(t, "/admin/webhooks")
+	assert.Equal(t, code, http.StatusForbidden)
+
+	ts.loginAs(t, "admin@example.com")
+
+	_, _, body = ts.get(t, "/admin/webhooks")
+	assert.StringContains(t, body, "Global Webhooks")
+	csrfToken = extractCSRFToken(t, body)
+
+	code, header, _ = ts.postForm(t, "/admin/webhooks", url.Values{
+		"csrf_token": {csrfToken},
+		"url":        {"https://index.example.com/hook"},
+		"events":     models.WebhookEvents,
+	})
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/admin/webhooks/view/4")
+	assert.Equal(t, hooks.Webhooks[len(hooks.Webhooks)-1].UserID, 0)
+
+	code, _, _ = ts.get(t, fmt.Sprintf("/admin/webhooks/view/%d", globalHook))
+	assert.Equal(t, code, http.StatusOK)
+
+	actions := []string{}
+	for _, e := range app.auditLog.(*mocks.AuditModel).Events {
+		actions = append(actions, e.Action)
+	}
+	assert.StringContains(t, strings.Join(actions, " "), "webhook.create webhook.redeliver webhook.delete")
+}
@@ -0,0 +1,448 @@
+// Package cli is the snippet command, a client for the Snippetbox JSON API.
+// It lives here rather than in cmd/snippet so it can be tested against the
+// real server handler.
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+const usage = `Usage: snippet [-config FILE] COMMAND [ARGS]
+
+Commands:
+  login   [-url URL] [-token TOKEN]     store the server URL and an API token
+  create  [flags] [FILE...]             create a snippet from each file, or stdin
+  get     ID                            show a snippet
+  raw     ID                            print a snippet's content only
+  list    [-page N] [-per-page N]       list your snippets
+  search  [-page N] [-per-page N] QUERY search public snippets
+  delete  ID                            delete one of your snippets
+
+The SNIPPETBOX_URL and SNIPPETBOX_TOKEN environment variables override the
+config file. Run "snippet COMMAND -h" for a command's flags.
+`
+
+// errUsage means the command line was wrong, and usage has been printed
+var errUsage = errors.New("usage")
+
+// App is the snippet command. Streams and the HTTP client are fields so
+// tests can supply their own.
+type App struct {
+	Stdin      io.Reader
+	Stdout     io.Writer
+	Stderr     io.Writer
+	ConfigPath string       // DefaultConfigPath() if empty
+	HTTPClient *http.Client // http.DefaultClient if nil
+}
+
+// Run() runs the command in args, which excludes the program name, and
+// returns the exit status: 0 on success, 1 on error and 2 for bad usage
+func (app *App) Run(args []string) int {
+	fs := flag.NewFlagSet("snippet", flag.ContinueOnError)
+	fs.SetOutput(app.Stderr)
+	fs.Usage = func() { fmt.Fprint(app.Stderr, usage) }
+	fs.StringVar(&app.ConfigPath, "config", app.ConfigPath, "Config file")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return exitStatus(err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if app.ConfigPath == "" {
+		app.ConfigPath, err = DefaultConfigPath()
+		if err != nil {
+			fmt.Fprintln(app.Stderr, "snippet:", err)
+			return 1
+		}
+	}
+
+	commands := map[string]func([]string) error{
+		"login":  app.login,
+		"create": app.create,
+		"get":    app.get,
+		"raw":    app.raw,
+		"list":   app.list,
+		"search": app.search,
+		"delete": app.delete,
+	}
+
+	name := fs.Arg(0)
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(app.Stderr, "snippet: unknown command %q\n", name)
+		fs.Usage()
+		return 2
+	}
+
+	err = command(fs.Args()[1:])
+	if err != nil {
+		if !errors.Is(err, errUsage) && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(app.Stderr, "snippet %s: %s\n", name, err)
+		}
+		return exitStatus(err)
+	}
+	return 0
+}
+
+func exitStatus(err error) int {
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// flags() returns a flag set for a command, whose usage line is synopsis
+func (app *App) flags(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(app.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(app.Stderr, "Usage: snippet %s %s\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse() parses a command's flags, turning a mistake into errUsage since
+// the flag package has already explained it
+func parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsage
+	}
+	return err
+}
+
+// config() loads the config file and applies the environment on top
+func (app *App) config() (*Config, error) {
+	config, err := LoadConfig(app.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if url := os.Getenv("SNIPPETBOX_URL"); url != "" {
+		config.URL = url
+	}
+	if token := os.Getenv("SNIPPETBOX_TOKEN"); token != "" {
+		config.Token = token
+	}
+	return config, nil
+}
+
+// client() returns an API client for the configured server
+func (app *App) client() (*Client, error) {
+	config, err := app.config()
+	if err != nil {
+		return nil, err
+	}
+	if config.URL == "" {
+		return nil, errors.New(`no server configured; run "snippet login" first`)
+	}
+
+	return &Client{BaseURL: config.URL, Token: config.Token, HTTPClient: app.HTTPClient}, nil
+}
+
+func (app *App) login(args []string) error {
+	fs := app.flags("login", "[-url URL] [-token TOKEN]")
+	url := fs.String("url", "", "Server URL, such as https://snippets.example.com")
+	token := fs.String("token", "", "API token (read from stdin if not given)")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	config, err := LoadConfig(app.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if *url != "" {
+		config.URL = *url
+	}
+	if config.URL == "" {
+		return errors.New("the -url flag is required")
+	}
+
+	if *token == "" {
+		fmt.Fprint(app.Stderr, "API token: ")
+		line, err := bufio.NewReader(app.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		*token = strings.TrimSpace(line)
+	}
+	if *token == "" {
+		return errors.New("no API token given")
+	}
+	config.Token = *token
+
+	// only the owner's own snippets need a valid token, so listing them
+	// checks it works before it's saved
+	client := &Client{BaseURL: config.URL, Token: config.Token, HTTPClient: app.HTTPClient}
+	_, err = client.List(ListOptions{Mine: true, PerPage: 1})
+	if err != nil {
+		return err
+	}
+
+	err = config.Save(app.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(app.Stdout, "Logged in to %s\n", config.URL)
+	return nil
+}
+
+func (app *App) create(args []string) error {
+	fs := app.flags("create", "[flags] [FILE...]")
+	title := fs.String("title", "", "Title (defaults to the file name, or the first line of stdin)")
+	language := fs.String("lang", "", "Language (guessed from the file extension if not given)")
+	expires := fs.Int("expires", 365, "Days until the snippet expires: 1, 7 or 365")
+	visibility := fs.String("visibility", "public", "public, unlisted or private")
+	redact := fs.Bool("redact", false, "Redact anything that looks like a secret")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if *title != "" && len(files) > 1 {
+		return errors.New("-title can only be used with a single file")
+	}
+
+	client, err := app.client()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		s, err := app.readSnippet(file)
+		if err != nil {
+			return err
+		}
+		if *title != "" {
+			s.Title = *title
+		}
+		if *language != "" {
+			s.Language = *language
+		}
+		s.Expires = *expires
+		s.Visibility = *visibility
+		s.Redact = *redact
+
+		created, err := client.Create(s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(app.Stdout, created.URL)
+	}
+	return nil
+}
+
+// readSnippet() reads a file, or stdin for "-", and works out a title and
+// language from what it can
+func (app *App) readSnippet(file string) (NewSnippet, error) {
+	if file == "-" {
+		content, err := io.ReadAll(app.Stdin)
+		if err != nil {
+			return NewSnippet{}, err
+		}
+		return NewSnippet{Title: models.FirstLine(string(content)), Content: string(content)}, nil
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return NewSnippet{}, err
+	}
+	return NewSnippet{
+		Title:    filepath.Base(file),
+		Content:  string(content),
+		Language: models.LanguageFor(file),
+	}, nil
+}
+
+// snippetID() reads the single ID argument of get, raw and delete
+func (app *App) snippetID(name string, args []string) (int, error) {
+	fs := app.flags(name, "ID")
+
+	err := parse(fs, args)
+	if err != nil {
+		return 0, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 0, errUsage
+	}
+
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid snippet ID %q", fs.Arg(0))
+	}
+	return id, nil
+}
+
+func (app *App) get(args []string) error {
+	id, err := app.snippetID("get", args)
+	if err != nil {
+		return err
+	}
+
+	client, err := app.client()
+	if err != nil {
+		return err
+	}
+
+	s, err := client.Get(id)
+	if err != nil {
+		return err
+	}
+
+	language := s.Language
+	if language == "" {
+		language = "text"
+	}
+
+	fmt.Fprintf(app.Stdout, "#%d %s\n", s.ID, s.Title)
+	fmt.Fprintf(app.Stdout, "Language:   %s\n", language)
+	fmt.Fprintf(app.Stdout, "Visibility: %s\n", s.Visibility)
+	fmt.Fprintf(app.Stdout, "Created:    %s\n", s.Created.Format("02 Jan 2006 at 15:04"))
+	fmt.Fprintf(app.Stdout, "Expires:    %s\n", s.Expires.Format("02 Jan 2006 at 15:04"))
+	fmt.Fprintf(app.Stdout, "URL:        %s\n\n", s.URL)
+	fmt.Fprint(app.Stdout, s.Content)
+	if !strings.HasSuffix(s.Content, "\n") {
+		fmt.Fprintln(app.Stdout)
+	}
+	return nil
+}
+
+func (app *App) raw(args []string) error {
+	id, err := app.snippetID("raw", args)
+	if err != nil {
+		return err
+	}
+
+	client, err := app.client()
+	if err != nil {
+		return err
+	}
+
+	s, err := client.Get(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(app.Stdout, s.Content)
+	return err
+}
+
+func (app *App) list(args []string) error {
+	fs := app.flags("list", "[-page N] [-per-page N]")
+	page := fs.Int("page", 1, "Page number")
+	perPage := fs.Int("per-page", 0, "Snippets per page (the server's default if 0)")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return app.printList(ListOptions{Mine: true, Page: *page, PerPage: *perPage})
+}
+
+func (app *App) search(args []string) error {
+	fs := app.flags("search", "[-page N] [-per-page N] QUERY")
+	page := fs.Int("page", 1, "Page number")
+	perPage := fs.Int("per-page", 0, "Snippets per page (the server's default if 0)")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return app.printList(ListOptions{Query: strings.Join(fs.Args(), " "), Page: *page, PerPage: *perPage})
+}
+
+// printList() prints a page of snippets as a table
+func (app *App) printList(opts ListOptions) error {
+	client, err := app.client()
+	if err != nil {
+		return err
+	}
+
+	list, err := client.List(opts)
+	if err != nil {
+		return err
+	}
+
+	if len(list.Snippets) == 0 {
+		fmt.Fprintln(app.Stdout, "No snippets found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(app.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tLANGUAGE\tVISIBILITY\tCREATED")
+	for _, s := range list.Snippets {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", s.ID, s.Title, s.Language, s.Visibility, s.Created.Format("02 Jan 2006"))
+	}
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(app.Stdout, "Page %d of %d, %d snippets\n", list.Metadata.Page, list.Metadata.LastPage, list.Metadata.Total)
+	return nil
+}
+
+func (app *App) delete(args []string) error {
+	id, err := app.snippetID("delete", args)
+	if err != nil {
+		return err
+	}
+
+	client, err := app.client()
+	if err != nil {
+		return err
+	}
+
+	err = client.Delete(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(app.Stdout, "Deleted snippet %d\n", id)
+	return nil
+}
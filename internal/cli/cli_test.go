@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippetbox", "config.json")
+
+	t.Run("Missing", func(t *testing.T) {
+		config, err := LoadConfig(path)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, *config, Config{})
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		err := (&Config{URL: "https://snippets.example.com", Token: "sbx_TOKEN"}).Save(path)
+		assert.Equal(t, err, nil)
+
+		config, err := LoadConfig(path)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, *config, Config{URL: "https://snippets.example.com", Token: "sbx_TOKEN"})
+	})
+
+	t.Run("Environment", func(t *testing.T) {
+		t.Setenv("SNIPPETBOX_URL", "https://other.example.com")
+		t.Setenv("SNIPPETBOX_TOKEN", "")
+
+		config, err := (&App{ConfigPath: path}).config()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, *config, Config{URL: "https://other.example.com", Token: "sbx_TOKEN"})
+	})
+}
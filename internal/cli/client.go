@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to the Snippetbox JSON API
+type Client struct {
+	BaseURL    string // such as "https://snippets.example.com"
+	Token      string // sent as a bearer token, if not empty
+	HTTPClient *http.Client
+}
+
+// Snippet is a snippet as the API returns it
+type Snippet struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Language   string    `json:"language"`
+	Visibility string    `json:"visibility"`
+	Created    time.Time `json:"created"`
+	Expires    time.Time `json:"expires"`
+	Hidden     bool      `json:"hidden"`
+	URL        string    `json:"url"`
+}
+
+// NewSnippet is what Create() sends
+type NewSnippet struct {
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	Language   string `json:"language,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+	Expires    int    `json:"expires"`
+	Redact     bool   `json:"redact,omitempty"`
+}
+
+// ListOptions narrow down List()
+type ListOptions struct {
+	Query   string
+	Mine    bool // the token owner's snippets, rather than public ones
+	Page    int
+	PerPage int
+}
+
+// SnippetList is a page of snippets
+type SnippetList struct {
+	Snippets []*Snippet `json:"snippets"`
+	Metadata struct {
+		Page     int `json:"page"`
+		PerPage  int `json:"per_page"`
+		Total    int `json:"total"`
+		LastPage int `json:"last_page"`
+	} `json:"metadata"`
+}
+
+// APIError is an error response from the API
+type APIError struct {
+	Status  int               `json:"status"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+func (e *APIError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	fields := []string{}
+	for field, message := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", field, message))
+	}
+	slices.Sort(fields)
+
+	return e.Message + "\n  " + strings.Join(fields, "\n  ")
+}
+
+func (c *Client) Create(s NewSnippet) (*Snippet, error) {
+	var data struct {
+		Snippet *Snippet `json:"snippet"`
+	}
+
+	err := c.do(http.MethodPost, "/api/v1/snippets", s, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.Snippet, nil
+}
+
+func (c *Client) Get(id int) (*Snippet, error) {
+	var data struct {
+		Snippet *Snippet `json:"snippet"`
+	}
+
+	err := c.do(http.MethodGet, "/api/v1/snippets/"+strconv.Itoa(id), nil, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.Snippet, nil
+}
+
+func (c *Client) List(opts ListOptions) (*SnippetList, error) {
+	query := url.Values{}
+	if opts.Query != "" {
+		query.Set("q", opts.Query)
+	}
+	if opts.Mine {
+		query.Set("owner", "me")
+	}
+	if opts.Page != 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PerPage != 0 {
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+
+	path := "/api/v1/snippets"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	list := &SnippetList{}
+
+	err := c.do(http.MethodGet, path, nil, list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *Client) Delete(id int) error {
+	return c.do(http.MethodDelete, "/api/v1/snippets/"+strconv.Itoa(id), nil, nil)
+}
+
+// do() sends body, if any, as JSON and decodes the response into dst, if
+// it isn't nil. Error responses are returned as an *APIError.
+func (c *Client) do(method, path string, body, dst any) error {
+	var reader io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(js)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	rs, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode >= 400 {
+		var e struct {
+			Error *APIError `json:"error"`
+		}
+		if json.NewDecoder(rs.Body).Decode(&e) != nil || e.Error == nil {
+			return &APIError{Status: rs.StatusCode, Message: http.StatusText(rs.StatusCode)}
+		}
+		return e.Error
+	}
+
+	if dst == nil {
+		return nil
+	}
+	return json.NewDecoder(rs.Body).Decode(dst)
+}
@@ -0,0 +1,396 @@
+// Package highlight splits source code into tokens for colouring, such as
+// keywords, strings, comments and numbers.
+//
+// It knows the snippet languages well enough to colour them the way an
+// editor would at a glance, without parsing them: a language is a set of
+// keywords, comment markers and quote characters. Block comments are
+// followed from one line to the next; strings end with their line.
+package highlight
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is what a token is, which decides its colour
+type Kind int
+
+const (
+	Text Kind = iota
+	Keyword
+	String
+	Comment
+	Number
+	// Inserted and Deleted are the added and removed lines of a diff
+	Inserted
+	Deleted
+)
+
+// Token is a run of text of one kind. Tokens never hold a newline.
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+// syntax is what the tokenizer knows about a language
+type syntax struct {
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+	keywords     map[string]bool
+	// characters names may hold besides letters, digits and _, such as
+	// the - in CSS properties
+	nameChars string
+	// keywords are matched whatever their case, as in SQL
+	foldCase bool
+	// text outside <...> is left alone, as in HTML and XML
+	markup bool
+}
+
+func words(s string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var cLike = [2]string{"/*", "*/"}
+
+var syntaxes = map[string]*syntax{
+	"bash": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords:     words("if then else elif fi for while until do done case esac in function return local export readonly declare set unset shift exit break continue echo source true false"),
+	},
+	"c": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("auto break case char const continue default do double else enum extern float for goto if inline int long register restrict return short signed sizeof static struct switch typedef union unsigned void volatile while bool true false NULL #include #define #ifdef #ifndef #endif #if #else #pragma"),
+	},
+	"cpp": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("auto bool break case catch char class const constexpr continue default delete do double else enum explicit extern false float for friend goto if inline int long namespace new noexcept nullptr operator override private protected public return short signed sizeof static struct switch template this throw true try typedef typename union unsigned using virtual void volatile while #include #define #ifdef #ifndef #endif #if #else #pragma"),
+	},
+	"csharp": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("abstract as async await base bool break byte case catch char class const continue decimal default delegate do double else enum event false finally float for foreach get if in int interface internal is long namespace new null object out override params private protected public readonly ref return sealed set short static string struct switch this throw true try typeof using var virtual void while"),
+	},
+	"css": {
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("@media @import @font-face @keyframes @supports !important"),
+		nameChars:    "-",
+	},
+	"go": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       "\"'`",
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false iota"),
+	},
+	"html": {
+		blockComment: [2]string{"<!--", "-->"},
+		quotes:       `"'`,
+		markup:       true,
+	},
+	"java": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("abstract boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long new null package private protected public return short static super switch synchronized this throw throws true false try var void volatile while"),
+	},
+	"javascript": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       "\"'`",
+		keywords:     words("async await break case catch class const continue debugger default delete do else export extends false finally for from function if import in instanceof let new null of return static super switch this throw true try typeof undefined var void while yield"),
+	},
+	"json": {
+		quotes:   `"`,
+		keywords: words("true false null"),
+	},
+	"kotlin": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("as break class continue do else false for fun if import in interface is null object package private public return super this throw true try typealias val var when while data sealed override open companion"),
+	},
+	"php": {
+		lineComments: []string{"//", "#"},
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("abstract and array as break case catch class const continue declare default do echo else elseif empty extends false final finally fn for foreach function global if implements include interface isset list match namespace new null or private protected public require return static switch throw trait true try use var while yield"),
+	},
+	"python": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords:     words("and as assert async await break class continue def del elif else except False finally for from global if import in is lambda None nonlocal not or pass raise return True try while with yield self"),
+	},
+	"ruby": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords:     words("alias and begin break case class def defined? do else elsif end ensure false for if in module next nil not or redo rescue retry return self super then true undef unless until when while yield require attr_accessor"),
+		nameChars:    "?!",
+	},
+	"rust": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		// not ', which also starts lifetimes
+		quotes:   `"`,
+		keywords: words("as async await break const continue crate dyn else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while Some None Ok Err"),
+	},
+	"sql": {
+		lineComments: []string{"--"},
+		blockComment: cLike,
+		quotes:       `"'`,
+		keywords:     words("add all alter and as asc between by case check column constraint create database default delete desc distinct drop else end exists foreign from group having if in index inner insert into is join key left like limit not null on or order outer primary references right select set table then union unique update values view when where with"),
+		foldCase:     true,
+	},
+	"swift": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       `"`,
+		keywords:     words("as associatedtype break case catch class continue default defer do else enum extension false fileprivate for func guard if import in init inout internal is let nil private protocol public repeat return self static struct subscript super switch throw throws true try var where while"),
+	},
+	"toml": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords:     words("true false"),
+	},
+	"typescript": {
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       "\"'`",
+		keywords:     words("abstract any as async await boolean break case catch class const continue declare default delete do else enum export extends false finally for from function if implements import in instanceof interface keyof let never new null number of private protected public readonly return static string super switch this throw true try type typeof undefined unknown var void while yield"),
+	},
+	"xml": {
+		blockComment: [2]string{"<!--", "-->"},
+		quotes:       `"'`,
+		markup:       true,
+	},
+	"yaml": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords:     words("true false null yes no on off"),
+	},
+}
+
+// Lines() splits the content into lines of tokens. Languages it doesn't
+// know, such as "" for plain text and Markdown, are all Text.
+func Lines(content, language string) [][]Token {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	out := make([][]Token, 0, len(lines))
+
+	if language == "diff" {
+		for _, line := range lines {
+			out = append(out, diffLine(line))
+		}
+		return out
+	}
+
+	s, ok := syntaxes[language]
+	if !ok {
+		for _, line := range lines {
+			t := &tokenizer{}
+			t.add(Text, line)
+			out = append(out, t.done())
+		}
+		return out
+	}
+
+	t := &tokenizer{syntax: s}
+	for _, line := range lines {
+		out = append(out, t.line(line))
+	}
+	return out
+}
+
+func diffLine(line string) []Token {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "@@"):
+		return []Token{{Kind: Comment, Text: line}}
+	case strings.HasPrefix(line, "+"):
+		return []Token{{Kind: Inserted, Text: line}}
+	case strings.HasPrefix(line, "-"):
+		return []Token{{Kind: Deleted, Text: line}}
+	}
+	t := &tokenizer{}
+	t.add(Text, line)
+	return t.done()
+}
+
+// tokenizer keeps what carries over from one line to the next
+type tokenizer struct {
+	syntax    *syntax
+	inComment bool
+	inTag     bool
+	tokens    []Token
+}
+
+// add() appends text to the line's tokens, joining it to the last token
+// if that is of the same kind
+func (t *tokenizer) add(kind Kind, text string) {
+	if text == "" {
+		return
+	}
+	if n := len(t.tokens); n > 0 && t.tokens[n-1].Kind == kind {
+		t.tokens[n-1].Text += text
+		return
+	}
+	t.tokens = append(t.tokens, Token{Kind: kind, Text: text})
+}
+
+func (t *tokenizer) line(line string) []Token {
+	s := t.syntax
+	t.tokens = nil
+
+	for i := 0; i < len(line); {
+		rest := line[i:]
+
+		if t.inComment {
+			end := strings.Index(rest, s.blockComment[1])
+			if end < 0 {
+				t.add(Comment, rest)
+				break
+			}
+			end += len(s.blockComment[1])
+			t.add(Comment, rest[:end])
+			t.inComment = false
+			i += end
+			continue
+		}
+
+		if s.blockComment[0] != "" && strings.HasPrefix(rest, s.blockComment[0]) {
+			t.inComment = true
+			t.add(Comment, s.blockComment[0])
+			i += len(s.blockComment[0])
+			continue
+		}
+
+		if s.markup && !t.inTag {
+			// text between tags, up to the next tag or comment
+			if rest[0] != '<' {
+				end := strings.IndexByte(rest, '<')
+				if end < 0 {
+					end = len(rest)
+				}
+				t.add(Text, rest[:end])
+				i += end
+				continue
+			}
+			t.inTag = true
+			n := 1 + nameLength(rest[1:], "/?!-:.")
+			t.add(Keyword, rest[:n])
+			i += n
+			continue
+		}
+		if s.markup && (rest[0] == '>' || strings.HasPrefix(rest, "/>")) {
+			t.inTag = false
+			n := 1
+			if rest[0] == '/' {
+				n = 2
+			}
+			t.add(Keyword, rest[:n])
+			i += n
+			continue
+		}
+
+		if lineComment(s, rest) {
+			t.add(Comment, rest)
+			break
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+
+		switch {
+		case strings.ContainsRune(s.quotes, r):
+			n := quotedLength(rest, r)
+			t.add(String, rest[:n])
+			i += n
+		case unicode.IsDigit(r):
+			n := nameLength(rest, ".")
+			t.add(Number, rest[:n])
+			i += n
+		case isNameStart(r):
+			n := nameLength(rest, s.nameChars)
+			word := rest[:n]
+			if s.foldCase {
+				word = strings.ToLower(word)
+			}
+			if s.keywords[word] {
+				t.add(Keyword, rest[:n])
+			} else {
+				t.add(Text, rest[:n])
+			}
+			i += n
+		default:
+			t.add(Text, rest[:size])
+			i += size
+		}
+	}
+
+	return t.done()
+}
+
+// done() returns the line's tokens, empty rather than nil for a blank line
+func (t *tokenizer) done() []Token {
+	if t.tokens == nil {
+		return []Token{}
+	}
+	return t.tokens
+}
+
+func lineComment(s *syntax, rest string) bool {
+	for _, marker := range s.lineComments {
+		if strings.HasPrefix(rest, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNameStart() reports whether r can start a keyword or identifier.
+// Preprocessor directives and CSS at-rules are keywords too.
+func isNameStart(r rune) bool {
+	return r == '_' || r == '#' || r == '@' || r == '!' || unicode.IsLetter(r)
+}
+
+// nameLength() returns the length in bytes of the identifier, number or
+// tag name at the start of s, which may also use the extra characters
+func nameLength(s, extra string) int {
+	for i, r := range s {
+		if i == 0 && isNameStart(r) {
+			continue
+		}
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(extra, r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// quotedLength() returns the length in bytes of the string at the start
+// of s, up to its closing quote or the end of the line
+func quotedLength(s string, quote rune) int {
+	escaped := false
+	for i, r := range s {
+		if i == 0 {
+			continue
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == quote:
+			return i + utf8.RuneLen(r)
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,116 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+// kinds() returns the tokens of a line as "kind:text" strings, which are
+// easier to compare
+func kinds(tokens []Token) []string {
+	names := map[Kind]string{Text: "text", Keyword: "keyword", String: "string", Comment: "comment", Number: "number", Inserted: "inserted", Deleted: "deleted"}
+
+	out := []string{}
+	for _, t := range tokens {
+		out = append(out, names[t.Kind]+":"+t.Text)
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		language string
+		want     [][]string
+	}{
+		{
+			name:     "Go",
+			content:  "func main() {\n\tfmt.Println(\"hi\\\"\", 42) // greet\n}",
+			language: "go",
+			want: [][]string{
+				{"keyword:func", "text: main() {"},
+				{"text:\tfmt.Println(", `string:"hi\""`, "text:, ", "number:42", "text:) ", "comment:// greet"},
+				{"text:}"},
+			},
+		},
+		{
+			name:     "Block comment over lines",
+			content:  "x /* one\ntwo */ return",
+			language: "c",
+			want: [][]string{
+				{"text:x ", "comment:/* one"},
+				{"comment:two */", "text: ", "keyword:return"},
+			},
+		},
+		{
+			name:     "Unterminated string",
+			content:  "s = 'abc\nt = 1",
+			language: "python",
+			want: [][]string{
+				{"text:s = ", "string:'abc"},
+				{"text:t = ", "number:1"},
+			},
+		},
+		{
+			name:     "SQL ignores case",
+			content:  "SELECT id FROM users",
+			language: "sql",
+			want: [][]string{
+				{"keyword:SELECT", "text: id ", "keyword:FROM", "text: users"},
+			},
+		},
+		{
+			name:     "Markup",
+			content:  `<a href="/">don't</a><!-- x -->`,
+			language: "html",
+			want: [][]string{
+				{"keyword:<a", "text: href=", `string:"/"`, "keyword:>", "text:don't", "keyword:</a>", "comment:<!-- x -->"},
+			},
+		},
+		{
+			name:     "Diff",
+			content:  "--- a\n+++ b\n@@ -1 +1 @@\n-old\n+new\n same",
+			language: "diff",
+			want: [][]string{
+				{"comment:--- a"},
+				{"comment:+++ b"},
+				{"comment:@@ -1 +1 @@"},
+				{"deleted:-old"},
+				{"inserted:+new"},
+				{"text: same"},
+			},
+		},
+		{
+			name:     "Plain text",
+			content:  "if 1 // no\r\n",
+			language: "",
+			want: [][]string{
+				{"text:if 1 // no"},
+				{},
+			},
+		},
+		{
+			name:     "Empty line",
+			content:  "",
+			language: "go",
+			want:     [][]string{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := Lines(tt.content, tt.language)
+			assert.Equal(t, len(lines), len(tt.want))
+
+			for i := range lines {
+				got := kinds(lines[i])
+				assert.Equal(t, len(got), len(tt.want[i]))
+				for j := range got {
+					assert.Equal(t, got[j], tt.want[i][j])
+				}
+			}
+		})
+	}
+}
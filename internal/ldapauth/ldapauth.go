@@ -0,0 +1,162 @@
+// Package ldapauth authenticates users against an LDAP directory using
+// the search-then-bind pattern: a search finds the user's entry from
+// their login, and a bind as that entry checks their password.
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Config describes how to reach the directory and how to map its
+// entries onto local users
+type Config struct {
+	// URL of the server, ldap://host:389 or ldaps://host:636
+	URL string
+	// StartTLS upgrades an ldap:// connection before binding
+	StartTLS bool
+	// TLSConfig is used for ldaps:// and StartTLS, if nil the server
+	// name is taken from the URL
+	TLSConfig *tls.Config
+
+	// BindDN and BindPassword are the service account used for the
+	// search, if BindDN is empty the search is done anonymously
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is where the search for users starts
+	BaseDN string
+	// UserFilter finds the user's entry, %s is replaced with the
+	// escaped login, e.g. "(&(objectClass=person)(mail=%s))"
+	UserFilter string
+
+	NameAttribute  string
+	EmailAttribute string
+}
+
+// Conn is the subset of *ldap.Conn we use, so tests can stand in for
+// a directory server
+type Conn interface {
+	Bind(username, password string) error
+	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+	StartTLS(config *tls.Config) error
+	Close() error
+}
+
+// Provisioner returns the local user for an email address, creating
+// them if needed. models.UserModel implements it.
+type Provisioner interface {
+	Provision(name, email string) (int, error)
+}
+
+// Authenticator implements models.Authenticator for an LDAP directory
+type Authenticator struct {
+	Config Config
+	Users  Provisioner
+
+	// Dial opens a connection to the directory, it defaults to
+	// ldap.DialURL
+	Dial func(addr string, tlsConfig *tls.Config) (Conn, error)
+}
+
+var errNoEmail = errors.New("ldapauth: directory entry has no email address")
+
+func dialURL(addr string, tlsConfig *tls.Config) (Conn, error) {
+	return ldap.DialURL(addr, ldap.DialWithTLSConfig(tlsConfig))
+}
+
+func (a *Authenticator) tlsConfig() (*tls.Config, error) {
+	if a.Config.TLSConfig != nil {
+		return a.Config.TLSConfig, nil
+	}
+
+	u, err := url.Parse(a.Config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{ServerName: u.Hostname()}, nil
+}
+
+func (a *Authenticator) Authenticate(login, password string) (int, error) {
+	// an empty password would be an unauthenticated bind, which most
+	// servers accept for any DN
+	if login == "" || password == "" {
+		return 0, models.ErrInvalidCredentials
+	}
+
+	tlsConfig, err := a.tlsConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	dial := a.Dial
+	if dial == nil {
+		dial = dialURL
+	}
+
+	conn, err := dial(a.Config.URL, tlsConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if a.Config.StartTLS {
+		err = conn.StartTLS(tlsConfig)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if a.Config.BindDN != "" {
+		err = conn.Bind(a.Config.BindDN, a.Config.BindPassword)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// ask for at most two entries: more than one match means the filter
+	// is ambiguous and we refuse to guess
+	request := ldap.NewSearchRequest(
+		a.Config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(a.Config.UserFilter, ldap.EscapeFilter(login)),
+		[]string{"dn", a.Config.NameAttribute, a.Config.EmailAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return 0, err
+	}
+	if result == nil || len(result.Entries) != 1 {
+		return 0, models.ErrInvalidCredentials
+	}
+
+	entry := result.Entries[0]
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	email := entry.GetAttributeValue(a.Config.EmailAttribute)
+	if email == "" {
+		return 0, errNoEmail
+	}
+
+	name := entry.GetAttributeValue(a.Config.NameAttribute)
+	if name == "" {
+		name = email
+	}
+
+	return a.Users.Provision(name, email)
+}
@@ -0,0 +1,209 @@
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// directory is an in-process stand-in for an LDAP server. Its search
+// matches entries having any attribute value named in an equality
+// filter, which is all Authenticate() needs.
+type directory struct {
+	entries   []*ldap.Entry
+	passwords map[string]string
+
+	// what the connection under test was asked to do
+	startTLS bool
+	binds    []string
+	filters  []string
+}
+
+func (d *directory) dial(addr string, tlsConfig *tls.Config) (Conn, error) {
+	return &conn{d}, nil
+}
+
+type conn struct {
+	d *directory
+}
+
+func (c *conn) Bind(username, password string) error {
+	c.d.binds = append(c.d.binds, username)
+
+	if want, ok := c.d.passwords[username]; !ok || want != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	return nil
+}
+
+func (c *conn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	c.d.filters = append(c.d.filters, request.Filter)
+
+	result := &ldap.SearchResult{}
+	for _, entry := range c.d.entries {
+		if matches(entry, request.Filter) {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	return result, nil
+}
+
+func matches(entry *ldap.Entry, filter string) bool {
+	for _, attribute := range entry.Attributes {
+		for _, value := range attribute.Values {
+			if strings.Contains(filter, "("+attribute.Name+"="+value+")") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *conn) StartTLS(config *tls.Config) error {
+	c.d.startTLS = true
+	return nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+// provisioner records the users created on first login
+type provisioner struct {
+	provisioned map[string]string
+}
+
+func (p *provisioner) Provision(name, email string) (int, error) {
+	p.provisioned[email] = name
+	return 7, nil
+}
+
+func newDirectory() *directory {
+	return &directory{
+		entries: []*ldap.Entry{
+			ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":  {"alice"},
+				"cn":   {"Alice Liddell"},
+				"mail": {"alice@example.com"},
+			}),
+			ldap.NewEntry("uid=nomail,ou=people,dc=example,dc=com", map[string][]string{
+				"uid": {"nomail"},
+				"cn":  {"No Mail"},
+			}),
+		},
+		passwords: map[string]string{
+			"cn=snippetbox,dc=example,dc=com":        "service",
+			"uid=alice,ou=people,dc=example,dc=com":  "wonderland",
+			"uid=nomail,ou=people,dc=example,dc=com": "secret",
+		},
+	}
+}
+
+func newAuthenticator(d *directory, p *provisioner) *Authenticator {
+	return &Authenticator{
+		Config: Config{
+			URL:            "ldap://ldap.example.com",
+			StartTLS:       true,
+			BindDN:         "cn=snippetbox,dc=example,dc=com",
+			BindPassword:   "service",
+			BaseDN:         "ou=people,dc=example,dc=com",
+			UserFilter:     "(&(objectClass=person)(mail=%s))",
+			NameAttribute:  "cn",
+			EmailAttribute: "mail",
+		},
+		Users: p,
+		Dial:  d.dial,
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantID   int
+		wantErr  error
+	}{
+		{
+			name:     "Valid credentials",
+			login:    "alice@example.com",
+			password: "wonderland",
+			wantID:   7,
+		},
+		{
+			name:     "Wrong password",
+			login:    "alice@example.com",
+			password: "looking-glass",
+			wantErr:  models.ErrInvalidCredentials,
+		},
+		{
+			name:     "Empty password",
+			login:    "alice@example.com",
+			password: "",
+			wantErr:  models.ErrInvalidCredentials,
+		},
+		{
+			name:     "Unknown user",
+			login:    "bob@example.com",
+			password: "wonderland",
+			wantErr:  models.ErrInvalidCredentials,
+		},
+		{
+			name:     "Filter injection",
+			login:    "*)(mail=alice@example.com",
+			password: "wonderland",
+			wantErr:  models.ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDirectory()
+			p := &provisioner{provisioned: map[string]string{}}
+
+			id, err := newAuthenticator(d, p).Authenticate(tt.login, tt.password)
+
+			assert.Equal(t, id, tt.wantID)
+			assert.Equal(t, errors.Is(err, tt.wantErr), true)
+
+			if tt.wantErr == nil {
+				assert.Equal(t, p.provisioned["alice@example.com"], "Alice Liddell")
+			} else {
+				assert.Equal(t, len(p.provisioned), 0)
+			}
+		})
+	}
+}
+
+func TestAuthenticateConnection(t *testing.T) {
+	d := newDirectory()
+	p := &provisioner{provisioned: map[string]string{}}
+
+	_, err := newAuthenticator(d, p).Authenticate("alice@example.com", "wonderland")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// StartTLS must happen before any credentials are sent, and the
+	// service account binds before the user does
+	assert.Equal(t, d.startTLS, true)
+	assert.Equal(t, len(d.binds), 2)
+	assert.Equal(t, d.binds[0], "cn=snippetbox,dc=example,dc=com")
+	assert.Equal(t, d.binds[1], "uid=alice,ou=people,dc=example,dc=com")
+	assert.Equal(t, d.filters[0], "(&(objectClass=person)(mail=alice@example.com))")
+}
+
+func TestAuthenticateMissingEmail(t *testing.T) {
+	d := newDirectory()
+
+	a := newAuthenticator(d, &provisioner{provisioned: map[string]string{}})
+	a.Config.UserFilter = "(uid=%s)"
+
+	_, err := a.Authenticate("nomail", "secret")
+	assert.Equal(t, errors.Is(err, errNoEmail), true)
+}
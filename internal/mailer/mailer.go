@@ -0,0 +1,61 @@
+// Package mailer sends the handful of plain-text emails Snippetbox needs,
+// such as account unlock links.
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTP delivers mail through an SMTP server, authenticating with PLAIN
+// auth if a username is set
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Sender   string
+}
+
+func (m *SMTP) Send(to, subject, body string) error {
+	// refuse header injection through the recipient or subject
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("mailer: invalid header value")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	msg := "From: " + m.Sender + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	return smtp.SendMail(addr, auth, m.Sender, []string{to}, []byte(msg))
+}
+
+// Log writes mail to a logger instead of sending it, for development
+// setups without an SMTP server
+type Log struct {
+	Logger *log.Logger
+}
+
+func (m *Log) Send(to, subject, body string) error {
+	m.Logger.Printf("mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// AccountExport is everything we hold about a user that they can take
+// away with them
+type AccountExport struct {
+	User     *User
+	Snippets []*Snippet
+}
+
+// AccountModel works on a user together with everything they own, which
+// spans several tables and so always happens in a transaction
+type AccountModel struct {
+	DB *sql.DB
+}
+
+type AccountModelInterface interface {
+	Export(userID int) (*AccountExport, error)
+	Delete(userID int, anonymise bool) ([]*Snippet, error)
+}
+
+// Export() reads the user and all of their snippets, expired ones
+// included, from a single consistent snapshot
+func (m *AccountModel) Export(userID int) (*AccountExport, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export := &AccountExport{User: &User{}}
+
+	statement := "SELECT id, name, email, created FROM users WHERE id = ?"
+
+	err = tx.QueryRow(statement, userID).Scan(&export.User.ID, &export.User.Name, &export.User.Email, &export.User.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	statement = `SELECT id, user_id, title, content, language, visibility, created, expires FROM snippets
+	WHERE user_id = ? ORDER BY created`
+
+	rows, err := tx.Query(statement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	export.Snippets = []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		err := rows.Scan(&s.ID, &s.UserID, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.Created, &s.Expires)
+		if err != nil {
+			return nil, err
+		}
+		export.Snippets = append(export.Snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return export, tx.Commit()
+}
+
+// Delete() removes the user along with their sessions and login methods.
+// Their snippets are deleted too, or kept without an owner if anonymise
+// is true. Either everything goes or nothing does. It returns the deleted
+// snippets that hadn't expired yet, read in the same transaction so that
+// none created meanwhile are missed.
+func (m *AccountModel) Delete(userID int, anonymise bool) ([]*Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := []*Snippet{}
+
+	snippets := "DELETE FROM snippets WHERE user_id = ?"
+	if anonymise {
+		snippets = "UPDATE snippets SET user_id = NULL WHERE user_id = ?"
+	} else {
+		statement := "SELECT " + snippetColumns + ` FROM snippets
+		WHERE user_id = ? AND expires > UTC_TIMESTAMP() FOR UPDATE`
+
+		rows, err := tx.Query(statement, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			s, err := scanSnippet(rows)
+			if err != nil {
+				return nil, err
+			}
+			deleted = append(deleted, s)
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	statements := []string{
+		snippets,
+		// the scs sessions table doesn't know its users, so go through ours
+		`DELETE s FROM sessions s JOIN user_sessions us ON us.token = s.token
+		WHERE us.user_id = ?`,
+		"DELETE FROM user_sessions WHERE user_id = ?",
+		"DELETE FROM persistent_logins WHERE user_id = ?",
+		"DELETE FROM passkeys WHERE user_id = ?",
+		"DELETE FROM identities WHERE user_id = ?",
+		"DELETE FROM api_tokens WHERE user_id = ?",
+	}
+
+	for _, statement := range statements {
+		_, err = tx.Exec(statement, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM users WHERE id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return deleted, tx.Commit()
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+func newMock(t *testing.T) (*AccountModel, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &AccountModel{DB: db}, mock
+}
+
+func expect(mock sqlmock.Sqlmock, statement string) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(regexp.QuoteMeta(statement)).WithArgs(1)
+}
+
+// expectDeleted() expects the snippets about to be deleted to be read,
+// and gives back one of them
+func expectDeleted(mock sqlmock.Sqlmock) {
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + snippetColumns + " FROM snippets")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "language", "visibility", "created", "expires", "hidden"}).
+			AddRow(3, 1, "Haiku", "An old silent pond...", "", "public", now, now.Add(time.Hour), false))
+}
+
+func TestAccountModelDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		anonymise   bool
+		snippets    string
+		wantDeleted int
+	}{
+		{
+			name:        "Delete snippets",
+			snippets:    "DELETE FROM snippets WHERE user_id = ?",
+			wantDeleted: 1,
+		},
+		{
+			name:      "Anonymise snippets",
+			anonymise: true,
+			snippets:  "UPDATE snippets SET user_id = NULL WHERE user_id = ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock := newMock(t)
+
+			mock.ExpectBegin()
+			if !tt.anonymise {
+				expectDeleted(mock)
+			}
+			expect(mock, tt.snippets).WillReturnResult(sqlmock.NewResult(0, 2))
+			expect(mock, "DELETE s FROM sessions s").WillReturnResult(sqlmock.NewResult(0, 1))
+			expect(mock, "DELETE FROM user_sessions").WillReturnResult(sqlmock.NewResult(0, 1))
+			expect(mock, "DELETE FROM persistent_logins").WillReturnResult(sqlmock.NewResult(0, 0))
+			expect(mock, "DELETE FROM passkeys").WillReturnResult(sqlmock.NewResult(0, 0))
+			expect(mock, "DELETE FROM identities").WillReturnResult(sqlmock.NewResult(0, 0))
+			expect(mock, "DELETE FROM api_tokens").WillReturnResult(sqlmock.NewResult(0, 0))
+			expect(mock, "DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			deleted, err := m.Delete(1, tt.anonymise)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(deleted), tt.wantDeleted)
+			assert.Equal(t, mock.ExpectationsWereMet(), nil)
+
+			if tt.wantDeleted > 0 {
+				assert.Equal(t, deleted[0].ID, 3)
+				assert.Equal(t, deleted[0].Content, "An old silent pond...")
+			}
+		})
+	}
+}
+
+func TestAccountModelDeleteRollsBack(t *testing.T) {
+	m, mock := newMock(t)
+
+	failure := errors.New("connection lost")
+
+	mock.ExpectBegin()
+	expectDeleted(mock)
+	expect(mock, "DELETE FROM snippets").WillReturnResult(sqlmock.NewResult(0, 2))
+	expect(mock, "DELETE s FROM sessions s").WillReturnError(failure)
+	mock.ExpectRollback()
+
+	_, err := m.Delete(1, false)
+	assert.Equal(t, err, failure)
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
+
+func TestAccountModelDeleteMissingUser(t *testing.T) {
+	m, mock := newMock(t)
+
+	mock.ExpectBegin()
+	expectDeleted(mock)
+	for _, statement := range []string{"DELETE FROM snippets", "DELETE s FROM sessions s", "DELETE FROM user_sessions",
+		"DELETE FROM persistent_logins", "DELETE FROM passkeys", "DELETE FROM identities", "DELETE FROM api_tokens"} {
+		expect(mock, statement).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	expect(mock, "DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := m.Delete(1, false)
+	assert.Equal(t, err, ErrNoRecord)
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
+
+func TestAccountModelExport(t *testing.T) {
+	m, mock := newMock(t)
+
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, email, created FROM users")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "created"}).
+			AddRow(1, "Alice", "alice@example.com", now))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, user_id, title, content, language, visibility, created, expires FROM snippets")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "language", "visibility", "created", "expires"}).
+			AddRow(3, 1, "Haiku", "An old silent pond...", "", "public", now, now).
+			AddRow(4, 1, "Expired", "package main", "go", "private", now, now.Add(-time.Hour)))
+	mock.ExpectCommit()
+
+	export, err := m.Export(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, export.User.Email, "alice@example.com")
+	assert.Equal(t, len(export.Snippets), 2)
+	assert.Equal(t, export.Snippets[0].Content, "An old silent pond...")
+	assert.Equal(t, export.Snippets[1].Language, "go")
+	assert.Equal(t, export.Snippets[1].Visibility, VisibilityPrivate)
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix starts every API token, so that they are easy to spot,
+// for instance by the secret scanner
+const APITokenPrefix = "sbx_"
+
+// Scope is something an API token may be used for
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+	// ScopeAdmin lets the token use its owner's moderator or admin role;
+	// without it the token can do no more than an ordinary user
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes lists every scope, in order
+var Scopes = []Scope{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// Valid() reports whether s is one of the scopes above
+func (s Scope) Valid() bool {
+	return slices.Contains(Scopes, s)
+}
+
+// APIToken lets a script use the JSON API as the user who created it. We
+// only store a hash of the token itself, which is shown to the user once.
+type APIToken struct {
+	ID       int
+	UserID   int
+	Name     string
+	Scopes   []Scope
+	Created  time.Time
+	Expires  time.Time // zero if it never expires
+	LastUsed time.Time // zero if never used
+}
+
+// HasScope() reports whether the token may be used for scope
+func (t *APIToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired() reports whether the token has passed its expiry date
+func (t *APIToken) Expired() bool {
+	return !t.Expires.IsZero() && !time.Now().Before(t.Expires)
+}
+
+type APITokenModel struct {
+	DB *sql.DB
+}
+
+type APITokenModelInterface interface {
+	Insert(userID int, name string, scopes []Scope, expires int) (int, string, error)
+	Authenticate(token string) (*APIToken, error)
+	List(userID int) ([]*APIToken, error)
+	Delete(userID, id int) error
+}
+
+// NewAPIToken() returns a new random token
+func NewAPIToken() string {
+	return APITokenPrefix + rand.Text()
+}
+
+// Insert() creates a token for the user, returning its id and the token.
+// It expires after the given number of days, or never if that is 0.
+func (m *APITokenModel) Insert(userID int, name string, scopes []Scope, expires int) (int, string, error) {
+	token := NewAPIToken()
+	hash := sha256.Sum256([]byte(token))
+
+	statement := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, created, expires)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), IF(? > 0, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY), NULL))`
+
+	result, err := m.DB.Exec(statement, userID, name, hash[:], joinScopes(scopes), expires, expires)
+	if err != nil {
+		return 0, "", err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return int(id), token, nil
+}
+
+// Authenticate() returns the token, and notes that it has been used. It
+// returns ErrNoRecord for a token that doesn't exist, has been deleted or
+// has expired.
+func (m *APITokenModel) Authenticate(token string) (*APIToken, error) {
+	hash := sha256.Sum256([]byte(token))
+
+	statement := `SELECT id, user_id, name, scopes, created, expires, last_used FROM api_tokens
+	WHERE token_hash = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())`
+
+	t, err := scanAPIToken(m.DB.QueryRow(statement, hash[:]))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	statement = "UPDATE api_tokens SET last_used = UTC_TIMESTAMP() WHERE id = ?"
+
+	_, err = m.DB.Exec(statement, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// List() returns the user's tokens, newest first
+func (m *APITokenModel) List(userID int) ([]*APIToken, error) {
+	statement := `SELECT id, user_id, name, scopes, created, expires, last_used FROM api_tokens
+	WHERE user_id = ? ORDER BY created DESC, id DESC`
+
+	rows, err := m.DB.Query(statement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Delete() revokes one of the user's tokens, returning ErrNoRecord if
+// they have no token with that id
+func (m *APITokenModel) Delete(userID, id int) error {
+	statement := "DELETE FROM api_tokens WHERE id = ? AND user_id = ?"
+
+	result, err := m.DB.Exec(statement, id, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// scanAPIToken() reads a row of the columns selected above
+func scanAPIToken(row interface{ Scan(...any) error }) (*APIToken, error) {
+	t := &APIToken{}
+	var scopes string
+	var expires, lastUsed sql.NullTime
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.Created, &expires, &lastUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Scopes = splitScopes(scopes)
+	t.Expires = expires.Time
+	t.LastUsed = lastUsed.Time
+
+	return t, nil
+}
+
+// scopes are stored as a comma separated list, such as "read,write"
+func joinScopes(scopes []Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, ",")
+}
+
+func splitScopes(s string) []Scope {
+	scopes := []Scope{}
+	for _, field := range strings.Split(s, ",") {
+		if field != "" {
+			scopes = append(scopes, Scope(field))
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+func TestAPITokenModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &APITokenModel{DB: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO api_tokens")).
+		WithArgs(1, "CI", sqlmock.AnyArg(), "read,write", 30, 30).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+
+	id, token, err := m.Insert(1, "CI", []Scope{ScopeRead, ScopeWrite}, 30)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, id, 5)
+	assert.Equal(t, strings.HasPrefix(token, APITokenPrefix), true)
+
+	// only the hash of the token is looked up, never the token itself
+	sum := sha256.Sum256([]byte(token))
+	hash := sum[:]
+
+	// expired tokens are left to the query
+	now := time.Now()
+	columns := []string{"id", "user_id", "name", "scopes", "created", "expires", "last_used"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE token_hash = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())")).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, 1, "CI", "read,write", now, now.AddDate(0, 0, 30), nil))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE api_tokens SET last_used = UTC_TIMESTAMP() WHERE id = ?")).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	apiToken, err := m.Authenticate(token)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, apiToken.UserID, 1)
+	assert.Equal(t, apiToken.HasScope(ScopeWrite), true)
+	assert.Equal(t, apiToken.HasScope(ScopeAdmin), false)
+	assert.Equal(t, apiToken.LastUsed.IsZero(), true)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM api_tokens WHERE token_hash = ?")).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = m.Authenticate(APITokenPrefix + "unknown")
+	assert.Equal(t, err, ErrNoRecord)
+
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
+
+func TestAPITokenExpired(t *testing.T) {
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    bool
+	}{
+		{name: "Never", want: false},
+		{name: "Tomorrow", expires: time.Now().AddDate(0, 0, 1), want: false},
+		{name: "Yesterday", expires: time.Now().AddDate(0, 0, -1), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &APIToken{Expires: tt.expires}
+			assert.Equal(t, token.Expired(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+)
+
+// AuditEvent records who did what to what, for the audit log
+type AuditEvent struct {
+	ID        int
+	ActorID   int // 0 for an anonymous visitor
+	Action    string
+	Target    string
+	IP        string
+	UserAgent string
+	Metadata  map[string]any
+	Created   time.Time
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+type AuditModelInterface interface {
+	Insert(event *AuditEvent) error
+	List(filter AuditFilter) ([]*AuditEvent, error)
+	Each(filter AuditFilter, fn func(*AuditEvent) error) error
+	DeleteBefore(t time.Time) (int, error)
+}
+
+func (m *AuditModel) Insert(event *AuditEvent) error {
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return err
+	}
+
+	var actorID sql.NullInt64
+	if event.ActorID != 0 {
+		actorID = sql.NullInt64{Int64: int64(event.ActorID), Valid: true}
+	}
+
+	userAgent := truncate(event.UserAgent, 255)
+
+	// events may be written a little after they happened, so keep the
+	// time they were recorded at
+	created := event.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	statement := `INSERT INTO audit_log (actor_id, action, target, ip, user_agent, metadata, created)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = m.DB.Exec(statement, actorID, event.Action, event.Target, event.IP, userAgent, metadata, created.UTC())
+	return err
+}
+
+// AuditFilter narrows down the events returned by List() and Each()
+type AuditFilter struct {
+	ActorID int       // 0 for anyone
+	Action  string    // matches actions starting with it, so "admin." finds all admin actions
+	From    time.Time // zero for no lower bound
+	To      time.Time // zero for no upper bound
+}
+
+func (f AuditFilter) where() (string, []any) {
+	clause := " WHERE action LIKE ?"
+	args := []any{escapeLike(f.Action) + "%"}
+
+	if f.ActorID != 0 {
+		clause += " AND actor_id = ?"
+		args = append(args, f.ActorID)
+	}
+	if !f.From.IsZero() {
+		clause += " AND created >= ?"
+		args = append(args, f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		clause += " AND created < ?"
+		args = append(args, f.To.UTC())
+	}
+
+	return clause, args
+}
+
+// List() returns the newest 200 events matching the filter
+func (m *AuditModel) List(filter AuditFilter) ([]*AuditEvent, error) {
+	events := []*AuditEvent{}
+
+	err := m.query(filter, " ORDER BY id DESC LIMIT 200", func(e *AuditEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Each() calls fn for every event matching the filter, oldest first,
+// without holding them all in memory
+func (m *AuditModel) Each(filter AuditFilter, fn func(*AuditEvent) error) error {
+	return m.query(filter, " ORDER BY id", fn)
+}
+
+func (m *AuditModel) query(filter AuditFilter, order string, fn func(*AuditEvent) error) error {
+	where, args := filter.where()
+
+	statement := "SELECT id, actor_id, action, target, ip, user_agent, metadata, created FROM audit_log" + where + order
+
+	rows, err := m.DB.Query(statement, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := &AuditEvent{}
+		var actorID sql.NullInt64
+		var metadata []byte
+
+		err := rows.Scan(&e.ID, &actorID, &e.Action, &e.Target, &e.IP, &e.UserAgent, &metadata, &e.Created)
+		if err != nil {
+			return err
+		}
+		e.ActorID = int(actorID.Int64)
+
+		err = json.Unmarshal(metadata, &e.Metadata)
+		if err != nil {
+			return err
+		}
+
+		err = fn(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// DeleteBefore() removes the events older than t, returning how many
+func (m *AuditModel) DeleteBefore(t time.Time) (int, error) {
+	statement := "DELETE FROM audit_log WHERE created < ?"
+
+	result, err := m.DB.Exec(statement, t.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// truncate() cuts s down to at most n bytes, without splitting a UTF-8
+// sequence the database would then reject
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
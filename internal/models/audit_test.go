@@ -0,0 +1,66 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+func TestAuditModelList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &AuditModel{DB: db}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "actor_id", "action", "target", "ip", "user_agent", "metadata", "created"}).
+		AddRow(2, 3, "admin.user_role", "user:1", "127.0.0.1", "Go", []byte(`{"role":"admin"}`), created).
+		AddRow(1, nil, "admin.login", "", "127.0.0.1", "Go", []byte(`null`), created)
+
+	// the underscore is escaped, so it only matches itself
+	mock.ExpectQuery(regexp.QuoteMeta("FROM audit_log WHERE action LIKE ? AND created >= ? ORDER BY id DESC LIMIT 200")).
+		WithArgs(`admin.user\_%`, from).
+		WillReturnRows(rows)
+
+	events, err := m.List(AuditFilter{Action: "admin.user_", From: from})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, events[0].ActorID, 3)
+	assert.Equal(t, events[0].Metadata["role"], any("admin"))
+	assert.Equal(t, events[1].ActorID, 0)
+	assert.Equal(t, len(events[1].Metadata), 0)
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{"Short", "curl/8.5", 255, "curl/8.5"},
+		{"ASCII", "abcdef", 4, "abcd"},
+		{"On a boundary", "aé", 3, "aé"},
+		{"Inside a rune", "aé", 2, "a"},
+		{"Inside a 4 byte rune", "ab😀", 5, "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.s, tt.n)
+			assert.Equal(t, got, tt.want)
+			assert.Equal(t, utf8.ValidString(got), true)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"log"
+)
+
+// Authenticator checks a user's credentials and returns the id of the
+// matching local user, or ErrInvalidCredentials. UserModel implements it
+// with the bcrypt password stored in the users table.
+type Authenticator interface {
+	Authenticate(email, password string) (int, error)
+}
+
+// Authenticators tries each authenticator in List in turn and returns the
+// first success. An authenticator that fails, such as an LDAP server that
+// is down, is logged and skipped, so the ones after it still let their
+// users in. The credentials are rejected with ErrInvalidCredentials if any
+// authenticator rejected them and none accepted them; the error is only
+// returned if every authenticator failed, so that an outage doesn't turn a
+// wrong password into something other than a failed login.
+type Authenticators struct {
+	List   []Authenticator
+	Logger *log.Logger
+}
+
+func (a *Authenticators) Authenticate(email, password string) (int, error) {
+	var failed error
+	rejected := false
+
+	for _, authenticator := range a.List {
+		id, err := authenticator.Authenticate(email, password)
+		if err == nil {
+			return id, nil
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			rejected = true
+			continue
+		}
+
+		if a.Logger != nil {
+			a.Logger.Printf("authenticator %T: %s", authenticator, err)
+		}
+		if failed == nil {
+			failed = err
+		}
+	}
+
+	if failed != nil && !rejected {
+		return 0, failed
+	}
+	return 0, ErrInvalidCredentials
+}
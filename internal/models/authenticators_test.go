@@ -0,0 +1,68 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+// fakeAuthenticator accepts one user, or fails with err if it is set
+type fakeAuthenticator struct {
+	email string
+	id    int
+	err   error
+}
+
+func (a *fakeAuthenticator) Authenticate(email, password string) (int, error) {
+	if a.err != nil {
+		return 0, a.err
+	}
+	if email == a.email && password == "password" {
+		return a.id, nil
+	}
+	return 0, ErrInvalidCredentials
+}
+
+func TestAuthenticators(t *testing.T) {
+	errDown := errors.New("ldap: connection refused")
+
+	directory := &fakeAuthenticator{email: "directory@example.com", id: 1}
+	down := &fakeAuthenticator{err: errDown}
+	local := &fakeAuthenticator{email: "local@example.com", id: 2}
+
+	tests := []struct {
+		name    string
+		list    []Authenticator
+		email   string
+		wantID  int
+		wantErr error
+		wantLog bool
+	}{
+		{"First accepts", []Authenticator{directory, local}, "directory@example.com", 1, nil, false},
+		{"Second accepts", []Authenticator{directory, local}, "local@example.com", 2, nil, false},
+		{"None accept", []Authenticator{directory, local}, "nobody@example.com", 0, ErrInvalidCredentials, false},
+		{"First fails, second accepts", []Authenticator{down, local}, "local@example.com", 2, nil, true},
+		{"First fails, second rejects", []Authenticator{down, local}, "directory@example.com", 0, ErrInvalidCredentials, true},
+		{"All fail", []Authenticator{down}, "local@example.com", 0, errDown, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := new(bytes.Buffer)
+			a := &Authenticators{List: tt.list, Logger: log.New(logged, "", 0)}
+
+			id, err := a.Authenticate(tt.email, "password")
+			assert.Equal(t, id, tt.wantID)
+			assert.Equal(t, errors.Is(err, tt.wantErr), true)
+
+			if tt.wantLog {
+				assert.StringContains(t, logged.String(), "connection refused")
+			} else {
+				assert.Equal(t, logged.String(), "")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// DevKey is a user's developer key for the Pastebin compatible API. Each
+// user has at most one, which the Pastebin protocol sends as
+// api_dev_key. Like API tokens, only a hash of the key is stored.
+type DevKey struct {
+	UserID   int
+	Created  time.Time
+	LastUsed time.Time // zero if never used
+}
+
+type DevKeyModel struct {
+	DB *sql.DB
+}
+
+type DevKeyModelInterface interface {
+	Generate(userID int) (string, error)
+	Get(userID int) (*DevKey, error)
+	Authenticate(key string) (int, error)
+	Delete(userID int) error
+}
+
+// NewDevKey() returns a new random key, 32 hex digits like Pastebin's own
+func NewDevKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Generate() gives the user a new key, replacing any they had
+func (m *DevKeyModel) Generate(userID int) (string, error) {
+	key := NewDevKey()
+	hash := sha256.Sum256([]byte(key))
+
+	statement := `INSERT INTO dev_keys (user_id, key_hash, created, last_used) VALUES (?, ?, UTC_TIMESTAMP(), NULL)
+	ON DUPLICATE KEY UPDATE key_hash = VALUES(key_hash), created = VALUES(created), last_used = NULL`
+
+	_, err := m.DB.Exec(statement, userID, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// Get() returns the user's key, or ErrNoRecord if they don't have one
+func (m *DevKeyModel) Get(userID int) (*DevKey, error) {
+	k := &DevKey{}
+	var lastUsed sql.NullTime
+
+	statement := "SELECT user_id, created, last_used FROM dev_keys WHERE user_id = ?"
+
+	err := m.DB.QueryRow(statement, userID).Scan(&k.UserID, &k.Created, &lastUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	k.LastUsed = lastUsed.Time
+	return k, nil
+}
+
+// Authenticate() returns the id of the user whose key it is, and notes
+// that it has been used. It returns ErrNoRecord for an unknown key.
+func (m *DevKeyModel) Authenticate(key string) (int, error) {
+	hash := sha256.Sum256([]byte(key))
+	var userID int
+
+	statement := "SELECT user_id FROM dev_keys WHERE key_hash = ?"
+
+	err := m.DB.QueryRow(statement, hash[:]).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		} else {
+			return 0, err
+		}
+	}
+
+	statement = "UPDATE dev_keys SET last_used = UTC_TIMESTAMP() WHERE user_id = ?"
+
+	_, err = m.DB.Exec(statement, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// Delete() revokes the user's key, returning ErrNoRecord if they don't
+// have one
+func (m *DevKeyModel) Delete(userID int) error {
+	statement := "DELETE FROM dev_keys WHERE user_id = ?"
+
+	result, err := m.DB.Exec(statement, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
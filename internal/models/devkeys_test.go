@@ -0,0 +1,65 @@
+package models
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+func TestDevKeyModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &DevKeyModel{DB: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO dev_keys")).
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	key, err := m.Generate(1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(key), true)
+
+	// only the hash of the key is looked up, never the key itself
+	sum := sha256.Sum256([]byte(key))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT user_id FROM dev_keys WHERE key_hash = ?")).
+		WithArgs(sum[:]).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE dev_keys SET last_used = UTC_TIMESTAMP() WHERE user_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	userID, err := m.Authenticate(key)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, userID, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT user_id FROM dev_keys WHERE key_hash = ?")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	_, err = m.Authenticate("0123456789abcdef0123456789abcdef")
+	assert.Equal(t, err, ErrNoRecord)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT user_id, created, last_used FROM dev_keys WHERE user_id = ?")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "created", "last_used"}).AddRow(1, time.Now(), nil))
+
+	k, err := m.Get(1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, k.LastUsed.IsZero(), true)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM dev_keys WHERE user_id = ?")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Equal(t, m.Delete(2), ErrNoRecord)
+
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Identity links an account at an external identity provider (identified
+// by the issuer and subject of its ID tokens) to a local user
+type Identity struct {
+	ID      int
+	UserID  int
+	Issuer  string
+	Subject string
+	Email   string
+	Created time.Time
+}
+
+type IdentityModel struct {
+	DB *sql.DB
+}
+
+type IdentityModelInterface interface {
+	Insert(userID int, issuer, subject, email string) error
+	Get(issuer, subject string) (*Identity, error)
+}
+
+func (m *IdentityModel) Insert(userID int, issuer, subject, email string) error {
+	statement := `INSERT INTO identities (user_id, issuer, subject, email, created)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(statement, userID, issuer, subject, email)
+	return err
+}
+
+func (m *IdentityModel) Get(issuer, subject string) (*Identity, error) {
+	statement := `SELECT id, user_id, issuer, subject, email, created FROM identities
+	WHERE issuer = ? AND subject = ?`
+
+	i := &Identity{}
+
+	err := m.DB.QueryRow(statement, issuer, subject).Scan(&i.ID, &i.UserID, &i.Issuer, &i.Subject, &i.Email, &i.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
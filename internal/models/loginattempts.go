@@ -0,0 +1,117 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// LoginAttempt holds the failed login counter for a key, which is either
+// a client IP address or an account email
+type LoginAttempt struct {
+	Key         string
+	Failures    int
+	LockedUntil time.Time
+	LastFailure time.Time
+}
+
+type LoginAttemptModel struct {
+	DB *sql.DB
+}
+
+type LoginAttemptModelInterface interface {
+	Get(key string) (*LoginAttempt, error)
+	Fail(key string) (int, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+	SetUnlockToken(key, token string, expiry time.Time) error
+	Unlock(token string) error
+}
+
+func (m *LoginAttemptModel) Get(key string) (*LoginAttempt, error) {
+	statement := `SELECT login_key, failures, locked_until, last_failure FROM login_attempts
+	WHERE login_key = ?`
+
+	a := &LoginAttempt{}
+	var lockedUntil sql.NullTime
+
+	err := m.DB.QueryRow(statement, key).Scan(&a.Key, &a.Failures, &lockedUntil, &a.LastFailure)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+	a.LockedUntil = lockedUntil.Time
+
+	return a, nil
+}
+
+// Fail() records a failed attempt and returns the number of failures so
+// far. Failures older than a day are forgotten, so the counter starts
+// over instead of punishing the occasional typo forever.
+func (m *LoginAttemptModel) Fail(key string) (int, error) {
+	statement := `INSERT INTO login_attempts (login_key, failures, last_failure)
+	VALUES (?, 1, UTC_TIMESTAMP())
+	ON DUPLICATE KEY UPDATE
+		failures = IF(last_failure < UTC_TIMESTAMP() - INTERVAL 1 DAY, 1, failures + 1),
+		last_failure = UTC_TIMESTAMP()`
+
+	_, err := m.DB.Exec(statement, key)
+	if err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err = m.DB.QueryRow("SELECT failures FROM login_attempts WHERE login_key = ?", key).Scan(&failures)
+	return failures, err
+}
+
+func (m *LoginAttemptModel) Lock(key string, until time.Time) error {
+	statement := "UPDATE login_attempts SET locked_until = ? WHERE login_key = ?"
+
+	_, err := m.DB.Exec(statement, until.UTC(), key)
+	return err
+}
+
+func (m *LoginAttemptModel) Reset(key string) error {
+	statement := "DELETE FROM login_attempts WHERE login_key = ?"
+
+	_, err := m.DB.Exec(statement, key)
+	return err
+}
+
+// SetUnlockToken() stores a hash of the token sent in an unlock email,
+// the plain-text token only ever exists in the email itself
+func (m *LoginAttemptModel) SetUnlockToken(key, token string, expiry time.Time) error {
+	hash := sha256.Sum256([]byte(token))
+
+	statement := "UPDATE login_attempts SET unlock_hash = ?, unlock_expiry = ? WHERE login_key = ?"
+
+	_, err := m.DB.Exec(statement, hash[:], expiry.UTC(), key)
+	return err
+}
+
+// Unlock() clears the lockout the token was issued for
+func (m *LoginAttemptModel) Unlock(token string) error {
+	hash := sha256.Sum256([]byte(token))
+
+	statement := "DELETE FROM login_attempts WHERE unlock_hash = ? AND unlock_expiry > UTC_TIMESTAMP()"
+
+	result, err := m.DB.Exec(statement, hash[:])
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// AccountModel exports the mock user and snippet, and records which
+// accounts were deleted
+type AccountModel struct {
+	Deleted    []int
+	Anonymised []int
+}
+
+func (m *AccountModel) Export(userID int) (*models.AccountExport, error) {
+	switch userID {
+		case 1:
+			return &models.AccountExport{User: mockUser, Snippets: []*models.Snippet{mockSnippet}}, nil
+		default:
+			return nil, models.ErrNoRecord
+	}
+}
+
+func (m *AccountModel) Delete(userID int, anonymise bool) ([]*models.Snippet, error) {
+	m.Deleted = append(m.Deleted, userID)
+	if anonymise {
+		m.Anonymised = append(m.Anonymised, userID)
+		return []*models.Snippet{}, nil
+	}
+	if userID != mockSnippet.UserID {
+		return []*models.Snippet{}, nil
+	}
+	return []*models.Snippet{mockSnippet}, nil
+}
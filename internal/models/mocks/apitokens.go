@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// APITokenModel keeps API tokens in memory, by the token itself
+type APITokenModel struct {
+	Tokens map[string]*models.APIToken
+	nextID int
+}
+
+func (m *APITokenModel) Insert(userID int, name string, scopes []models.Scope, expires int) (int, string, error) {
+	if m.Tokens == nil {
+		m.Tokens = map[string]*models.APIToken{}
+	}
+
+	m.nextID++
+	token := models.NewAPIToken()
+	m.Tokens[token] = &models.APIToken{
+		ID: m.nextID,
+		UserID: userID,
+		Name: name,
+		Scopes: scopes,
+		Created: time.Now(),
+	}
+	if expires > 0 {
+		m.Tokens[token].Expires = time.Now().AddDate(0, 0, expires)
+	}
+	return m.nextID, token, nil
+}
+
+func (m *APITokenModel) Authenticate(token string) (*models.APIToken, error) {
+	t, ok := m.Tokens[token]
+	if !ok || t.Expired() {
+		return nil, models.ErrNoRecord
+	}
+	t.LastUsed = time.Now()
+	return t, nil
+}
+
+func (m *APITokenModel) List(userID int) ([]*models.APIToken, error) {
+	tokens := []*models.APIToken{}
+	for _, t := range m.Tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *APITokenModel) Delete(userID, id int) error {
+	for token, t := range m.Tokens {
+		if t.ID == id && t.UserID == userID {
+			delete(m.Tokens, token)
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
@@ -0,0 +1,85 @@
+package mocks
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// AuditModel keeps the audit log in memory. It is safe to use from the
+// background writer.
+type AuditModel struct {
+	mu     sync.Mutex
+	Events []*models.AuditEvent
+}
+
+func (m *AuditModel) Insert(event *models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.ID = len(m.Events) + 1
+	if event.Created.IsZero() {
+		event.Created = time.Now()
+	}
+	m.Events = append(m.Events, event)
+	return nil
+}
+
+// Actions() returns the actions recorded so far, in order
+func (m *AuditModel) Actions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	actions := []string{}
+	for _, e := range m.Events {
+		actions = append(actions, e.Action)
+	}
+	return actions
+}
+
+func (m *AuditModel) matches(e *models.AuditEvent, filter models.AuditFilter) bool {
+	return strings.HasPrefix(e.Action, filter.Action) &&
+		(filter.ActorID == 0 || e.ActorID == filter.ActorID) &&
+		(filter.From.IsZero() || !e.Created.Before(filter.From)) &&
+		(filter.To.IsZero() || e.Created.Before(filter.To))
+}
+
+func (m *AuditModel) List(filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []*models.AuditEvent{}
+	for i := len(m.Events) - 1; i >= 0; i-- {
+		if m.matches(m.Events[i], filter) {
+			events = append(events, m.Events[i])
+		}
+	}
+	return events, nil
+}
+
+func (m *AuditModel) Each(filter models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	events, _ := m.List(filter)
+	for i := len(events) - 1; i >= 0; i-- {
+		if err := fn(events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *AuditModel) DeleteBefore(t time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := []*models.AuditEvent{}
+	for _, e := range m.Events {
+		if !e.Created.Before(t) {
+			kept = append(kept, e)
+		}
+	}
+	n := len(m.Events) - len(kept)
+	m.Events = kept
+	return n, nil
+}
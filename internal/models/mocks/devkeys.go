@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// DevKeyModel keeps developer keys in memory, by the key itself
+type DevKeyModel struct {
+	Keys map[string]*models.DevKey
+}
+
+func (m *DevKeyModel) Generate(userID int) (string, error) {
+	if m.Keys == nil {
+		m.Keys = map[string]*models.DevKey{}
+	}
+
+	for key, k := range m.Keys {
+		if k.UserID == userID {
+			delete(m.Keys, key)
+		}
+	}
+
+	key := models.NewDevKey()
+	m.Keys[key] = &models.DevKey{UserID: userID, Created: time.Now()}
+	return key, nil
+}
+
+func (m *DevKeyModel) Get(userID int) (*models.DevKey, error) {
+	for _, k := range m.Keys {
+		if k.UserID == userID {
+			return k, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *DevKeyModel) Authenticate(key string) (int, error) {
+	k, ok := m.Keys[key]
+	if !ok {
+		return 0, models.ErrNoRecord
+	}
+	k.LastUsed = time.Now()
+	return k.UserID, nil
+}
+
+func (m *DevKeyModel) Delete(userID int) error {
+	for key, k := range m.Keys {
+		if k.UserID == userID {
+			delete(m.Keys, key)
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
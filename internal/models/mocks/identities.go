@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+type IdentityModel struct {
+	Identities []*models.Identity
+}
+
+func (m *IdentityModel) Insert(userID int, issuer, subject, email string) error {
+	m.Identities = append(m.Identities, &models.Identity{
+		ID: len(m.Identities) + 1,
+		UserID: userID,
+		Issuer: issuer,
+		Subject: subject,
+		Email: email,
+		Created: time.Now(),
+	})
+	return nil
+}
+
+func (m *IdentityModel) Get(issuer, subject string) (*models.Identity, error) {
+	for _, i := range m.Identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+type LoginAttemptModel struct {
+	Attempts     map[string]*models.LoginAttempt
+	UnlockTokens map[string]string
+}
+
+func (m *LoginAttemptModel) Get(key string) (*models.LoginAttempt, error) {
+	a, ok := m.Attempts[key]
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+	return a, nil
+}
+
+func (m *LoginAttemptModel) Fail(key string) (int, error) {
+	if m.Attempts == nil {
+		m.Attempts = map[string]*models.LoginAttempt{}
+	}
+
+	a, ok := m.Attempts[key]
+	if !ok {
+		a = &models.LoginAttempt{Key: key}
+		m.Attempts[key] = a
+	}
+	a.Failures++
+	a.LastFailure = time.Now()
+
+	return a.Failures, nil
+}
+
+func (m *LoginAttemptModel) Lock(key string, until time.Time) error {
+	if a, ok := m.Attempts[key]; ok {
+		a.LockedUntil = until
+	}
+	return nil
+}
+
+func (m *LoginAttemptModel) Reset(key string) error {
+	delete(m.Attempts, key)
+	return nil
+}
+
+func (m *LoginAttemptModel) SetUnlockToken(key, token string, expiry time.Time) error {
+	if m.UnlockTokens == nil {
+		m.UnlockTokens = map[string]string{}
+	}
+	m.UnlockTokens[token] = key
+	return nil
+}
+
+func (m *LoginAttemptModel) Unlock(token string) error {
+	key, ok := m.UnlockTokens[token]
+	if !ok {
+		return models.ErrNoRecord
+	}
+	delete(m.UnlockTokens, token)
+	delete(m.Attempts, key)
+	return nil
+}
@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// PasskeyModel keeps registered credentials in memory so that a test
+// can register a passkey and then log in with it
+type PasskeyModel struct {
+	Passkeys []*models.Passkey
+}
+
+func (m *PasskeyModel) Insert(userID int, name string, credential *webauthn.Credential) error {
+	m.Passkeys = append(m.Passkeys, &models.Passkey{
+		ID: len(m.Passkeys) + 1,
+		UserID: userID,
+		Name: name,
+		Credential: *credential,
+		Created: time.Now(),
+	})
+	return nil
+}
+
+func (m *PasskeyModel) GetByUser(userID int) ([]*models.Passkey, error) {
+	passkeys := []*models.Passkey{}
+	for _, p := range m.Passkeys {
+		if p.UserID == userID {
+			passkeys = append(passkeys, p)
+		}
+	}
+	return passkeys, nil
+}
+
+func (m *PasskeyModel) UpdateCredential(credential *webauthn.Credential) error {
+	for _, p := range m.Passkeys {
+		if bytes.Equal(p.Credential.ID, credential.ID) {
+			p.Credential = *credential
+			p.LastUsed = time.Now()
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+
+func (m *PasskeyModel) Delete(userID, id int) error {
+	for i, p := range m.Passkeys {
+		if p.ID == id && p.UserID == userID {
+			m.Passkeys = append(m.Passkeys[:i], m.Passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
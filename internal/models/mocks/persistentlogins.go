@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// PersistentLoginModel keeps "remember me" tokens in memory
+type PersistentLoginModel struct {
+	Logins map[string]*models.PersistentLogin
+}
+
+func (m *PersistentLoginModel) Insert(userID int, series, selector, validator string, expires time.Time) error {
+	if m.Logins == nil {
+		m.Logins = map[string]*models.PersistentLogin{}
+	}
+
+	hash := sha256.Sum256([]byte(validator))
+	m.Logins[selector] = &models.PersistentLogin{
+		Selector: selector,
+		Series: series,
+		UserID: userID,
+		ValidatorHash: hash[:],
+		Expires: expires,
+	}
+	return nil
+}
+
+func (m *PersistentLoginModel) Get(selector string) (*models.PersistentLogin, error) {
+	p, ok := m.Logins[selector]
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+	return p, nil
+}
+
+func (m *PersistentLoginModel) Rotate(selector string) error {
+	p, ok := m.Logins[selector]
+	if !ok || p.Rotated {
+		return models.ErrNoRecord
+	}
+	p.Rotated = true
+	return nil
+}
+
+func (m *PersistentLoginModel) DeleteSeries(series string) error {
+	for selector, p := range m.Logins {
+		if p.Series == series {
+			delete(m.Logins, selector)
+		}
+	}
+	return nil
+}
+
+func (m *PersistentLoginModel) DeleteByUser(userID int) error {
+	for selector, p := range m.Logins {
+		if p.UserID == userID {
+			delete(m.Logins, selector)
+		}
+	}
+	return nil
+}
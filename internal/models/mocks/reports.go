@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// ReportModel keeps the moderation queue in memory
+type ReportModel struct {
+	Reports []*models.Report
+	// resolution of each resolved report, by id
+	Resolved map[int]string
+}
+
+func (m *ReportModel) Insert(snippetID, reporterID int, reason, details string) (int, error) {
+	r := &models.Report{
+		ID: len(m.Reports) + 1,
+		SnippetID: snippetID,
+		ReporterID: reporterID,
+		Reason: reason,
+		Details: details,
+		Created: time.Now(),
+		SnippetTitle: mockSnippet.Title,
+	}
+	m.Reports = append(m.Reports, r)
+	return r.ID, nil
+}
+
+func (m *ReportModel) Get(id int) (*models.Report, error) {
+	for _, r := range m.Reports {
+		if r.ID == id && m.Resolved[id] == "" {
+			return r, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *ReportModel) Open() ([]*models.Report, error) {
+	reports := []*models.Report{}
+	for _, r := range m.Reports {
+		if m.Resolved[r.ID] == "" {
+			reports = append(reports, r)
+		}
+	}
+	return reports, nil
+}
+
+func (m *ReportModel) Resolve(snippetID, moderatorID int, resolution string) (int, error) {
+	if m.Resolved == nil {
+		m.Resolved = map[int]string{}
+	}
+
+	n := 0
+	for _, r := range m.Reports {
+		if r.SnippetID == snippetID && m.Resolved[r.ID] == "" {
+			m.Resolved[r.ID] = resolution
+			n++
+		}
+	}
+	return n, nil
+}
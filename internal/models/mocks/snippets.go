@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/PPRAMANIK62/snippetbox/internal/models"
@@ -8,27 +10,150 @@ import (
 
 var mockSnippet = &models.Snippet{
 	ID: 1,
+	UserID: 1,
 	Title: "An old silent pond",
 	Content: "An old silent pond...",
+	Visibility: models.VisibilityPublic,
 	Created: time.Now(),
 	Expires: time.Now(),
 }
 
-type SnippetModel struct{}
+type SnippetModel struct {
+	// snippets passed to Insert()
+	Inserted []*models.Snippet
+	// ids passed to Delete() and DeleteMany()
+	Deleted []int
+	// hidden state set by SetHidden()
+	Hidden map[int]bool
+	// tags set by SetTags()
+	Tagged map[int][]string
+}
 
-func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
+func (m *SnippetModel) Insert(userID int, title, content, language string, visibility models.Visibility, expires int) (int, error) {
+	m.Inserted = append(m.Inserted, &models.Snippet{
+		ID: 2,
+		UserID: userID,
+		Title: title,
+		Content: content,
+		Language: language,
+		Visibility: visibility,
+		Created: time.Now(),
+		Expires: time.Now().AddDate(0, 0, expires),
+	})
 	return 2, nil
 }
 
 func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 	switch id {
 		case 1:
-			return mockSnippet, nil
+			s := *mockSnippet
+			s.Hidden = m.Hidden[id]
+			return &s, nil
 		default:
+			// the most recent Insert() with this id
+			for i := len(m.Inserted) - 1; i >= 0; i-- {
+				if m.Inserted[i].ID == id {
+					s := *m.Inserted[i]
+					return &s, nil
+				}
+			}
 			return nil, models.ErrNoRecord
 	}
 }
 
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
+	if m.Hidden[mockSnippet.ID] {
+		return []*models.Snippet{}, nil
+	}
+	return []*models.Snippet{mockSnippet}, nil
+}
+
+// LatestFor() looks at the mock snippet and those inserted, newest first
+func (m *SnippetModel) LatestFor(userID int, language, tag string, limit int) ([]*models.Snippet, error) {
+	snippets := []*models.Snippet{}
+	for _, s := range append([]*models.Snippet{mockSnippet}, m.Inserted...) {
+		if m.Hidden[s.ID] || s.Visibility != models.VisibilityPublic {
+			continue
+		}
+		if (userID != 0 && s.UserID != userID) || (language != "" && s.Language != language) {
+			continue
+		}
+		if tag != "" && !slices.Contains(m.Tagged[s.ID], tag) {
+			continue
+		}
+		snippets = append([]*models.Snippet{s}, snippets...)
+	}
+	return snippets[:min(len(snippets), limit)], nil
+}
+
+func (m *SnippetModel) Tags(id int) ([]string, error) {
+	return append([]string{}, m.Tagged[id]...), nil
+}
+
+func (m *SnippetModel) SetTags(id int, tags []string) error {
+	if m.Tagged == nil {
+		m.Tagged = map[int][]string{}
+	}
+	m.Tagged[id] = tags
+	return nil
+}
+
+func (m *SnippetModel) Search(filter models.SnippetFilter) ([]*models.Snippet, error) {
+	if filter.UserID != 0 && filter.UserID != mockSnippet.UserID {
+		return []*models.Snippet{}, nil
+	}
+	if !strings.Contains(mockSnippet.Title, filter.Search) && !strings.Contains(mockSnippet.Content, filter.Search) {
+		return []*models.Snippet{}, nil
+	}
 	return []*models.Snippet{mockSnippet}, nil
 }
+
+func (m *SnippetModel) Page(query string, userID, limit, offset int) ([]*models.Snippet, int, error) {
+	if userID != 0 && userID != mockSnippet.UserID {
+		return []*models.Snippet{}, 0, nil
+	}
+	if m.Hidden[mockSnippet.ID] || !strings.Contains(mockSnippet.Title+mockSnippet.Content, query) {
+		return []*models.Snippet{}, 0, nil
+	}
+	if offset > 0 {
+		return []*models.Snippet{}, 1, nil
+	}
+	return []*models.Snippet{mockSnippet}, 1, nil
+}
+
+func (m *SnippetModel) Delete(userID, id int) error {
+	if id != mockSnippet.ID || userID != mockSnippet.UserID {
+		return models.ErrNoRecord
+	}
+	m.Deleted = append(m.Deleted, id)
+	return nil
+}
+
+func (m *SnippetModel) DeleteMany(ids []int) (int, error) {
+	n := 0
+	for _, id := range ids {
+		if id == mockSnippet.ID {
+			n++
+		}
+	}
+	m.Deleted = append(m.Deleted, ids...)
+	return n, nil
+}
+
+func (m *SnippetModel) SetHidden(id int, hidden bool) error {
+	if m.Hidden == nil {
+		m.Hidden = map[int]bool{}
+	}
+	m.Hidden[id] = hidden
+	return nil
+}
+
+func (m *SnippetModel) Expired(from, to time.Time) ([]*models.Snippet, error) {
+	snippets := []*models.Snippet{}
+	for _, s := range append([]*models.Snippet{mockSnippet}, m.Inserted...) {
+		if s.Expires.After(from) && !s.Expires.After(to) {
+			snippets = append(snippets, s)
+		}
+	}
+	return snippets, nil
+}
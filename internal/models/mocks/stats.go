@@ -0,0 +1,19 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+type StatsModel struct{}
+
+func (m *StatsModel) Get(days int) (*models.Stats, error) {
+	return &models.Stats{
+		Users: 3,
+		Snippets: 1,
+		ActiveUsersDay: 1,
+		ActiveUsersWeek: 2,
+		SnippetsPerDay: []models.DayCount{{Day: time.Now(), Count: 1}},
+	}, nil
+}
@@ -1,8 +1,62 @@
 package mocks
 
-import "github.com/PPRAMANIK62/snippetbox/internal/models"
+import (
+	"strings"
+	"time"
 
-type UserModel struct{}
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+var mockUser = &models.User{
+	ID: 1,
+	Name: "Alice",
+	Email: "test@example.com",
+	Role: models.RoleUser,
+	Created: time.Now(),
+}
+
+var mockModerator = &models.User{
+	ID: 2,
+	Name: "Bob",
+	Email: "moderator@example.com",
+	Role: models.RoleModerator,
+	Created: time.Now(),
+}
+
+var mockAdmin = &models.User{
+	ID: 3,
+	Name: "Carol",
+	Email: "admin@example.com",
+	Role: models.RoleAdmin,
+	Created: time.Now(),
+}
+
+// UserModel knows the three mock users above, who all have the
+// password "password" unless it is changed
+type UserModel struct {
+	// changes made through the model, by user id
+	Roles              map[int]models.Role
+	Disabled           map[int]bool
+	MustChangePassword map[int]bool
+	Passwords          map[int]string
+}
+
+func (m *UserModel) lookup(u *models.User) *models.User {
+	changed := *u
+	if role, ok := m.Roles[u.ID]; ok {
+		changed.Role = role
+	}
+	changed.Disabled = m.Disabled[u.ID]
+	changed.MustChangePassword = m.MustChangePassword[u.ID]
+	return &changed
+}
+
+func (m *UserModel) password(id int) string {
+	if password, ok := m.Passwords[id]; ok {
+		return password
+	}
+	return "password"
+}
 
 func (m *UserModel) Insert(name, email, password string) error {
 	switch email {
@@ -14,17 +68,113 @@ func (m *UserModel) Insert(name, email, password string) error {
 }
 
 func (m *UserModel) Authenticate(email, password string) (int, error) {
-	if email == "test@example.com" && password == "password" {
-		return 1, nil
+	u, err := m.GetByEmail(email)
+	if err != nil || password != m.password(u.ID) {
+		return 0, models.ErrInvalidCredentials
 	}
-	return 0, models.ErrInvalidCredentials
+	return u.ID, nil
 }
 
 func (m *UserModel) Exists(id int) (bool, error) {
 	switch id {
-		case 1:
+		case 1, 2, 3:
 			return true, nil
 		default:
 			return false, nil
 	}
 }
+
+func (m *UserModel) Get(id int) (*models.User, error) {
+	switch id {
+		case 1:
+			return m.lookup(mockUser), nil
+		case 2:
+			return m.lookup(mockModerator), nil
+		case 3:
+			return m.lookup(mockAdmin), nil
+		default:
+			return nil, models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) GetByEmail(email string) (*models.User, error) {
+	switch email {
+		case "test@example.com":
+			return m.lookup(mockUser), nil
+		case "moderator@example.com":
+			return m.lookup(mockModerator), nil
+		case "admin@example.com":
+			return m.lookup(mockAdmin), nil
+		default:
+			return nil, models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) Provision(name, email string) (int, error) {
+	return 1, nil
+}
+
+func (m *UserModel) List(search string) ([]*models.User, error) {
+	users := []*models.User{}
+	for _, u := range []*models.User{mockUser, mockModerator, mockAdmin} {
+		if strings.Contains(u.Name, search) || strings.Contains(u.Email, search) {
+			users = append(users, m.lookup(u))
+		}
+	}
+	return users, nil
+}
+
+func (m *UserModel) SetRole(id int, role models.Role) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	if m.Roles == nil {
+		m.Roles = map[int]models.Role{}
+	}
+	m.Roles[id] = role
+	return nil
+}
+
+func (m *UserModel) SetDisabled(id int, disabled bool) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	if m.Disabled == nil {
+		m.Disabled = map[int]bool{}
+	}
+	m.Disabled[id] = disabled
+	return nil
+}
+
+func (m *UserModel) RequirePasswordChange(id int) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	if m.MustChangePassword == nil {
+		m.MustChangePassword = map[int]bool{}
+	}
+	m.MustChangePassword[id] = true
+	return nil
+}
+
+func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	if currentPassword != m.password(id) {
+		return models.ErrInvalidCredentials
+	}
+	return m.PasswordSet(id, newPassword)
+}
+
+func (m *UserModel) PasswordSet(id int, newPassword string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	if m.Passwords == nil {
+		m.Passwords = map[int]string{}
+	}
+	m.Passwords[id] = newPassword
+	delete(m.MustChangePassword, id)
+	return nil
+}
@@ -0,0 +1,67 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// UserSessionModel keeps the logged in sessions in memory
+type UserSessionModel struct {
+	Sessions []*models.UserSession
+	nextID   int
+}
+
+func (m *UserSessionModel) Insert(userID int, token, series, ip, userAgent string) error {
+	m.nextID++
+	m.Sessions = append(m.Sessions, &models.UserSession{
+		ID: m.nextID,
+		UserID: userID,
+		Token: token,
+		Created: time.Now(),
+		LastSeen: time.Now(),
+		IP: ip,
+		UserAgent: userAgent,
+		Series: series,
+	})
+	return nil
+}
+
+func (m *UserSessionModel) Get(token string) (*models.UserSession, error) {
+	for _, s := range m.Sessions {
+		if s.Token == token {
+			return s, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *UserSessionModel) GetByUser(userID int) ([]*models.UserSession, error) {
+	sessions := []*models.UserSession{}
+	for _, s := range m.Sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *UserSessionModel) Touch(token, ip string) error {
+	for _, s := range m.Sessions {
+		if s.Token == token {
+			s.LastSeen = time.Now()
+			s.IP = ip
+		}
+	}
+	return nil
+}
+
+func (m *UserSessionModel) Delete(token string) error {
+	for i, s := range m.Sessions {
+		if s.Token == token {
+			m.Sessions = append(m.Sessions[:i], m.Sessions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
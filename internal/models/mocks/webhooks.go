@@ -0,0 +1,152 @@
+package mocks
+
+import (
+	"slices"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/models"
+)
+
+// WebhookModel keeps webhooks, their deliveries and the attempts at them
+// in memory, in the order they were added, with every delivery in Queue
+type WebhookModel struct {
+	Webhooks []*models.Webhook
+	Queue    []*models.WebhookDelivery
+}
+
+func (m *WebhookModel) Insert(userID int, url string, events []string) (int, error) {
+	h := &models.Webhook{
+		ID: 1,
+		UserID: userID,
+		URL: url,
+		Secret: models.NewWebhookSecret(),
+		Events: events,
+		Created: time.Now(),
+	}
+	if len(m.Webhooks) > 0 {
+		h.ID = m.Webhooks[len(m.Webhooks)-1].ID + 1
+	}
+	m.Webhooks = append(m.Webhooks, h)
+	return h.ID, nil
+}
+
+func (m *WebhookModel) Get(id int) (*models.Webhook, error) {
+	for _, h := range m.Webhooks {
+		if h.ID == id {
+			return h, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *WebhookModel) List(userID int) ([]*models.Webhook, error) {
+	webhooks := []*models.Webhook{}
+	for _, h := range m.Webhooks {
+		if h.UserID == userID {
+			webhooks = append(webhooks, h)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *WebhookModel) Delete(userID, id int) error {
+	for i, h := range m.Webhooks {
+		if h.ID == id && h.UserID == userID {
+			m.Webhooks = slices.Delete(m.Webhooks, i, i+1)
+			m.Queue = slices.DeleteFunc(m.Queue, func(d *models.WebhookDelivery) bool {
+				return d.WebhookID == id
+			})
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+
+func (m *WebhookModel) Subscribed(event string, ownerID int, public bool) ([]*models.Webhook, error) {
+	webhooks := []*models.Webhook{}
+	for _, h := range m.Webhooks {
+		if ((ownerID != 0 && h.UserID == ownerID) || (h.UserID == 0 && public)) && h.Subscribes(event) {
+			webhooks = append(webhooks, h)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *WebhookModel) Enqueue(webhookID int, event string, payload []byte) (int, error) {
+	d := &models.WebhookDelivery{
+		ID: 1,
+		WebhookID: webhookID,
+		Event: event,
+		Payload: payload,
+		Status: models.DeliveryPending,
+		NextAttempt: time.Now(),
+		Created: time.Now(),
+	}
+	if len(m.Queue) > 0 {
+		d.ID = m.Queue[len(m.Queue)-1].ID + 1
+	}
+	m.Queue = append(m.Queue, d)
+	return d.ID, nil
+}
+
+func (m *WebhookModel) Due(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	deliveries := []*models.WebhookDelivery{}
+	for _, d := range m.Queue {
+		if len(deliveries) == limit {
+			break
+		}
+		if d.Status != models.DeliveryPending || d.NextAttempt.After(now) {
+			continue
+		}
+		h, err := m.Get(d.WebhookID)
+		if err != nil {
+			continue
+		}
+		due := *d
+		due.URL, due.Secret, due.Global = h.URL, h.Secret, h.UserID == 0
+		deliveries = append(deliveries, &due)
+	}
+	return deliveries, nil
+}
+
+func (m *WebhookModel) RecordAttempt(a *models.WebhookAttempt, status string, next time.Time) error {
+	d, err := m.GetDelivery(a.DeliveryID)
+	if err != nil {
+		return err
+	}
+	d.AttemptLog = append(d.AttemptLog, a)
+	d.Attempts++
+	d.Status = status
+	d.NextAttempt = time.Time{}
+	if status == models.DeliveryPending {
+		d.NextAttempt = next
+	}
+	return nil
+}
+
+func (m *WebhookModel) Deliveries(webhookID, limit int) ([]*models.WebhookDelivery, error) {
+	deliveries := []*models.WebhookDelivery{}
+	for i := len(m.Queue) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if m.Queue[i].WebhookID == webhookID {
+			deliveries = append(deliveries, m.Queue[i])
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *WebhookModel) GetDelivery(id int) (*models.WebhookDelivery, error) {
+	for _, d := range m.Queue {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *WebhookModel) Redeliver(id int) (int, error) {
+	d, err := m.GetDelivery(id)
+	if err != nil {
+		return 0, err
+	}
+	return m.Enqueue(d.WebhookID, d.Event, d.Payload)
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// Passkey is a WebAuthn credential registered by a user, along with
+// the bookkeeping we need to show it on the management page
+type Passkey struct {
+	ID         int
+	UserID     int
+	Name       string
+	Credential webauthn.Credential
+	Created    time.Time
+	LastUsed   time.Time
+}
+
+type PasskeyModel struct {
+	DB *sql.DB
+}
+
+type PasskeyModelInterface interface {
+	Insert(userID int, name string, credential *webauthn.Credential) error
+	GetByUser(userID int) ([]*Passkey, error)
+	UpdateCredential(credential *webauthn.Credential) error
+	Delete(userID, id int) error
+}
+
+func (m *PasskeyModel) Insert(userID int, name string, credential *webauthn.Credential) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+
+	statement := `INSERT INTO passkeys (user_id, name, credential_id, credential, sign_count, created)
+	VALUES (?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err = m.DB.Exec(statement, userID, name, credential.ID, data, credential.Authenticator.SignCount)
+	return err
+}
+
+func (m *PasskeyModel) GetByUser(userID int) ([]*Passkey, error) {
+	statement := `SELECT id, user_id, name, credential, created, last_used FROM passkeys
+	WHERE user_id = ? ORDER BY created`
+
+	rows, err := m.DB.Query(statement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := []*Passkey{}
+	for rows.Next() {
+		p := &Passkey{}
+		var data []byte
+		var lastUsed sql.NullTime
+
+		err := rows.Scan(&p.ID, &p.UserID, &p.Name, &data, &p.Created, &lastUsed)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(data, &p.Credential)
+		if err != nil {
+			return nil, err
+		}
+		p.LastUsed = lastUsed.Time
+
+		passkeys = append(passkeys, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return passkeys, nil
+}
+
+// UpdateCredential() stores the credential after a successful assertion,
+// so that the new signature counter and flags are used for the next login
+func (m *PasskeyModel) UpdateCredential(credential *webauthn.Credential) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+
+	statement := `UPDATE passkeys SET credential = ?, sign_count = ?, last_used = UTC_TIMESTAMP()
+	WHERE credential_id = ?`
+
+	result, err := m.DB.Exec(statement, data, credential.Authenticator.SignCount, credential.ID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+func (m *PasskeyModel) Delete(userID, id int) error {
+	statement := "DELETE FROM passkeys WHERE id = ? AND user_id = ?"
+
+	result, err := m.DB.Exec(statement, id, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PersistentLogin is one token of a "remember me" series. The cookie
+// holds the selector, used to look the token up, and the validator, of
+// which we only store a hash. Every use rotates the token, so the series
+// is a chain of tokens of which only the newest is valid.
+type PersistentLogin struct {
+	Selector      string
+	Series        string
+	UserID        int
+	ValidatorHash []byte
+	Expires       time.Time
+	Rotated       bool
+}
+
+// Matches() reports whether the validator from a cookie belongs to the token
+func (p *PersistentLogin) Matches(validator string) bool {
+	hash := sha256.Sum256([]byte(validator))
+	return subtle.ConstantTimeCompare(hash[:], p.ValidatorHash) == 1
+}
+
+type PersistentLoginModel struct {
+	DB *sql.DB
+}
+
+type PersistentLoginModelInterface interface {
+	Insert(userID int, series, selector, validator string, expires time.Time) error
+	Get(selector string) (*PersistentLogin, error)
+	Rotate(selector string) error
+	DeleteSeries(series string) error
+	DeleteByUser(userID int) error
+}
+
+func (m *PersistentLoginModel) Insert(userID int, series, selector, validator string, expires time.Time) error {
+	hash := sha256.Sum256([]byte(validator))
+
+	statement := `INSERT INTO persistent_logins (selector, series, user_id, validator_hash, expires)
+	VALUES (?, ?, ?, ?, ?)`
+
+	_, err := m.DB.Exec(statement, selector, series, userID, hash[:], expires.UTC())
+	return err
+}
+
+func (m *PersistentLoginModel) Get(selector string) (*PersistentLogin, error) {
+	statement := `SELECT selector, series, user_id, validator_hash, expires, rotated FROM persistent_logins
+	WHERE selector = ?`
+
+	p := &PersistentLogin{}
+
+	err := m.DB.QueryRow(statement, selector).Scan(&p.Selector, &p.Series, &p.UserID, &p.ValidatorHash, &p.Expires, &p.Rotated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Rotate() marks the token as used. Rotated tokens are kept until the
+// series ends, so that a stolen copy being replayed can be recognised.
+func (m *PersistentLoginModel) Rotate(selector string) error {
+	statement := "UPDATE persistent_logins SET rotated = TRUE WHERE selector = ? AND NOT rotated"
+
+	result, err := m.DB.Exec(statement, selector)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+func (m *PersistentLoginModel) DeleteSeries(series string) error {
+	statement := "DELETE FROM persistent_logins WHERE series = ?"
+
+	_, err := m.DB.Exec(statement, series)
+	return err
+}
+
+func (m *PersistentLoginModel) DeleteByUser(userID int) error {
+	statement := "DELETE FROM persistent_logins WHERE user_id = ?"
+
+	_, err := m.DB.Exec(statement, userID)
+	return err
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Report is a visitor's complaint about a snippet, waiting in the
+// moderation queue until a moderator resolves it
+type Report struct {
+	ID         int
+	SnippetID  int
+	ReporterID int // 0 for an anonymous visitor
+	Reason     string
+	Details    string
+	Created    time.Time
+
+	// filled in by Open(), for the moderation page
+	SnippetTitle string
+}
+
+type ReportModel struct {
+	DB *sql.DB
+}
+
+type ReportModelInterface interface {
+	Insert(snippetID, reporterID int, reason, details string) (int, error)
+	Get(id int) (*Report, error)
+	Open() ([]*Report, error)
+	Resolve(snippetID, moderatorID int, resolution string) (int, error)
+}
+
+func (m *ReportModel) Insert(snippetID, reporterID int, reason, details string) (int, error) {
+	var reporter sql.NullInt64
+	if reporterID != 0 {
+		reporter = sql.NullInt64{Int64: int64(reporterID), Valid: true}
+	}
+
+	statement := `INSERT INTO reports (snippet_id, reporter_id, reason, details, created)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(statement, snippetID, reporter, reason, details)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Get() returns an open report
+func (m *ReportModel) Get(id int) (*Report, error) {
+	statement := `SELECT id, snippet_id, reporter_id, reason, details, created FROM reports
+	WHERE resolved IS NULL AND id = ?`
+
+	r := &Report{}
+	var reporterID sql.NullInt64
+
+	err := m.DB.QueryRow(statement, id).Scan(&r.ID, &r.SnippetID, &reporterID, &r.Reason, &r.Details, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+	r.ReporterID = int(reporterID.Int64)
+
+	return r, nil
+}
+
+// Open() returns the moderation queue, oldest report first
+func (m *ReportModel) Open() ([]*Report, error) {
+	statement := `SELECT r.id, r.snippet_id, r.reporter_id, r.reason, r.details, r.created, s.title
+	FROM reports r JOIN snippets s ON s.id = r.snippet_id
+	WHERE r.resolved IS NULL ORDER BY r.id`
+
+	rows, err := m.DB.Query(statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		r := &Report{}
+		var reporterID sql.NullInt64
+		err := rows.Scan(&r.ID, &r.SnippetID, &reporterID, &r.Reason, &r.Details, &r.Created, &r.SnippetTitle)
+		if err != nil {
+			return nil, err
+		}
+		r.ReporterID = int(reporterID.Int64)
+		reports = append(reports, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// Resolve() closes every open report about the snippet, since a decision
+// about the snippet answers all of them, and returns how many there were
+func (m *ReportModel) Resolve(snippetID, moderatorID int, resolution string) (int, error) {
+	statement := `UPDATE reports SET resolved = UTC_TIMESTAMP(), resolved_by = ?, resolution = ?
+	WHERE resolved IS NULL AND snippet_id = ?`
+
+	result, err := m.DB.Exec(statement, moderatorID, resolution, snippetID)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
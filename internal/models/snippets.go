@@ -3,15 +3,141 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID         int
+	UserID     int // 0 if the owner deleted their account
+	Title      string
+	Content    string
+	Language   string // one of Languages, "" for plain text
+	Visibility Visibility
+	Created    time.Time
+	Expires    time.Time
+	Hidden     bool // by a moderator, after it was reported
+}
+
+// Visibility is who can find a snippet
+type Visibility string
+
+const (
+	// VisibilityPublic snippets are listed and searchable
+	VisibilityPublic Visibility = "public"
+	// VisibilityUnlisted snippets can be seen by anyone with the link,
+	// but aren't listed
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPrivate snippets can only be seen by their owner
+	VisibilityPrivate Visibility = "private"
+)
+
+// Valid() reports whether v is one of the visibilities above
+func (v Visibility) Valid() bool {
+	return v == VisibilityPublic || v == VisibilityUnlisted || v == VisibilityPrivate
+}
+
+// Languages are the languages a snippet can be marked as written in
+var Languages = []string{
+	"bash", "c", "cpp", "csharp", "css", "diff", "go", "html", "java", "javascript", "json", "kotlin",
+	"markdown", "php", "python", "ruby", "rust", "sql", "swift", "toml", "typescript", "xml", "yaml",
+}
+
+// ValidLanguage() reports whether language is one of Languages, or ""
+// for plain text
+func ValidLanguage(language string) bool {
+	return language == "" || slices.Contains(Languages, language)
+}
+
+// MaxTags is how many tags a snippet can have
+const MaxTags = 5
+
+// tagRX is what a tag looks like once ParseTags() has lowercased it
+var tagRX = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidTag() reports whether tag is a single valid tag
+func ValidTag(tag string) bool {
+	return tagRX.MatchString(tag)
+}
+
+// ParseTags() splits the tags typed into a form, separated by commas or
+// spaces, into a sorted list without repeats. It reports false if any of
+// them isn't a valid tag, or there are more than MaxTags.
+func ParseTags(s string) ([]string, bool) {
+	tags := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	slices.Sort(tags)
+	tags = slices.Compact(tags)
+
+	for _, tag := range tags {
+		if !ValidTag(tag) {
+			return nil, false
+		}
+	}
+	return tags, len(tags) <= MaxTags
+}
+
+// extensions maps file extensions to languages
+var extensions = map[string]string{
+	".sh":    "bash",
+	".bash":  "bash",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".diff":  "diff",
+	".patch": "diff",
+	".go":    "go",
+	".html":  "html",
+	".htm":   "html",
+	".java":  "java",
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".json":  "json",
+	".kt":    "kotlin",
+	".md":    "markdown",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".sql":   "sql",
+	".swift": "swift",
+	".toml":  "toml",
+	".ts":    "typescript",
+	".xml":   "xml",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+// LanguageFor() guesses a file's language from its extension, returning ""
+// for plain text
+func LanguageFor(filename string) string {
+	return extensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// FirstLine() returns the first line of s that isn't blank, cut down to
+// the longest title a snippet can have
+func FirstLine(s string) string {
+	for line := range strings.Lines(s) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if utf8.RuneCountInString(line) > 100 {
+			line = string([]rune(line)[:100])
+		}
+		return line
+	}
+	return ""
 }
 
 type SnippetModel struct {
@@ -19,16 +145,41 @@ type SnippetModel struct {
 }
 
 type SnippetModelInterface interface {
-	Insert(title, content string, expires int) (int, error)
+	Insert(userID int, title, content, language string, visibility Visibility, expires int) (int, error)
 	Get(id int) (*Snippet, error)
 	Latest() ([]*Snippet, error)
+	LatestFor(userID int, language, tag string, limit int) ([]*Snippet, error)
+	Tags(id int) ([]string, error)
+	SetTags(id int, tags []string) error
+	Search(filter SnippetFilter) ([]*Snippet, error)
+	Page(query string, userID, limit, offset int) ([]*Snippet, int, error)
+	Delete(userID, id int) error
+	DeleteMany(ids []int) (int, error)
+	SetHidden(id int, hidden bool) error
+	Expired(from, to time.Time) ([]*Snippet, error)
+}
+
+// snippetColumns are the columns scanSnippet() reads
+const snippetColumns = "id, user_id, title, content, language, visibility, created, expires, hidden"
+
+func scanSnippet(row interface{ Scan(...any) error }) (*Snippet, error) {
+	s := &Snippet{}
+	var userID sql.NullInt64
+
+	err := row.Scan(&s.ID, &userID, &s.Title, &s.Content, &s.Language, &s.Visibility, &s.Created, &s.Expires, &s.Hidden)
+	if err != nil {
+		return nil, err
+	}
+	s.UserID = int(userID.Int64)
+
+	return s, nil
 }
 
-func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
-	statement := `INSERT INTO snippets (title, content, created, expires)
-	VALUES (?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+func (m *SnippetModel) Insert(userID int, title, content, language string, visibility Visibility, expires int) (int, error) {
+	statement := `INSERT INTO snippets (user_id, title, content, language, visibility, created, expires)
+	VALUES (?, ?, ?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
 
-	result, err := m.DB.Exec(statement, title, content, expires)
+	result, err := m.DB.Exec(statement, userID, title, content, language, visibility, expires)
 	if err != nil {
 		return 0, err
 	}
@@ -42,12 +193,9 @@ func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
 }
 
 func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	statement := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	statement := "SELECT " + snippetColumns + " FROM snippets WHERE expires > UTC_TIMESTAMP() AND id = ?"
 
-	s := &Snippet{}
-
-	err := m.DB.QueryRow(statement, id).Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	s, err := scanSnippet(m.DB.QueryRow(statement, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -59,9 +207,10 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 	return s, nil
 }
 
+// Latest() returns the 10 newest public snippets
 func (m *SnippetModel) Latest() ([]*Snippet, error) {
-	statement := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() ORDER BY created DESC LIMIT 10`
+	statement := "SELECT " + snippetColumns + ` FROM snippets
+	WHERE expires > UTC_TIMESTAMP() AND NOT hidden AND visibility = 'public' ORDER BY created DESC LIMIT 10`
 
 	rows, err := m.DB.Query(statement)
 	if err != nil {
@@ -71,8 +220,287 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 
 	snippets := []*Snippet{}
 	for rows.Next() {
-		s := &Snippet{}
-		err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// LatestFor() returns up to limit of the newest public snippets, for the
+// feeds. If userID isn't 0 they are only that user's, if language isn't
+// empty only those in it, and if tag isn't empty only those tagged with it.
+func (m *SnippetModel) LatestFor(userID int, language, tag string, limit int) ([]*Snippet, error) {
+	statement := "SELECT " + snippetColumns + ` FROM snippets
+	WHERE expires > UTC_TIMESTAMP() AND NOT hidden AND visibility = 'public'`
+	args := []any{}
+
+	if userID != 0 {
+		statement += " AND user_id = ?"
+		args = append(args, userID)
+	}
+	if language != "" {
+		statement += " AND language = ?"
+		args = append(args, language)
+	}
+	if tag != "" {
+		statement += " AND id IN (SELECT snippet_id FROM snippet_tags WHERE tag = ?)"
+		args = append(args, tag)
+	}
+
+	statement += " ORDER BY created DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := m.DB.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// Tags() returns a snippet's tags in alphabetical order
+func (m *SnippetModel) Tags(id int) ([]string, error) {
+	statement := "SELECT tag FROM snippet_tags WHERE snippet_id = ? ORDER BY tag"
+
+	rows, err := m.DB.Query(statement, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		err := rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// SetTags() replaces a snippet's tags, which should have been through
+// ParseTags()
+func (m *SnippetModel) SetTags(id int, tags []string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM snippet_tags WHERE snippet_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec("INSERT INTO snippet_tags (snippet_id, tag) VALUES (?, ?)", id, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SnippetFilter narrows down the snippets returned by Search()
+type SnippetFilter struct {
+	Search string // in the title or content
+	UserID int    // 0 for any owner
+	Status string // "active", "expired" or "" for both
+}
+
+// Search() returns the newest 100 snippets matching the filter, expired
+// ones included, for the admin console
+func (m *SnippetModel) Search(filter SnippetFilter) ([]*Snippet, error) {
+	statement := "SELECT " + snippetColumns + " FROM snippets WHERE (title LIKE ? OR content LIKE ?)"
+
+	pattern := "%" + escapeLike(filter.Search) + "%"
+	args := []any{pattern, pattern}
+
+	if filter.UserID != 0 {
+		statement += " AND user_id = ?"
+		args = append(args, filter.UserID)
+	}
+
+	switch filter.Status {
+	case "active":
+		statement += " AND expires > UTC_TIMESTAMP()"
+	case "expired":
+		statement += " AND expires <= UTC_TIMESTAMP()"
+	}
+
+	statement += " ORDER BY created DESC LIMIT 100"
+
+	rows, err := m.DB.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// Page() returns a page of the public snippets, newest first, with the
+// total number there are. If userID isn't 0 it is that user's snippets
+// instead, unlisted and private ones too. If query isn't empty only
+// snippets with it in the title or content are included.
+func (m *SnippetModel) Page(query string, userID, limit, offset int) ([]*Snippet, int, error) {
+	where := "WHERE expires > UTC_TIMESTAMP() AND NOT hidden"
+	args := []any{}
+
+	if userID != 0 {
+		where += " AND user_id = ?"
+		args = append(args, userID)
+	} else {
+		where += " AND visibility = 'public'"
+	}
+
+	if query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		where += " AND (title LIKE ? OR content LIKE ?)"
+		args = append(args, pattern, pattern)
+	}
+
+	var total int
+
+	err := m.DB.QueryRow("SELECT COUNT(*) FROM snippets "+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	statement := "SELECT " + snippetColumns + " FROM snippets " + where + " ORDER BY created DESC, id DESC LIMIT ? OFFSET ?"
+
+	rows, err := m.DB.Query(statement, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// Delete() deletes one of the user's snippets, returning ErrNoRecord if
+// they have no snippet with that id
+func (m *SnippetModel) Delete(userID, id int) error {
+	statement := "DELETE FROM snippets WHERE id = ? AND user_id = ?"
+
+	result, err := m.DB.Exec(statement, id, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// DeleteMany() deletes the snippets with the given ids and returns how
+// many there were
+func (m *SnippetModel) DeleteMany(ids []int) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	statement := "DELETE FROM snippets WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+
+	result, err := m.DB.Exec(statement, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// SetHidden() hides a snippet from everyone but moderators, or shows it
+// again
+func (m *SnippetModel) SetHidden(id int, hidden bool) error {
+	statement := "UPDATE snippets SET hidden = ? WHERE id = ?"
+
+	_, err := m.DB.Exec(statement, hidden, id)
+	return err
+}
+
+// Expired() returns the snippets that expired after from and by to, oldest
+// first
+func (m *SnippetModel) Expired(from, to time.Time) ([]*Snippet, error) {
+	statement := "SELECT " + snippetColumns + " FROM snippets WHERE expires > ? AND expires <= ? ORDER BY expires"
+
+	rows, err := m.DB.Query(statement, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s, err := scanSnippet(rows)
 		if err != nil {
 			return nil, err
 		}
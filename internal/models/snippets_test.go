@@ -0,0 +1,239 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+var snippetRowColumns = []string{"id", "user_id", "title", "content", "language", "visibility", "created", "expires", "hidden"}
+
+func TestSnippetModelPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &SnippetModel{DB: db}
+
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// the percent sign is escaped, so it only matches itself
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM snippets WHERE expires > UTC_TIMESTAMP() AND NOT hidden AND visibility = 'public' AND (title LIKE ? OR content LIKE ?)")).
+		WithArgs(`%100\%%`, `%100\%%`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+
+	rows := sqlmock.NewRows(snippetRowColumns).
+		AddRow(3, nil, "100% pure", "Haiku", "", "public", created, created.AddDate(0, 0, 7), false)
+
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY created DESC, id DESC LIMIT ? OFFSET ?")).
+		WithArgs(`%100\%%`, `%100\%%`, 10, 20).
+		WillReturnRows(rows)
+
+	snippets, total, err := m.Page("100%", 0, 10, 20)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+
+	assert.Equal(t, total, 21)
+	assert.Equal(t, len(snippets), 1)
+	assert.Equal(t, snippets[0].ID, 3)
+	assert.Equal(t, snippets[0].UserID, 0)
+}
+
+func TestSnippetModelPageByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &SnippetModel{DB: db}
+
+	created := time.Now()
+
+	// the owner sees their unlisted and private snippets too
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM snippets WHERE expires > UTC_TIMESTAMP() AND NOT hidden AND user_id = ?")).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("AND user_id = ? ORDER BY created DESC, id DESC LIMIT ? OFFSET ?")).
+		WithArgs(7, 20, 0).
+		WillReturnRows(sqlmock.NewRows(snippetRowColumns).
+			AddRow(4, 7, "Notes", "Private notes", "markdown", "private", created, created, false))
+
+	snippets, total, err := m.Page("", 7, 20, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+
+	assert.Equal(t, total, 1)
+	assert.Equal(t, snippets[0].Language, "markdown")
+	assert.Equal(t, snippets[0].Visibility, VisibilityPrivate)
+}
+
+func TestSnippetModelDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &SnippetModel{DB: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM snippets WHERE id = ? AND user_id = ?")).
+		WithArgs(4, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = m.Delete(7, 4)
+	assert.Equal(t, err, nil)
+
+	// someone else's snippet
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM snippets WHERE id = ? AND user_id = ?")).
+		WithArgs(4, 8).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = m.Delete(8, 4)
+	assert.Equal(t, err, ErrNoRecord)
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
+
+func TestLanguageFor(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "main.go", want: "go"},
+		{file: "dir/Script.PY", want: "python"},
+		{file: "config.yml", want: "yaml"},
+		{file: "notes.txt", want: ""},
+		{file: "Makefile", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert.Equal(t, LanguageFor(tt.file), tt.want)
+		})
+	}
+}
+
+// TestExtensions checks every guess is one of Languages
+func TestExtensions(t *testing.T) {
+	for ext, language := range extensions {
+		if !slices.Contains(Languages, language) {
+			t.Errorf("%s maps to %q, which isn't one of Languages", ext, language)
+		}
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	assert.Equal(t, FirstLine("\n  \n  Hello world  \nsecond"), "Hello world")
+	assert.Equal(t, FirstLine(""), "")
+	assert.Equal(t, FirstLine(strings.Repeat("é", 150)), strings.Repeat("é", 100))
+}
+
+func TestSnippetModelLatestFor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &SnippetModel{DB: db}
+
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE expires > UTC_TIMESTAMP() AND NOT hidden AND visibility = 'public' AND user_id = ? AND language = ? ORDER BY created DESC, id DESC LIMIT ?")).
+		WithArgs(4, "go", 20).
+		WillReturnRows(sqlmock.NewRows(snippetRowColumns).
+			AddRow(9, 4, "Hello", "package main", "go", "public", created, created.AddDate(0, 0, 7), false))
+
+	snippets, err := m.LatestFor(4, "go", "", 20)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snippets), 1)
+	assert.Equal(t, snippets[0].Language, "go")
+
+	// without a user or language every public snippet is included
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE expires > UTC_TIMESTAMP() AND NOT hidden AND visibility = 'public' ORDER BY created DESC, id DESC LIMIT ?")).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows(snippetRowColumns))
+
+	snippets, err = m.LatestFor(0, "", "", 20)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snippets), 0)
+
+	mock.ExpectQuery(regexp.QuoteMeta("AND visibility = 'public' AND id IN (SELECT snippet_id FROM snippet_tags WHERE tag = ?) ORDER BY created DESC, id DESC LIMIT ?")).
+		WithArgs("haiku", 20).
+		WillReturnRows(sqlmock.NewRows(snippetRowColumns).
+			AddRow(1, 4, "Pond", "An old silent pond...", "", "public", created, created.AddDate(0, 0, 7), false))
+
+	snippets, err = m.LatestFor(0, "", "haiku", 20)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snippets), 1)
+
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
+
+func TestSnippetModelSetTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &SnippetModel{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM snippet_tags WHERE snippet_id = ?")).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO snippet_tags (snippet_id, tag) VALUES (?, ?)")).
+		WithArgs(4, "go").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO snippet_tags (snippet_id, tag) VALUES (?, ?)")).
+		WithArgs(4, "http").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = m.SetTags(4, []string{"go", "http"})
+	assert.Equal(t, err, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT tag FROM snippet_tags WHERE snippet_id = ? ORDER BY tag")).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"tag"}).AddRow("go").AddRow("http"))
+
+	tags, err := m.Tags(4)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Join(tags, ","), "go,http")
+
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"Empty", "", "", true},
+		{"Commas and spaces", "go, http  web-dev", "go,http,web-dev", true},
+		{"Lowercased and without repeats", "Go,go GO", "go", true},
+		{"Too many", "a b c d e f", "", false},
+		{"Invalid", "c#", "", false},
+		{"Too long", strings.Repeat("x", 33), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, ok := ParseTags(tt.input)
+			assert.Equal(t, ok, tt.wantOK)
+			if ok {
+				assert.Equal(t, strings.Join(tags, ","), tt.want)
+			}
+		})
+	}
+}
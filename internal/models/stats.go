@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DayCount is the number of things that happened on a day
+type DayCount struct {
+	Day   time.Time
+	Count int
+}
+
+// Stats is the overview shown on the admin console
+type Stats struct {
+	Users           int
+	Snippets        int
+	ActiveUsersDay  int // users seen in the last 24 hours
+	ActiveUsersWeek int // users seen in the last 7 days
+	SnippetsPerDay  []DayCount
+}
+
+type StatsModel struct {
+	DB *sql.DB
+}
+
+type StatsModelInterface interface {
+	Get(days int) (*Stats, error)
+}
+
+// Get() gathers the totals and the number of snippets created on each of
+// the last few days
+func (m *StatsModel) Get(days int) (*Stats, error) {
+	stats := &Stats{}
+
+	statement := `SELECT
+		(SELECT COUNT(*) FROM users),
+		(SELECT COUNT(*) FROM snippets),
+		(SELECT COUNT(DISTINCT user_id) FROM user_sessions WHERE last_seen > UTC_TIMESTAMP() - INTERVAL 1 DAY),
+		(SELECT COUNT(DISTINCT user_id) FROM user_sessions WHERE last_seen > UTC_TIMESTAMP() - INTERVAL 7 DAY)`
+
+	err := m.DB.QueryRow(statement).Scan(&stats.Users, &stats.Snippets, &stats.ActiveUsersDay, &stats.ActiveUsersWeek)
+	if err != nil {
+		return nil, err
+	}
+
+	statement = `SELECT DATE(created) AS day, COUNT(*) FROM snippets
+	WHERE created > UTC_DATE() - INTERVAL ? DAY
+	GROUP BY day ORDER BY day DESC`
+
+	rows, err := m.DB.Query(statement, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DayCount
+		err := rows.Scan(&d.Day, &d.Count)
+		if err != nil {
+			return nil, err
+		}
+		stats.SnippetsPerDay = append(stats.SnippetsPerDay, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
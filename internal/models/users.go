@@ -1,6 +1,7 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"errors"
 	"strings"
@@ -15,7 +16,41 @@ type User struct {
 	Name           string
 	Email          string
 	HashedPassword string
+	Role           Role
 	Created        time.Time
+
+	// set by an admin: a disabled user can't log in, and a user who must
+	// change their password can't do anything else until they have
+	Disabled           bool
+	MustChangePassword bool
+}
+
+// Role is what a user is allowed to do. Each role includes everything
+// the roles below it may do.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// Includes() reports whether a user with role r may do what role other may
+func (r Role) Includes(other Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[other]
+}
+
+// Valid() reports whether r is one of the roles above
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
 }
 
 type UserModel struct {
@@ -26,6 +61,15 @@ type UserModelInterface interface {
 	Insert(name, email, password string) error
 	Authenticate(email, password string) (int, error)
 	Exists(id int) (bool, error)
+	Get(id int) (*User, error)
+	GetByEmail(email string) (*User, error)
+	Provision(name, email string) (int, error)
+	List(search string) ([]*User, error)
+	SetRole(id int, role Role) error
+	SetDisabled(id int, disabled bool) error
+	RequirePasswordChange(id int) error
+	PasswordUpdate(id int, currentPassword, newPassword string) error
+	PasswordSet(id int, newPassword string) error
 }
 
 func (m *UserModel) Insert(name, email, password string) error {
@@ -85,3 +129,187 @@ func (m *UserModel) Exists(id int) (bool, error) {
 	err := m.DB.QueryRow(statement, id).Scan(&exists)
 	return exists, err
 }
+
+func (m *UserModel) Get(id int) (*User, error) {
+	statement := `SELECT id, name, email, role, created, disabled, must_change_password FROM users
+	WHERE id = ?`
+
+	u := &User{}
+
+	err := m.DB.QueryRow(statement, id).Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Created, &u.Disabled, &u.MustChangePassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+func (m *UserModel) GetByEmail(email string) (*User, error) {
+	statement := `SELECT id, name, email, role, created, disabled, must_change_password FROM users
+	WHERE email = ?`
+
+	u := &User{}
+
+	err := m.DB.QueryRow(statement, email).Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Created, &u.Disabled, &u.MustChangePassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// Provision() returns the id of the user with the given email, creating
+// the user if they don't exist yet. It is used when an external identity
+// provider vouches for the email address, so the created account gets a
+// random password that nobody knows.
+func (m *UserModel) Provision(name, email string) (int, error) {
+	var id int
+
+	statement := "SELECT id FROM users WHERE email = ?"
+
+	err := m.DB.QueryRow(statement, email).Scan(&id)
+	if err == nil {
+		return id, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(rand.Text()), 12)
+	if err != nil {
+		return 0, err
+	}
+
+	statement = `INSERT INTO users (name, email, hashed_password, created) VALUES (?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(statement, name, email, string(hashedPassword))
+	if err != nil {
+		return 0, err
+	}
+
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(newID), nil
+}
+
+// List() returns the users whose name or email contains search, or the
+// first 100 users if search is empty
+func (m *UserModel) List(search string) ([]*User, error) {
+	statement := `SELECT id, name, email, role, created, disabled, must_change_password FROM users
+	WHERE name LIKE ? OR email LIKE ? ORDER BY created LIMIT 100`
+
+	pattern := "%" + escapeLike(search) + "%"
+
+	rows, err := m.DB.Query(statement, pattern, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		u := &User{}
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Created, &u.Disabled, &u.MustChangePassword)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (m *UserModel) SetRole(id int, role Role) error {
+	return m.update(id, "UPDATE users SET role = ? WHERE id = ?", role)
+}
+
+func (m *UserModel) SetDisabled(id int, disabled bool) error {
+	return m.update(id, "UPDATE users SET disabled = ? WHERE id = ?", disabled)
+}
+
+// RequirePasswordChange() makes the user choose a new password the next
+// time they log in
+func (m *UserModel) RequirePasswordChange(id int) error {
+	return m.update(id, "UPDATE users SET must_change_password = TRUE WHERE id = ?")
+}
+
+func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string) error {
+	var hashedPassword []byte
+
+	statement := "SELECT hashed_password FROM users WHERE id = ?"
+
+	err := m.DB.QueryRow(statement, id).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(currentPassword))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	return m.PasswordSet(id, newPassword)
+}
+
+// PasswordSet() changes the user's password without checking the current
+// one, for users who have confirmed who they are some other way
+func (m *UserModel) PasswordSet(id int, newPassword string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+	if err != nil {
+		return err
+	}
+
+	return m.update(id, "UPDATE users SET hashed_password = ?, must_change_password = FALSE WHERE id = ?", string(hashedPassword))
+}
+
+// update() runs an UPDATE of the user with the given id, which is passed
+// after args, returning ErrNoRecord if there is no such user
+func (m *UserModel) update(id int, statement string, args ...any) error {
+	result, err := m.DB.Exec(statement, append(args, id)...)
+	if err != nil {
+		return err
+	}
+
+	// MySQL doesn't count rows that already had the new values, so
+	// check whether nothing changed because the user doesn't exist
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		exists, err := m.Exists(id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNoRecord
+		}
+	}
+
+	return nil
+}
+
+// escapeLike() escapes the wildcards in s for use in a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+func TestRoleIncludes(t *testing.T) {
+	tests := []struct {
+		role  Role
+		other Role
+		want  bool
+	}{
+		{role: RoleAdmin, other: RoleModerator, want: true},
+		{role: RoleAdmin, other: RoleAdmin, want: true},
+		{role: RoleModerator, other: RoleUser, want: true},
+		{role: RoleModerator, other: RoleAdmin, want: false},
+		{role: RoleUser, other: RoleModerator, want: false},
+		{role: "", other: RoleUser, want: false},
+		{role: "superuser", other: RoleUser, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+" includes "+string(tt.other), func(t *testing.T) {
+			assert.Equal(t, tt.role.Includes(tt.other), tt.want)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// UserSession links a session in the scs sessions table to the user
+// logged in with it, so that users can see their devices and revoke them
+type UserSession struct {
+	ID        int
+	UserID    int
+	Token     string
+	Created   time.Time
+	LastSeen  time.Time
+	IP        string
+	UserAgent string
+
+	// the "remember me" series that logged the session in, if any
+	Series string
+}
+
+type UserSessionModel struct {
+	DB *sql.DB
+}
+
+type UserSessionModelInterface interface {
+	Insert(userID int, token, series, ip, userAgent string) error
+	Get(token string) (*UserSession, error)
+	GetByUser(userID int) ([]*UserSession, error)
+	Touch(token, ip string) error
+	Delete(token string) error
+}
+
+func (m *UserSessionModel) Insert(userID int, token, series, ip, userAgent string) error {
+	// forget the user's sessions that have expired or been destroyed
+	// since, as scs cleans up its own table but knows nothing of ours
+	statement := `DELETE us FROM user_sessions us
+	LEFT JOIN sessions s ON s.token = us.token
+	WHERE us.user_id = ? AND (s.token IS NULL OR s.expiry < UTC_TIMESTAMP(6))`
+
+	_, err := m.DB.Exec(statement, userID)
+	if err != nil {
+		return err
+	}
+
+	userAgent = truncate(userAgent, 255)
+
+	statement = `INSERT INTO user_sessions (user_id, token, series, created, last_seen, ip, user_agent)
+	VALUES (?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP(), ?, ?)`
+
+	_, err = m.DB.Exec(statement, userID, token, series, ip, userAgent)
+	return err
+}
+
+func (m *UserSessionModel) Get(token string) (*UserSession, error) {
+	statement := `SELECT id, user_id, token, series, created, last_seen, ip, user_agent FROM user_sessions
+	WHERE token = ?`
+
+	s := &UserSession{}
+
+	err := m.DB.QueryRow(statement, token).Scan(&s.ID, &s.UserID, &s.Token, &s.Series, &s.Created, &s.LastSeen, &s.IP, &s.UserAgent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// GetByUser() returns the user's sessions that haven't expired yet,
+// most recently used first
+func (m *UserSessionModel) GetByUser(userID int) ([]*UserSession, error) {
+	statement := `SELECT us.id, us.user_id, us.token, us.series, us.created, us.last_seen, us.ip, us.user_agent
+	FROM user_sessions us
+	JOIN sessions s ON s.token = us.token
+	WHERE us.user_id = ? AND s.expiry > UTC_TIMESTAMP(6)
+	ORDER BY us.last_seen DESC`
+
+	rows, err := m.DB.Query(statement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*UserSession{}
+	for rows.Next() {
+		s := &UserSession{}
+
+		err := rows.Scan(&s.ID, &s.UserID, &s.Token, &s.Series, &s.Created, &s.LastSeen, &s.IP, &s.UserAgent)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Touch() records that the session was just used. It only writes once a
+// minute, so that every request doesn't cost a database write.
+func (m *UserSessionModel) Touch(token, ip string) error {
+	statement := `UPDATE user_sessions SET last_seen = UTC_TIMESTAMP(), ip = ?
+	WHERE token = ? AND last_seen < UTC_TIMESTAMP() - INTERVAL 1 MINUTE`
+
+	_, err := m.DB.Exec(statement, ip, token)
+	return err
+}
+
+func (m *UserSessionModel) Delete(token string) error {
+	statement := "DELETE FROM user_sessions WHERE token = ?"
+
+	_, err := m.DB.Exec(statement, token)
+	return err
+}
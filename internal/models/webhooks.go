@@ -0,0 +1,435 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// the events a webhook can subscribe to
+const (
+	EventSnippetCreated = "snippet.created"
+	// EventSnippetUpdated is sent when a moderator hides a snippet, as
+	// snippets can't be edited
+	EventSnippetUpdated = "snippet.updated"
+	EventSnippetDeleted = "snippet.deleted"
+	EventSnippetExpired = "snippet.expired"
+)
+
+// WebhookEvents lists every event, in order
+var WebhookEvents = []string{EventSnippetCreated, EventSnippetUpdated, EventSnippetDeleted, EventSnippetExpired}
+
+// the states of a delivery
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a URL that is sent events about snippets. A user's webhooks
+// hear about their own snippets; global ones, which admins set up, hear
+// about every public snippet.
+type Webhook struct {
+	ID      int
+	UserID  int // 0 for a global webhook
+	URL     string
+	Secret  string // signs the deliveries, so the receiver can check them
+	Events  []string
+	Created time.Time
+}
+
+// Subscribes() reports whether the webhook wants the event
+func (h *Webhook) Subscribes(event string) bool {
+	return slices.Contains(h.Events, event)
+}
+
+// WebhookDelivery is one event queued for one webhook, which is sent until
+// it succeeds or runs out of attempts
+type WebhookDelivery struct {
+	ID          int
+	WebhookID   int
+	Event       string
+	Payload     []byte
+	Status      string
+	Attempts    int
+	NextAttempt time.Time // zero once the delivery is finished
+	Created     time.Time
+
+	// the webhook's details, filled in by Due()
+	URL    string
+	Secret string
+	Global bool
+
+	// the attempts so far, oldest first, filled in by Deliveries()
+	AttemptLog []*WebhookAttempt
+}
+
+// WebhookAttempt is one try at sending a delivery, and what came back
+type WebhookAttempt struct {
+	ID         int
+	DeliveryID int
+	Attempted  time.Time
+	StatusCode int    // 0 if there was no response
+	Response   string // the start of the response body
+	Error      string // why there was no response
+	Duration   time.Duration
+}
+
+// Succeeded() reports whether the receiver accepted the delivery
+func (a *WebhookAttempt) Succeeded() bool {
+	return a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+type WebhookModelInterface interface {
+	Insert(userID int, url string, events []string) (int, error)
+	Get(id int) (*Webhook, error)
+	List(userID int) ([]*Webhook, error)
+	Delete(userID, id int) error
+	Subscribed(event string, ownerID int, public bool) ([]*Webhook, error)
+	Enqueue(webhookID int, event string, payload []byte) (int, error)
+	Due(now time.Time, limit int) ([]*WebhookDelivery, error)
+	RecordAttempt(a *WebhookAttempt, status string, next time.Time) error
+	Deliveries(webhookID, limit int) ([]*WebhookDelivery, error)
+	GetDelivery(id int) (*WebhookDelivery, error)
+	Redeliver(id int) (int, error)
+}
+
+// NewWebhookSecret() returns a new random signing secret
+func NewWebhookSecret() string {
+	return "whsec_" + rand.Text()
+}
+
+// nullUserID() stores a global webhook's user id of 0 as NULL
+func nullUserID(userID int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(userID), Valid: userID != 0}
+}
+
+// Insert() adds a webhook for the user, or a global one if userID is 0,
+// with a new signing secret
+func (m *WebhookModel) Insert(userID int, url string, events []string) (int, error) {
+	statement := `INSERT INTO webhooks (user_id, url, secret, events, created)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(statement, nullUserID(userID), url, NewWebhookSecret(), strings.Join(events, ","))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// webhookColumns are the columns scanWebhook() reads
+const webhookColumns = "id, user_id, url, secret, events, created"
+
+func scanWebhook(row interface{ Scan(...any) error }) (*Webhook, error) {
+	h := &Webhook{}
+	var userID sql.NullInt64
+	var events string
+
+	err := row.Scan(&h.ID, &userID, &h.URL, &h.Secret, &events, &h.Created)
+	if err != nil {
+		return nil, err
+	}
+
+	h.UserID = int(userID.Int64)
+	h.Events = strings.Split(events, ",")
+
+	return h, nil
+}
+
+func (m *WebhookModel) Get(id int) (*Webhook, error) {
+	statement := "SELECT " + webhookColumns + " FROM webhooks WHERE id = ?"
+
+	h, err := scanWebhook(m.DB.QueryRow(statement, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// List() returns the user's webhooks, or the global ones if userID is 0,
+// oldest first
+func (m *WebhookModel) List(userID int) ([]*Webhook, error) {
+	statement := "SELECT " + webhookColumns + " FROM webhooks WHERE user_id <=> ? ORDER BY id"
+
+	return m.query(statement, nullUserID(userID))
+}
+
+func (m *WebhookModel) query(statement string, args ...any) ([]*Webhook, error) {
+	rows, err := m.DB.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Delete() removes one of the user's webhooks, or a global one if userID
+// is 0, along with its deliveries. It returns ErrNoRecord if there is no
+// such webhook.
+func (m *WebhookModel) Delete(userID, id int) error {
+	statement := "DELETE FROM webhooks WHERE id = ? AND user_id <=> ?"
+
+	result, err := m.DB.Exec(statement, id, nullUserID(userID))
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Subscribed() returns the webhooks that want to hear about an event on a
+// snippet: the owner's, and the global ones if the snippet is public
+func (m *WebhookModel) Subscribed(event string, ownerID int, public bool) ([]*Webhook, error) {
+	statement := "SELECT " + webhookColumns + ` FROM webhooks
+	WHERE (user_id = ? OR (user_id IS NULL AND ?)) AND FIND_IN_SET(?, events) > 0 ORDER BY id`
+
+	return m.query(statement, ownerID, public, event)
+}
+
+// Enqueue() queues a delivery of the payload to the webhook, to be sent
+// straight away
+func (m *WebhookModel) Enqueue(webhookID int, event string, payload []byte) (int, error) {
+	statement := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt, created)
+	VALUES (?, ?, ?, 'pending', 0, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(statement, webhookID, event, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// deliveryColumns are the columns scanDelivery() reads
+const deliveryColumns = "d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt, d.created"
+
+func scanDelivery(row interface{ Scan(...any) error }, extra ...any) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	var next sql.NullTime
+
+	err := row.Scan(append([]any{&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &next, &d.Created}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	d.NextAttempt = next.Time
+	return d, nil
+}
+
+// Due() returns up to limit pending deliveries whose next attempt is due,
+// with the URL and secret of their webhook
+func (m *WebhookModel) Due(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	statement := "SELECT " + deliveryColumns + `, w.url, w.secret, w.user_id IS NULL
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	WHERE d.status = 'pending' AND d.next_attempt <= ? ORDER BY d.next_attempt, d.id LIMIT ?`
+
+	rows, err := m.DB.Query(statement, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		var url, secret string
+		var global bool
+
+		d, err := scanDelivery(rows, &url, &secret, &global)
+		if err != nil {
+			return nil, err
+		}
+		d.URL, d.Secret, d.Global = url, secret, global
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt() saves an attempt at a delivery, and moves the delivery
+// on to the given status. A pending delivery is next tried at next.
+func (m *WebhookModel) RecordAttempt(a *WebhookAttempt, status string, next time.Time) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement := `INSERT INTO webhook_attempts (delivery_id, attempted, status_code, response, error, duration_ms)
+	VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.Exec(statement, a.DeliveryID, a.Attempted.UTC(), a.StatusCode, a.Response, a.Error, a.Duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	nextAttempt := sql.NullTime{Time: next.UTC(), Valid: status == DeliveryPending}
+
+	statement = "UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, next_attempt = ? WHERE id = ?"
+
+	_, err = tx.Exec(statement, status, nextAttempt, a.DeliveryID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Deliveries() returns the webhook's latest deliveries, newest first, each
+// with its attempts
+func (m *WebhookModel) Deliveries(webhookID, limit int) ([]*WebhookDelivery, error) {
+	statement := "SELECT " + deliveryColumns + " FROM webhook_deliveries d WHERE d.webhook_id = ? ORDER BY d.id DESC LIMIT ?"
+
+	rows, err := m.DB.Query(statement, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	byID := map[int]*WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+		byID[d.ID] = d
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	args := make([]any, len(deliveries))
+	for i, d := range deliveries {
+		args[i] = d.ID
+	}
+
+	statement = `SELECT id, delivery_id, attempted, status_code, response, error, duration_ms FROM webhook_attempts
+	WHERE delivery_id IN (?` + strings.Repeat(", ?", len(args)-1) + ") ORDER BY id"
+
+	rows, err = m.DB.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := &WebhookAttempt{}
+		var ms int64
+
+		err := rows.Scan(&a.ID, &a.DeliveryID, &a.Attempted, &a.StatusCode, &a.Response, &a.Error, &ms)
+		if err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(ms) * time.Millisecond
+
+		d := byID[a.DeliveryID]
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// GetDelivery() returns a delivery, without its attempts
+func (m *WebhookModel) GetDelivery(id int) (*WebhookDelivery, error) {
+	statement := "SELECT " + deliveryColumns + " FROM webhook_deliveries d WHERE d.id = ?"
+
+	d, err := scanDelivery(m.DB.QueryRow(statement, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// Redeliver() queues a new delivery with the same payload as an earlier
+// one, returning its id
+func (m *WebhookModel) Redeliver(id int) (int, error) {
+	statement := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt, created)
+	SELECT webhook_id, event, payload, 'pending', 0, UTC_TIMESTAMP(), UTC_TIMESTAMP() FROM webhook_deliveries WHERE id = ?`
+
+	result, err := m.DB.Exec(statement, id)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrNoRecord
+	}
+
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(newID), nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+func TestWebhookModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &WebhookModel{DB: db}
+
+	// a global webhook has no user
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhooks")).
+		WithArgs(sql.NullInt64{}, "https://example.com/hook", sqlmock.AnyArg(), "snippet.created,snippet.deleted").
+		WillReturnResult(sqlmock.NewResult(4, 1))
+
+	id, err := m.Insert(0, "https://example.com/hook", []string{EventSnippetCreated, EventSnippetDeleted})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, id, 4)
+
+	columns := []string{"id", "user_id", "url", "secret", "events", "created"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE (user_id = ? OR (user_id IS NULL AND ?)) AND FIND_IN_SET(?, events) > 0")).
+		WithArgs(1, true, EventSnippetDeleted).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, 1, "https://example.com/mine", "whsec_a", "snippet.deleted", time.Now()).
+			AddRow(4, nil, "https://example.com/hook", "whsec_b", "snippet.created,snippet.deleted", time.Now()))
+
+	webhooks, err := m.Subscribed(EventSnippetDeleted, 1, true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(webhooks), 2)
+	assert.Equal(t, webhooks[0].UserID, 1)
+	assert.Equal(t, webhooks[1].UserID, 0)
+	assert.Equal(t, webhooks[1].Subscribes(EventSnippetCreated), true)
+	assert.Equal(t, webhooks[1].Subscribes(EventSnippetExpired), false)
+
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE d.status = 'pending' AND d.next_attempt <= ?")).
+		WithArgs(now.UTC(), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event", "payload", "status", "attempts", "next_attempt", "created", "url", "secret", "global"}).
+			AddRow(7, 4, EventSnippetCreated, []byte(`{}`), DeliveryPending, 1, now, now, "https://example.com/hook", "whsec_b", true))
+
+	due, err := m.Due(now, 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(due), 1)
+	assert.Equal(t, due[0].URL, "https://example.com/hook")
+	assert.Equal(t, due[0].Global, true)
+
+	// the attempt and the delivery's new state are saved together, and a
+	// finished delivery has no next attempt
+	a := &WebhookAttempt{DeliveryID: 7, Attempted: now, StatusCode: 204, Duration: 30 * time.Millisecond}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_attempts")).
+		WithArgs(7, now.UTC(), 204, "", "", int64(30)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, next_attempt = ? WHERE id = ?")).
+		WithArgs(DeliverySucceeded, sql.NullTime{Time: time.Time{}.UTC()}, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Equal(t, m.RecordAttempt(a, DeliverySucceeded, time.Time{}), nil)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt, created)\n\tSELECT")).
+		WithArgs(99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = m.Redeliver(99)
+	assert.Equal(t, err, ErrNoRecord)
+
+	// users can only delete their own webhooks
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM webhooks WHERE id = ? AND user_id <=> ?")).
+		WithArgs(4, sql.NullInt64{Int64: 2, Valid: true}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Equal(t, m.Delete(2, 4), ErrNoRecord)
+
+	assert.Equal(t, mock.ExpectationsWereMet(), nil)
+}
+
+func TestNewWebhookSecret(t *testing.T) {
+	secret := NewWebhookSecret()
+	assert.Equal(t, strings.HasPrefix(secret, "whsec_"), true)
+	assert.Equal(t, secret != NewWebhookSecret(), true)
+}
@@ -0,0 +1,376 @@
+// Package pow issues and checks proof-of-work challenges, which make bots
+// pay for every form they submit without sending visitors to a third-party
+// CAPTCHA service.
+//
+// A challenge is a token signed by the server. The browser searches for a
+// solution such that the SHA-256 hash of "<token>:<solution>" starts with
+// as many zero bits as the token's difficulty asks for, which takes a
+// fraction of a second for one form but adds up for a bot sending
+// thousands. The difficulty goes up with the number of challenges being
+// checked, so a flood of submissions slows itself down.
+//
+// Browsers without JavaScript answer a simple arithmetic question instead,
+// which is only accepted some time after the challenge was issued. Answers
+// cost a bot nothing but that wait, so it doubles with each bit the
+// difficulty has been raised by, and each answer counts as AnswerWeight
+// checked challenges towards raising it.
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalid  = errors.New("pow: invalid challenge")
+	ErrExpired  = errors.New("pow: challenge expired")
+	ErrSpent    = errors.New("pow: challenge already used")
+	ErrUnsolved = errors.New("pow: challenge not solved")
+	ErrTooSoon  = errors.New("pow: challenge answered too soon")
+)
+
+// Challenge is sent to the browser with a form
+type Challenge struct {
+	Token      string
+	Difficulty int
+	// Question is asked of browsers that can't run the solver
+	Question string
+}
+
+// Issuer issues challenges and checks their solutions. The zero values of
+// the optional fields pick sensible defaults. It is safe for concurrent
+// use.
+type Issuer struct {
+	// Key signs the tokens, and should be at least 32 random bytes
+	Key []byte
+	// TTL is how long a challenge can be solved for
+	TTL time.Duration
+	// Difficulty is the number of leading zero bits a solution needs when
+	// the site is quiet, and MaxDifficulty the most it is raised to
+	Difficulty    int
+	MaxDifficulty int
+	// Surge is the number of challenges checked in a minute which raises
+	// the difficulty by one bit, doubling the work
+	Surge int
+	// MinAge is how long after it was issued a question can be answered
+	// while the site is quiet
+	MinAge time.Duration
+	// AnswerWeight is how many solved challenges an answered question
+	// counts as, towards the Surge
+	AnswerWeight int
+
+	// now can be replaced in tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	spent   map[string]time.Time
+	window  time.Time
+	checked int
+	// previous is the number checked in the last full minute
+	previous int
+}
+
+const (
+	defaultTTL           = 10 * time.Minute
+	defaultDifficulty    = 16
+	defaultMaxDifficulty = 22
+	defaultSurge         = 60
+	defaultMinAge        = 3 * time.Second
+	defaultAnswerWeight  = 4
+)
+
+// Issue() returns a new challenge at the current difficulty
+func (i *Issuer) Issue() Challenge {
+	nonce := make([]byte, 12)
+	rand.Read(nonce)
+
+	difficulty := i.difficulty()
+	payload := fmt.Sprintf("%d:%d:%s", i.clock().Unix(), difficulty, base64.RawURLEncoding.EncodeToString(nonce))
+
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(i.sign("token", payload))
+
+	a, b := i.operands(payload)
+
+	return Challenge{
+		Token:      token,
+		Difficulty: difficulty,
+		Question:   fmt.Sprintf("What is %s plus %s?", numbers[a], numbers[b]),
+	}
+}
+
+// Verify() checks a challenge has been solved, either with a proof-of-work
+// solution or, failing that, the answer to its question. A challenge can
+// only be used once.
+func (i *Issuer) Verify(token, solution, answer string) error {
+	payload, err := i.open(token)
+	if err != nil {
+		return err
+	}
+
+	issued, difficulty, _ := parsePayload(payload)
+	now := i.clock()
+
+	if now.After(issued.Add(i.ttl())) {
+		return ErrExpired
+	}
+
+	weight := 1
+
+	switch {
+	case solution != "":
+		if leadingZeros(hash(token, solution)) < difficulty {
+			return ErrUnsolved
+		}
+	case answer != "":
+		if now.Before(issued.Add(i.AnswerWait(difficulty))) {
+			return ErrTooSoon
+		}
+		a, b := i.operands(payload)
+		if !correctAnswer(strings.TrimSpace(answer), a+b) {
+			return ErrUnsolved
+		}
+		weight = i.answerWeight()
+	default:
+		return ErrUnsolved
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.count(now, weight)
+
+	if i.spent == nil {
+		i.spent = make(map[string]time.Time)
+	}
+	if _, ok := i.spent[token]; ok {
+		return ErrSpent
+	}
+
+	// forget tokens once they could no longer be used anyway
+	for t, expires := range i.spent {
+		if now.After(expires) {
+			delete(i.spent, t)
+		}
+	}
+	i.spent[token] = issued.Add(i.ttl())
+
+	return nil
+}
+
+// Solve() finds a solution to a challenge token by brute force, the same
+// way the browser does. It is used by the tests, and by anyone scripting
+// against the site who is willing to pay for it.
+func Solve(token string) (string, error) {
+	payload, err := decodePayload(token)
+	if err != nil {
+		return "", err
+	}
+
+	_, difficulty, err := parsePayload(payload)
+	if err != nil {
+		return "", err
+	}
+
+	for n := 0; ; n++ {
+		solution := strconv.Itoa(n)
+		if leadingZeros(hash(token, solution)) >= difficulty {
+			return solution, nil
+		}
+	}
+}
+
+// difficulty() returns the number of zero bits new challenges ask for,
+// going up by one for each Surge challenges checked in the busier of this
+// minute and the last
+func (i *Issuer) difficulty() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.count(time.Time{}, 0)
+
+	base := i.Difficulty
+	if base == 0 {
+		base = defaultDifficulty
+	}
+	most := i.MaxDifficulty
+	if most == 0 {
+		most = defaultMaxDifficulty
+	}
+	surge := i.Surge
+	if surge == 0 {
+		surge = defaultSurge
+	}
+
+	return min(base+max(i.checked, i.previous)/surge, max(base, most))
+}
+
+// count() moves the load window on to the current minute and, unless now
+// is zero, counts weight challenges checked at now. The caller holds mu.
+func (i *Issuer) count(now time.Time, weight int) {
+	current := i.clock().Truncate(time.Minute)
+
+	if !current.Equal(i.window) {
+		if current.Sub(i.window) == time.Minute {
+			i.previous = i.checked
+		} else {
+			i.previous = 0
+		}
+		i.window = current
+		i.checked = 0
+	}
+
+	if !now.IsZero() {
+		i.checked += weight
+	}
+}
+
+// open() checks the token's signature and returns its payload
+func (i *Issuer) open(token string) (string, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	if !hmac.Equal(mac, i.sign("token", string(payload))) {
+		return "", ErrInvalid
+	}
+
+	if _, _, err := parsePayload(string(payload)); err != nil {
+		return "", err
+	}
+
+	return string(payload), nil
+}
+
+func (i *Issuer) sign(purpose, payload string) []byte {
+	mac := hmac.New(sha256.New, i.Key)
+	mac.Write([]byte(purpose + ":" + payload))
+	return mac.Sum(nil)
+}
+
+// operands() derives the numbers in a challenge's question from its
+// signed payload, so the answer doesn't need to be stored or sent
+func (i *Issuer) operands(payload string) (int, int) {
+	sum := i.sign("question", payload)
+	return 1 + int(sum[0])%9, 1 + int(sum[1])%9
+}
+
+func (i *Issuer) clock() time.Time {
+	if i.now != nil {
+		return i.now()
+	}
+	return time.Now()
+}
+
+func (i *Issuer) ttl() time.Duration {
+	if i.TTL == 0 {
+		return defaultTTL
+	}
+	return i.TTL
+}
+
+// AnswerWait() returns how long after it was issued the question of a
+// challenge of the given difficulty can be answered: MinAge at the quiet
+// difficulty, doubling for each bit above it, but never so long the
+// challenge expires first
+func (i *Issuer) AnswerWait(difficulty int) time.Duration {
+	wait := i.MinAge
+	if wait == 0 {
+		wait = defaultMinAge
+	}
+
+	base := i.Difficulty
+	if base == 0 {
+		base = defaultDifficulty
+	}
+
+	for range max(0, difficulty-base) {
+		wait *= 2
+		if wait >= i.ttl()/2 {
+			return i.ttl() / 2
+		}
+	}
+	return wait
+}
+
+func (i *Issuer) answerWeight() int {
+	if i.AnswerWeight == 0 {
+		return defaultAnswerWeight
+	}
+	return i.AnswerWeight
+}
+
+// decodePayload() returns the payload of a token without checking the
+// signature
+func decodePayload(token string) (string, error) {
+	encoded, _, _ := strings.Cut(token, ".")
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	return string(payload), nil
+}
+
+// parsePayload() splits a payload of "<issued>:<difficulty>:<nonce>"
+func parsePayload(payload string) (time.Time, int, error) {
+	fields := strings.Split(payload, ":")
+	if len(fields) != 3 {
+		return time.Time{}, 0, ErrInvalid
+	}
+
+	issued, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalid
+	}
+	difficulty, err := strconv.Atoi(fields[1])
+	if err != nil || difficulty < 0 || difficulty > 64 {
+		return time.Time{}, 0, ErrInvalid
+	}
+
+	return time.Unix(issued, 0), difficulty, nil
+}
+
+func hash(token, solution string) []byte {
+	sum := sha256.Sum256([]byte(token + ":" + solution))
+	return sum[:]
+}
+
+// leadingZeros() counts the zero bits at the start of sum
+func leadingZeros(sum []byte) int {
+	n := bits.LeadingZeros64(binary.BigEndian.Uint64(sum[:8]))
+	if n == 64 {
+		n += bits.LeadingZeros64(binary.BigEndian.Uint64(sum[8:16]))
+	}
+	return n
+}
+
+var numbers = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+	"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen"}
+
+// correctAnswer() accepts the sum written either in digits or in words
+func correctAnswer(answer string, sum int) bool {
+	if n, err := strconv.Atoi(answer); err == nil {
+		return n == sum
+	}
+	return strings.EqualFold(answer, numbers[sum])
+}
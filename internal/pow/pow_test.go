@@ -0,0 +1,186 @@
+package pow
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+// clock is a stopped clock the tests move by hand
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestIssuer() (*Issuer, *clock) {
+	c := &clock{t: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+
+	i := &Issuer{
+		Key:           []byte("0123456789abcdef0123456789abcdef"),
+		Difficulty:    8,
+		MaxDifficulty: 10,
+		Surge:         5,
+		now:           c.now,
+	}
+
+	return i, c
+}
+
+func TestVerify(t *testing.T) {
+	i, c := newTestIssuer()
+
+	challenge := i.Issue()
+	assert.Equal(t, challenge.Difficulty, 8)
+
+	solution, err := Solve(challenge.Token)
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, i.Verify(challenge.Token, solution, ""), nil)
+
+	t.Run("Used twice", func(t *testing.T) {
+		assert.Equal(t, i.Verify(challenge.Token, solution, ""), ErrSpent)
+	})
+
+	t.Run("Not solved", func(t *testing.T) {
+		challenge := i.Issue()
+		assert.Equal(t, i.Verify(challenge.Token, "", ""), ErrUnsolved)
+
+		// find a solution that is wrong
+		wrong := 0
+		for leadingZeros(hash(challenge.Token, strconv.Itoa(wrong))) >= challenge.Difficulty {
+			wrong++
+		}
+		assert.Equal(t, i.Verify(challenge.Token, strconv.Itoa(wrong), ""), ErrUnsolved)
+	})
+
+	t.Run("Forged", func(t *testing.T) {
+		other := &Issuer{Key: []byte("another key entirely, not ours!!"), Difficulty: 1}
+		forged := other.Issue()
+		solution, _ := Solve(forged.Token)
+
+		assert.Equal(t, i.Verify(forged.Token, solution, ""), ErrInvalid)
+		assert.Equal(t, i.Verify("not a token", "1", ""), ErrInvalid)
+	})
+
+	t.Run("Lowered difficulty", func(t *testing.T) {
+		// changing the difficulty in the payload breaks the signature
+		challenge := i.Issue()
+		payload, _ := decodePayload(challenge.Token)
+		_, sig, _ := strings.Cut(challenge.Token, ".")
+
+		fields := strings.Split(payload, ":")
+		fields[1] = "0"
+		token := base64.RawURLEncoding.EncodeToString([]byte(strings.Join(fields, ":"))) + "." + sig
+
+		assert.Equal(t, i.Verify(token, "0", ""), ErrInvalid)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		challenge := i.Issue()
+		solution, _ := Solve(challenge.Token)
+
+		c.t = c.t.Add(defaultTTL + time.Second)
+		assert.Equal(t, i.Verify(challenge.Token, solution, ""), ErrExpired)
+	})
+}
+
+func TestVerifyAnswer(t *testing.T) {
+	i, c := newTestIssuer()
+
+	challenge := i.Issue()
+	payload, _ := decodePayload(challenge.Token)
+	a, b := i.operands(payload)
+
+	assert.StringContains(t, challenge.Question, numbers[a]+" plus "+numbers[b])
+
+	// bots that fill in the form straight away are turned down
+	assert.Equal(t, i.Verify(challenge.Token, "", strconv.Itoa(a+b)), ErrTooSoon)
+
+	c.t = c.t.Add(defaultMinAge)
+
+	assert.Equal(t, i.Verify(challenge.Token, "", strconv.Itoa(a+b+1)), ErrUnsolved)
+	assert.Equal(t, i.Verify(challenge.Token, "", " "+strings.ToUpper(numbers[a+b])+" "), nil)
+	assert.Equal(t, i.Verify(challenge.Token, "", strconv.Itoa(a+b)), ErrSpent)
+}
+
+func TestAnswerWait(t *testing.T) {
+	i, _ := newTestIssuer()
+
+	// the quiet difficulty is 8
+	assert.Equal(t, i.AnswerWait(8), defaultMinAge)
+	assert.Equal(t, i.AnswerWait(9), 2*defaultMinAge)
+	assert.Equal(t, i.AnswerWait(10), 4*defaultMinAge)
+	// but never so long the challenge expires first
+	assert.Equal(t, i.AnswerWait(30), defaultTTL/2)
+}
+
+func TestVerifyAnswerUnderLoad(t *testing.T) {
+	i, c := newTestIssuer()
+
+	answer := func() error {
+		challenge := i.Issue()
+		payload, _ := decodePayload(challenge.Token)
+		a, b := i.operands(payload)
+
+		c.t = c.t.Add(defaultMinAge)
+		return i.Verify(challenge.Token, "", strconv.Itoa(a+b))
+	}
+
+	// each answer counts as 4 solutions, so two of them raise the
+	// difficulty as much as five solutions would
+	assert.Equal(t, answer(), nil)
+	assert.Equal(t, answer(), nil)
+	assert.Equal(t, i.Issue().Difficulty, 9)
+
+	// and the question of a harder challenge takes longer to answer
+	assert.Equal(t, answer(), ErrTooSoon)
+}
+
+func TestDifficulty(t *testing.T) {
+	i, c := newTestIssuer()
+
+	solveMany := func(n int) {
+		for range n {
+			challenge := i.Issue()
+			solution, _ := Solve(challenge.Token)
+			if err := i.Verify(challenge.Token, solution, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	assert.Equal(t, i.Issue().Difficulty, 8)
+
+	solveMany(5)
+	assert.Equal(t, i.Issue().Difficulty, 9)
+
+	// the busy minute still counts during the next one
+	c.t = c.t.Add(time.Minute)
+	assert.Equal(t, i.Issue().Difficulty, 9)
+
+	// and goes no higher than the maximum
+	solveMany(20)
+	assert.Equal(t, i.Issue().Difficulty, 10)
+
+	// before going back down once things are quiet
+	c.t = c.t.Add(2 * time.Minute)
+	assert.Equal(t, i.Issue().Difficulty, 8)
+}
+
+func TestLeadingZeros(t *testing.T) {
+	sum := make([]byte, 32)
+	assert.Equal(t, leadingZeros(sum), 128)
+
+	sum[0] = 0x01
+	assert.Equal(t, leadingZeros(sum), 7)
+
+	sum[0], sum[9] = 0, 0x80
+	assert.Equal(t, leadingZeros(sum), 72)
+}
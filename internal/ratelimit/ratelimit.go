@@ -0,0 +1,181 @@
+// Package ratelimit throttles clients with token buckets.
+//
+// Each key, such as a client's IP address, has a bucket holding up to
+// Limit.Requests tokens which refills evenly over Limit.Per. A request
+// takes a token, and is refused when the bucket is empty. Buckets are
+// kept in least recently used order: a bucket left alone for Limit.Per is
+// full again, the same as a new one, so it is dropped, and once there are
+// more than the maximum number of keys the least recently used are dropped
+// too, however full they are. Memory use is bounded whatever the clients
+// do.
+package ratelimit
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests requests in a burst, refilled over Per
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit() reads a limit written as "<requests>/<duration>", such as
+// "300/1m" or "5/1h"
+func ParseLimit(s string) (Limit, error) {
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: limit %q is not <requests>/<duration>", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("ratelimit: limit %q needs a positive number of requests", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: limit %q needs a positive duration", s)
+	}
+
+	return Limit{Requests: n, Per: d}, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// rate() returns the tokens added per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of a request against a limiter, with what is
+// needed to fill in RateLimit and Retry-After headers
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket and Remaining the whole tokens left
+	// in it
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, if not Allowed
+	RetryAfter time.Duration
+}
+
+// Limiter holds a bucket for each key. It is safe for concurrent use.
+type Limiter struct {
+	limit   Limit
+	maxKeys int
+
+	// now can be replaced in tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// lru holds the buckets, most recently used first
+	lru *list.List
+}
+
+type bucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// New() returns a limiter applying limit to each key, which keeps track of
+// at most maxKeys keys
+func New(limit Limit, maxKeys int) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		maxKeys: max(maxKeys, 1),
+		now:     time.Now,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Limit() returns the limit applied to each key
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Allow() takes a token from the key's bucket, if there is one
+func (l *Limiter) Allow(key string) Result {
+	now := l.now()
+	rate := l.limit.rate()
+	burst := float64(l.limit.Requests)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.evictIdle(now)
+
+	var b *bucket
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+
+		// requests racing for the lock may arrive slightly out of order
+		b = e.Value.(*bucket)
+		if now.After(b.updated) {
+			b.tokens = min(burst, b.tokens+now.Sub(b.updated).Seconds()*rate)
+			b.updated = now
+		}
+	} else {
+		b = &bucket{key: key, tokens: burst, updated: now}
+		l.buckets[key] = l.lru.PushFront(b)
+
+		if l.lru.Len() > l.maxKeys {
+			l.remove(l.lru.Back())
+		}
+	}
+
+	result := Result{Limit: l.limit.Requests}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = seconds((burst - b.tokens) / rate)
+
+	return result
+}
+
+// Len() returns the number of keys being tracked
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lru.Len()
+}
+
+// evictIdle() drops the buckets which have had time to fill up again.
+// They are the least recently used, so are found at the back of the list.
+// The caller holds mu.
+func (l *Limiter) evictIdle(now time.Time) {
+	for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+		if now.Sub(e.Value.(*bucket).updated) < l.limit.Per {
+			return
+		}
+		l.remove(e)
+	}
+}
+
+func (l *Limiter) remove(e *list.Element) {
+	delete(l.buckets, e.Value.(*bucket).key)
+	l.lru.Remove(e)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PPRAMANIK62/snippetbox/internal/assert"
+)
+
+// clock is a stopped clock the tests move by hand
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestLimiter(limit Limit, maxKeys int) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+
+	l := New(limit, maxKeys)
+	l.now = c.now
+
+	return l, c
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		limit   string
+		want    Limit
+		wantErr bool
+	}{
+		{limit: "300/1m", want: Limit{Requests: 300, Per: time.Minute}},
+		{limit: "5/1h", want: Limit{Requests: 5, Per: time.Hour}},
+		{limit: "300", wantErr: true},
+		{limit: "0/1m", wantErr: true},
+		{limit: "ten/1m", wantErr: true},
+		{limit: "10/minute", wantErr: true},
+		{limit: "10/-1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			limit, err := ParseLimit(tt.limit)
+
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, limit, tt.want)
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	l, c := newTestLimiter(Limit{Requests: 3, Per: 3 * time.Second}, 10)
+
+	for i := range 3 {
+		result := l.Allow("a")
+		assert.Equal(t, result.Allowed, true)
+		assert.Equal(t, result.Limit, 3)
+		assert.Equal(t, result.Remaining, 2-i)
+		assert.Equal(t, result.Reset, time.Duration(i+1)*time.Second)
+	}
+
+	result := l.Allow("a")
+	assert.Equal(t, result.Allowed, false)
+	assert.Equal(t, result.Remaining, 0)
+	assert.Equal(t, result.RetryAfter, time.Second)
+
+	// other keys have buckets of their own
+	assert.Equal(t, l.Allow("b").Allowed, true)
+
+	// a token comes back every second
+	c.add(500 * time.Millisecond)
+	result = l.Allow("a")
+	assert.Equal(t, result.Allowed, false)
+	assert.Equal(t, result.RetryAfter, 500*time.Millisecond)
+
+	c.add(500 * time.Millisecond)
+	assert.Equal(t, l.Allow("a").Allowed, true)
+	assert.Equal(t, l.Allow("a").Allowed, false)
+
+	// and the bucket holds no more than the limit
+	c.add(time.Hour)
+	for range 3 {
+		assert.Equal(t, l.Allow("a").Allowed, true)
+	}
+	assert.Equal(t, l.Allow("a").Allowed, false)
+}
+
+func TestEviction(t *testing.T) {
+	t.Run("Idle buckets", func(t *testing.T) {
+		l, c := newTestLimiter(Limit{Requests: 2, Per: time.Minute}, 10)
+
+		l.Allow("a")
+		c.add(30 * time.Second)
+		l.Allow("b")
+		assert.Equal(t, l.Len(), 2)
+
+		// a has had time to fill up, b hasn't
+		c.add(30 * time.Second)
+		l.Allow("c")
+		assert.Equal(t, l.Len(), 2)
+
+		c.add(time.Hour)
+		l.Allow("c")
+		assert.Equal(t, l.Len(), 1)
+	})
+
+	t.Run("Too many keys", func(t *testing.T) {
+		l, _ := newTestLimiter(Limit{Requests: 1, Per: time.Minute}, 3)
+
+		l.Allow("a")
+		l.Allow("b")
+		l.Allow("c")
+		l.Allow("a")
+
+		// b is the least recently used
+		l.Allow("d")
+		assert.Equal(t, l.Len(), 3)
+
+		assert.Equal(t, l.Allow("a").Allowed, false)
+		assert.Equal(t, l.Allow("b").Allowed, true)
+	})
+
+	t.Run("Bounded memory", func(t *testing.T) {
+		l, c := newTestLimiter(Limit{Requests: 10, Per: time.Minute}, 1000)
+
+		for i := range 100000 {
+			l.Allow(strconv.Itoa(i))
+			c.add(time.Millisecond)
+		}
+		assert.Equal(t, l.Len(), 1000)
+	})
+}
+
+func TestAllowConcurrent(t *testing.T) {
+	t.Run("One key", func(t *testing.T) {
+		l, _ := newTestLimiter(Limit{Requests: 100, Per: time.Minute}, 10)
+
+		var wg sync.WaitGroup
+		var allowed atomic.Int64
+
+		// goroutines racing on one key take exactly the tokens there are
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 50 {
+					if l.Allow("shared").Allowed {
+						allowed.Add(1)
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		assert.Equal(t, allowed.Load(), int64(100))
+	})
+
+	t.Run("Many keys", func(t *testing.T) {
+		l, c := newTestLimiter(Limit{Requests: 5, Per: time.Second}, 50)
+
+		var wg sync.WaitGroup
+
+		// goroutines churning through more keys than the limiter keeps,
+		// while time moves on and idle buckets are evicted
+		for g := range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 500 {
+					l.Allow(strconv.Itoa(g*1000 + i%100))
+					c.add(time.Millisecond)
+				}
+			}()
+		}
+
+		wg.Wait()
+		assert.Equal(t, l.Len() <= 50, true)
+	})
+}
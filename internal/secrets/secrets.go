@@ -0,0 +1,242 @@
+// Package secrets finds credentials, such as cloud provider keys, private
+// keys and tokens, that people paste into snippets by mistake.
+//
+// A Scanner runs a set of rules over the text. Each rule is a regular
+// expression, optionally with a minimum Shannon entropy the match must
+// reach, which keeps generic rules like "password = ..." from flagging
+// ordinary words. The built-in rules live in rules.json, and operators can
+// add their own in a file of the same format.
+package secrets
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces each secret in the text returned by Redact()
+const Redacted = "[REDACTED]"
+
+// Rule describes one kind of secret
+type Rule struct {
+	ID          string
+	Description string
+	// Keywords, in lower case, of which the text must contain at least
+	// one for the pattern to be tried at all. Regular expressions are far
+	// slower than a substring search, so this keeps large snippets quick
+	// to scan. Empty to always try the pattern.
+	Keywords []string
+	Pattern  *regexp.Regexp
+	// Group is the capture group holding the secret itself, or 0 for the
+	// whole match. Only the secret is redacted, so "password=" stays.
+	Group int
+	// MinEntropy is the Shannon entropy, in bits per byte, the secret
+	// must have to count, or 0 to accept any match
+	MinEntropy float64
+}
+
+// Finding is a secret found in the text. Start and End are byte offsets
+// and Line counts from 1.
+type Finding struct {
+	RuleID      string
+	Description string
+	Start       int
+	End         int
+	Line        int
+}
+
+// ruleFile is a rule as it is written in a rule file
+type ruleFile struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Keywords    []string `json:"keywords"`
+	Pattern     string   `json:"pattern"`
+	Group       int      `json:"group"`
+	Entropy     float64  `json:"entropy"`
+}
+
+//go:embed rules.json
+var defaultRules string
+
+// DefaultRules() returns the built-in rules
+func DefaultRules() []Rule {
+	rules, err := ParseRules(strings.NewReader(defaultRules))
+	if err != nil {
+		panic(fmt.Sprintf("secrets: bad built-in rules: %s", err))
+	}
+	return rules
+}
+
+// ParseRules() reads rules from a JSON rule file, which holds an array of
+// objects with an id, description, pattern and optional keywords, group
+// and entropy
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var file []ruleFile
+
+	err := json.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(file))
+	for i, f := range file {
+		if f.ID == "" || f.Pattern == "" {
+			return nil, fmt.Errorf("secrets: rule %d needs an id and a pattern", i+1)
+		}
+
+		pattern, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("secrets: rule %s: %w", f.ID, err)
+		}
+		if f.Group < 0 || f.Group > pattern.NumSubexp() {
+			return nil, fmt.Errorf("secrets: rule %s: pattern has no group %d", f.ID, f.Group)
+		}
+
+		description := f.Description
+		if description == "" {
+			description = f.ID
+		}
+
+		keywords := make([]string, len(f.Keywords))
+		for i, k := range f.Keywords {
+			keywords[i] = strings.ToLower(k)
+		}
+
+		rules = append(rules, Rule{
+			ID:          f.ID,
+			Description: description,
+			Keywords:    keywords,
+			Pattern:     pattern,
+			Group:       f.Group,
+			MinEntropy:  f.Entropy,
+		})
+	}
+
+	return rules, nil
+}
+
+// Scanner looks for secrets using a fixed set of rules. It is safe for
+// concurrent use.
+type Scanner struct {
+	rules []Rule
+}
+
+func New(rules []Rule) *Scanner {
+	return &Scanner{rules: rules}
+}
+
+// Scan() returns the secrets in text in the order they appear. Where the
+// matches of several rules overlap only the first, longest one is kept.
+func (s *Scanner) Scan(text string) []Finding {
+	findings := []Finding{}
+	lower := strings.ToLower(text)
+
+	for _, rule := range s.rules {
+		if !containsAny(lower, rule.Keywords) {
+			continue
+		}
+
+		for _, m := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[2*rule.Group], m[2*rule.Group+1]
+			if start < 0 || start == end {
+				continue
+			}
+
+			secret := text[start:end]
+			if secret == Redacted || Entropy(secret) < rule.MinEntropy {
+				continue
+			}
+
+			findings = append(findings, Finding{
+				RuleID:      rule.ID,
+				Description: rule.Description,
+				Start:       start,
+				End:         end,
+			})
+		}
+	}
+
+	slices.SortFunc(findings, func(a, b Finding) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+
+	kept := findings[:0]
+	line, pos := 1, 0
+	for _, f := range findings {
+		if len(kept) > 0 && f.Start < kept[len(kept)-1].End {
+			continue
+		}
+
+		line += strings.Count(text[pos:f.Start], "\n")
+		pos = f.Start
+		f.Line = line
+
+		kept = append(kept, f)
+	}
+
+	return kept
+}
+
+// Redact() replaces each finding in text, which must be the text the
+// findings came from, with Redacted
+func Redact(text string, findings []Finding) string {
+	var b strings.Builder
+
+	pos := 0
+	for _, f := range findings {
+		b.WriteString(text[pos:f.Start])
+		b.WriteString(Redacted)
+		pos = f.End
+	}
+	b.WriteString(text[pos:])
+
+	return b.String()
+}
+
+// Entropy() returns the Shannon entropy of s in bits per byte. Random
+// base64 comes out at around 5 to 6, English words at 3 or less.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+// containsAny() returns true if s contains one of the keywords, or there
+// are no keywords
+func containsAny(s string, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+
+	for _, k := range keywords {
+		if strings.Contains(s, k) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -13,12 +13,15 @@ var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9
 type Validator struct {
 	NonFieldErrors []string
 	FieldErrors    map[string]string
+	// Trapped is set when a honeypot field was filled in, which only bots
+	// do as people never see the field
+	Trapped bool
 }
 
 // Valid() returns true if the FieldErrors map doesn't contain
 // validation errors
 func (v *Validator) Valid() bool {
-	return len(v.FieldErrors) == 0 && len(v.NonFieldErrors) == 0
+	return len(v.FieldErrors) == 0 && len(v.NonFieldErrors) == 0 && !v.Trapped
 }
 
 // AddNonFieldError() adds an error message to the NonFieldErrors slice
@@ -46,6 +49,14 @@ func (v *Validator) CheckField(ok bool, key, message string) {
 	}
 }
 
+// Honeypot() checks a honeypot field, which is hidden from people and
+// must be left empty
+func (v *Validator) Honeypot(value string) {
+	if value != "" {
+		v.Trapped = true
+	}
+}
+
 // NotBlank() returns true if a value is not an empty string
 func (v *Validator) NotBlank(value string) bool {
 	return strings.TrimSpace(value) != ""
@@ -0,0 +1,122 @@
+// drives the WebAuthn ceremonies for the login and passkey pages.
+// the server sends and expects binary fields as base64url strings,
+// while the browser API works with ArrayBuffers
+(function() {
+	var loginForm = document.getElementById("passkey-login");
+	var registerForm = document.getElementById("passkey-register");
+	var errorBox = document.getElementById("passkey-error");
+
+	if (!window.PublicKeyCredential) {
+		if (loginForm) loginForm.hidden = true;
+		if (registerForm) registerForm.hidden = true;
+		return;
+	}
+
+	function toBuffer(value) {
+		var base64 = value.replace(/-/g, "+").replace(/_/g, "/");
+		while (base64.length % 4) {
+			base64 += "=";
+		}
+		var binary = atob(base64);
+		var bytes = new Uint8Array(binary.length);
+		for (var i = 0; i < binary.length; i++) {
+			bytes[i] = binary.charCodeAt(i);
+		}
+		return bytes.buffer;
+	}
+
+	function fromBuffer(buffer) {
+		var bytes = new Uint8Array(buffer);
+		var binary = "";
+		for (var i = 0; i < bytes.length; i++) {
+			binary += String.fromCharCode(bytes[i]);
+		}
+		return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+	}
+
+	function csrfToken() {
+		var input = document.querySelector('input[name="csrf_token"]');
+		return input ? input.value : "";
+	}
+
+	function post(url, body) {
+		return fetch(url, {
+			method: "POST",
+			credentials: "same-origin",
+			headers: {
+				"Content-Type": "application/json",
+				"X-CSRF-Token": csrfToken()
+			},
+			body: body ? JSON.stringify(body) : null
+		}).then(function(res) {
+			if (!res.ok) {
+				throw new Error(res.statusText);
+			}
+			return res.json();
+		});
+	}
+
+	function showError(err) {
+		errorBox.textContent = "Passkey operation failed: " + err.message;
+		errorBox.hidden = false;
+	}
+
+	if (registerForm) {
+		registerForm.addEventListener("submit", function(e) {
+			e.preventDefault();
+			var name = registerForm.elements["name"].value;
+
+			post("/account/passkeys/register/begin").then(function(options) {
+				var publicKey = options.publicKey;
+				publicKey.challenge = toBuffer(publicKey.challenge);
+				publicKey.user.id = toBuffer(publicKey.user.id);
+				(publicKey.excludeCredentials || []).forEach(function(c) {
+					c.id = toBuffer(c.id);
+				});
+				return navigator.credentials.create({publicKey: publicKey});
+			}).then(function(credential) {
+				return post("/account/passkeys/register/finish?name=" + encodeURIComponent(name), {
+					id: credential.id,
+					rawId: fromBuffer(credential.rawId),
+					type: credential.type,
+					response: {
+						clientDataJSON: fromBuffer(credential.response.clientDataJSON),
+						attestationObject: fromBuffer(credential.response.attestationObject),
+						transports: credential.response.getTransports ? credential.response.getTransports() : []
+					}
+				});
+			}).then(function(result) {
+				window.location = result.redirect;
+			}).catch(showError);
+		});
+	}
+
+	if (loginForm) {
+		loginForm.addEventListener("submit", function(e) {
+			e.preventDefault();
+
+			post("/user/login/passkey/begin").then(function(options) {
+				var publicKey = options.publicKey;
+				publicKey.challenge = toBuffer(publicKey.challenge);
+				(publicKey.allowCredentials || []).forEach(function(c) {
+					c.id = toBuffer(c.id);
+				});
+				return navigator.credentials.get({publicKey: publicKey});
+			}).then(function(assertion) {
+				return post("/user/login/passkey/finish", {
+					id: assertion.id,
+					rawId: fromBuffer(assertion.rawId),
+					type: assertion.type,
+					response: {
+						clientDataJSON: fromBuffer(assertion.response.clientDataJSON),
+						authenticatorData: fromBuffer(assertion.response.authenticatorData),
+						signature: fromBuffer(assertion.response.signature),
+						userHandle: assertion.response.userHandle ? fromBuffer(assertion.response.userHandle) : null
+					}
+				});
+			}).then(function(result) {
+				window.location = result.redirect;
+			}).catch(showError);
+		});
+	}
+})();
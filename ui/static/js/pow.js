@@ -0,0 +1,80 @@
+// solves the proof-of-work challenge on the signup, login and report forms.
+// it finds a number which, after the token and a colon, gives a SHA-256
+// hash starting with the number of zero bits in data-difficulty. solving
+// starts when the page loads, and submitting the form waits until it's done
+(function() {
+	if (!window.crypto || !crypto.subtle || !window.TextEncoder) {
+		return;
+	}
+
+	var encoder = new TextEncoder();
+
+	function leadingZeros(bytes) {
+		var n = 0;
+		for (var i = 0; i < bytes.length; i++) {
+			if (bytes[i] === 0) {
+				n += 8;
+				continue;
+			}
+			for (var bit = 0x80; (bytes[i] & bit) === 0; bit >>= 1) {
+				n++;
+			}
+			break;
+		}
+		return n;
+	}
+
+	function solve(token, difficulty) {
+		var n = 0;
+
+		function next() {
+			// hash a batch at a time rather than waiting on each digest
+			var start = n;
+			var batch = [];
+			for (var i = 0; i < 512; i++, n++) {
+				batch.push(crypto.subtle.digest("SHA-256", encoder.encode(token + ":" + n)));
+			}
+
+			return Promise.all(batch).then(function(sums) {
+				for (var i = 0; i < sums.length; i++) {
+					if (leadingZeros(new Uint8Array(sums[i])) >= difficulty) {
+						return String(start + i);
+					}
+				}
+				return next();
+			});
+		}
+
+		return next();
+	}
+
+	var tokens = document.querySelectorAll('input[name="pow_token"]');
+	for (var i = 0; i < tokens.length; i++) {
+		(function(input) {
+			var form = input.form;
+			var solution = form.querySelector('input[name="pow_solution"]');
+			var waiting = false;
+
+			solve(input.value, parseInt(input.getAttribute("data-difficulty"), 10)).then(function(found) {
+				solution.value = found;
+				if (waiting) {
+					form.submit();
+				}
+			});
+
+			form.addEventListener("submit", function(event) {
+				if (solution.value) {
+					return;
+				}
+				event.preventDefault();
+				waiting = true;
+
+				var button = form.querySelector('input[type="submit"]');
+				if (button) {
+					button.disabled = true;
+					button.value = "Checking you're not a bot...";
+				}
+			});
+		})(tokens[i]);
+	}
+})();